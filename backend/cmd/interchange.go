@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pointcloud-annotator/backend/internal/cache"
+	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/events"
+	"github.com/pointcloud-annotator/backend/internal/handler"
+)
+
+// commands are the subcommands of the binary, keyed by name.
+var commands = map[string]func(args []string) error{
+	"export": runExport,
+	"import": runImport,
+}
+
+// runExport writes a point cloud's annotations in an interchange format, like
+// GET /api/v1/annotations/export:
+//
+//	annotator export -point-cloud <id> -format kitti -o labels.txt
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	pointCloudID := flags.String("point-cloud", "", "ID of the point cloud to export")
+	format := flags.String("format", "geojson", "Export format: geojson, kitti, nuscenes, csv or parquet")
+	output := flags.String("o", "", "Output file (default standard output)")
+	_ = flags.Parse(args)
+
+	return withHandler(func(h *handler.Handler) error {
+		if *output == "" {
+			return h.Export(context.Background(), os.Stdout, *pointCloudID, *format)
+		}
+
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if err := h.Export(context.Background(), file, *pointCloudID, *format); err != nil {
+			_ = file.Close()
+			return err
+		}
+		return file.Close()
+	})
+}
+
+// runImport creates annotations in a point cloud from an interchange format,
+// like POST /api/v1/annotations/import:
+//
+//	annotator import -point-cloud <id> -format nuscenes -i sample_annotation.json
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	pointCloudID := flags.String("point-cloud", "", "ID of the point cloud to import into")
+	format := flags.String("format", "geojson", "Import format: geojson, kitti or nuscenes")
+	input := flags.String("i", "", "Input file (default standard input)")
+	author := flags.String("author", "", "Author recorded in the annotation history")
+	_ = flags.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	return withHandler(func(h *handler.Handler) error {
+		result, err := h.Import(database.WithAuthor(context.Background(), *author), r, *pointCloudID, *format)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported %d annotations\n", result.Imported)
+		return nil
+	})
+}
+
+// withHandler runs fn with a handler connected to the configured database,
+// cache and event bus.
+func withHandler(fn func(h *handler.Handler) error) error {
+	cfg := config.New()
+	logger, err := newLogger(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = logger.Sync() }()
+
+	repo, err := database.NewPostgresRepository(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer repo.Close()
+
+	cacheClient, err := cache.NewRedisCache(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer func() { _ = cacheClient.Close() }()
+
+	// Imports are announced to the clients watching the point cloud
+	bus, err := events.NewRedisBus(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to annotation events: %w", err)
+	}
+	defer func() { _ = bus.Close() }()
+
+	return fn(handler.NewHandler(repo, cacheClient, bus, logger))
+}
@@ -3,10 +3,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/fx"
@@ -15,11 +18,24 @@ import (
 	"github.com/pointcloud-annotator/backend/internal/cache"
 	"github.com/pointcloud-annotator/backend/internal/config"
 	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/events"
 	"github.com/pointcloud-annotator/backend/internal/gateway"
 	"github.com/pointcloud-annotator/backend/internal/handler"
+	"github.com/pointcloud-annotator/backend/internal/identity"
 )
 
 func main() {
+	// Subcommands run once against the database instead of serving
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Parse command line flags
 	role := flag.String("role", "", "Service role: gateway or handler (overrides SERVICE_ROLE env var)")
 	port := flag.String("port", "", "Server port (overrides SERVER_PORT env var)")
@@ -63,11 +79,20 @@ func newGinEngine(cfg *config.Config) *gin.Engine {
 	engine.Use(gin.Recovery())
 	engine.Use(gin.Logger())
 
-	// CORS middleware
+	// CORS middleware; with a list of origins, only those are allowed
+	allowAnyOrigin := slices.Contains(cfg.CORSAllowedOrigins, "*")
 	engine.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		if allowAnyOrigin {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); slices.Contains(cfg.CORSAllowedOrigins, origin) {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, If-Match, If-None-Match, X-Author")
+		c.Header("Access-Control-Expose-Headers", "ETag")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -90,8 +115,27 @@ func startServer(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger, engine
 	// Setup API versioned routes
 	apiV1 := engine.Group("/api/v1")
 
-	// Health check endpoint
+	var repo database.Repository
+
+	// Health check endpoint; a handler is only healthy while its database
+	// answers, so that the gateway stops routing to it otherwise
 	engine.GET("/health", func(c *gin.Context) {
+		if repo != nil {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+			defer cancel()
+
+			if err := repo.Ping(ctx); err != nil {
+				logger.Warn("Health check failed", zap.Error(err))
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"status":  "unhealthy",
+					"role":    cfg.Role,
+					"service": "point-cloud-annotator",
+					"error":   "database is not available",
+				})
+				return
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "healthy",
 			"role":    cfg.Role,
@@ -99,12 +143,25 @@ func startServer(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger, engine
 		})
 	})
 
-	var repo database.Repository
 	var cacheClient cache.Cache
+	var bus events.Bus
+	var nonces identity.Nonces
+	var purger *database.TrashPurger
+	var gw *gateway.Gateway
 
 	if cfg.IsHandler() {
 		// Handler mode: connect to database and cache, register handlers
 		var err error
+
+		// Handlers configured for authentication must only serve the
+		// identities the gateway signs, or clients calling them directly
+		// could name any author
+		if cfg.AuthEnabled() && cfg.IdentitySecret == "" {
+			err = errors.New("JWT authentication needs IDENTITY_SECRET to verify the forwarded identity")
+			logger.Fatal("Failed to configure handler", zap.Error(err))
+			return err
+		}
+
 		repo, err = database.NewPostgresRepository(cfg, logger)
 		if err != nil {
 			logger.Fatal("Failed to connect to database", zap.Error(err))
@@ -117,17 +174,48 @@ func startServer(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger, engine
 			return err
 		}
 
-		h := handler.NewHandler(repo, cacheClient, logger)
+		bus, err = events.NewRedisBus(cfg, logger)
+		if err != nil {
+			logger.Fatal("Failed to subscribe to annotation events", zap.Error(err))
+			return err
+		}
+
+		// Behind an authenticating gateway, only serve the requests it
+		// signed an identity for, once
+		if cfg.IdentitySecret != "" {
+			nonces, err = identity.NewRedisNonces(cfg, logger)
+			if err != nil {
+				logger.Fatal("Failed to connect to the identity nonce store", zap.Error(err))
+				return err
+			}
+			apiV1.Use(identity.Trust([]byte(cfg.IdentitySecret), nonces, logger))
+		}
+
+		h := handler.NewHandler(repo, cacheClient, bus, logger)
 		h.RegisterRoutes(apiV1)
 
+		if cfg.TrashRetention > 0 && cfg.TrashPurgeInterval > 0 {
+			purger = database.NewTrashPurger(repo, cfg, logger)
+		}
+
 		logger.Info("Handler routes registered")
 	} else {
-		// Gateway mode: setup proxy to handler
-		gw := gateway.NewGateway(cfg, logger)
+		// Gateway mode: setup proxy to the handler replicas
+		var err error
+		gw, err = gateway.NewGateway(cfg, logger)
+		if err != nil {
+			logger.Fatal("Failed to configure gateway", zap.Error(err))
+			return err
+		}
 		gw.RegisterRoutes(apiV1)
+		gw.RegisterHealthRoutes(engine)
+		gw.RegisterMetricsRoutes(engine)
 
 		logger.Info("Gateway routes registered",
-			zap.String("handler_url", cfg.HandlerURL),
+			zap.Strings("handler_urls", cfg.HandlerURLs),
+			zap.String("handler_discovery", cfg.HandlerDiscovery),
+			zap.String("load_balancer", cfg.LoadBalancer),
+			zap.Bool("authentication", cfg.AuthEnabled()),
 		)
 	}
 
@@ -144,11 +232,31 @@ func startServer(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger, engine
 					logger.Fatal("Server failed", zap.Error(err))
 				}
 			}()
+
+			if purger != nil {
+				purger.Start()
+			}
+			if gw != nil {
+				gw.Start()
+			}
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
 			logger.Info("Server shutting down")
 
+			if purger != nil {
+				purger.Stop()
+			}
+			if gw != nil {
+				gw.Stop()
+			}
+
+			if bus != nil {
+				_ = bus.Close()
+			}
+			if nonces != nil {
+				_ = nonces.Close()
+			}
 			if repo != nil {
 				repo.Close()
 			}
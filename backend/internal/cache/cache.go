@@ -3,6 +3,8 @@ package cache
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -15,9 +17,11 @@ import (
 )
 
 const (
-	// Cache key prefixes
-	annotationKeyPrefix = "annotation:"
-	allAnnotationsKey   = "annotations:all"
+	// Cache key prefixes; every key is scoped to its point cloud.
+	pointCloudKeyPrefix      = "pointcloud:"
+	annotationKeyInfix       = ":annotation:"
+	annotationsKeyInfix      = ":annotations:"
+	annotationsGenerationKey = ":annotations:generation"
 
 	// Default TTL for cached items
 	defaultTTL = 5 * time.Minute
@@ -25,23 +29,41 @@ const (
 
 // Cache defines the interface for caching operations.
 type Cache interface {
-	// Get retrieves an annotation from cache by ID.
-	Get(ctx context.Context, id string) (*models.Annotation, error)
-
-	// GetAll retrieves all cached annotations.
-	GetAll(ctx context.Context) ([]models.Annotation, bool, error)
-
-	// Set stores an annotation in cache.
-	Set(ctx context.Context, annotation *models.Annotation) error
-
-	// SetAll stores all annotations in cache.
-	SetAll(ctx context.Context, annotations []models.Annotation) error
-
-	// Delete removes an annotation from cache.
-	Delete(ctx context.Context, id string) error
-
-	// InvalidateAll removes all cached annotations.
-	InvalidateAll(ctx context.Context) error
+	// Get retrieves an annotation of the given point cloud from cache by ID,
+	// along with the generation it looked in. An annotation read from the
+	// database on a miss is to be stored under that generation, like pages.
+	Get(ctx context.Context, pointCloudID, id string) (*models.Annotation, string, error)
+
+	// GetAll retrieves a cached page of the given point cloud's annotations,
+	// along with the generation of the lists it looked in. A page read from
+	// the database on a miss is to be stored under that generation, so that a
+	// write in between orphans it rather than leaving it stale.
+	GetAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery) (*models.AnnotationPage, string, bool, error)
+
+	// Set stores an annotation read from the database in cache under the
+	// generation Get returned. Writes do not store the annotations they
+	// wrote: they invalidate them with InvalidateAll.
+	Set(ctx context.Context, generation string, annotation *models.Annotation) error
+
+	// SetAll stores a page of the given point cloud's annotations in cache
+	// under the generation GetAll returned.
+	SetAll(ctx context.Context, pointCloudID, generation string, query *models.AnnotationQuery, page *models.AnnotationPage) error
+
+	// GetTagCounts retrieves the cached tag counts of the given point cloud,
+	// along with the generation of the lists it looked in.
+	GetTagCounts(ctx context.Context, pointCloudID string) ([]models.TagCount, string, bool, error)
+
+	// SetTagCounts stores the tag counts of the given point cloud in cache
+	// under the generation GetTagCounts returned. They are invalidated
+	// together with the annotation lists.
+	SetTagCounts(ctx context.Context, pointCloudID, generation string, counts []models.TagCount) error
+
+	// InvalidateAll removes all cached annotations, annotation lists and tag
+	// counts of the given point cloud.
+	InvalidateAll(ctx context.Context, pointCloudID string) error
+
+	// InvalidatePointCloud removes every cached entry of the given point cloud.
+	InvalidatePointCloud(ctx context.Context, pointCloudID string) error
 
 	// Close closes the cache connection.
 	Close() error
@@ -79,53 +101,112 @@ func NewRedisCache(cfg *config.Config, logger *zap.Logger) (Cache, error) {
 	}, nil
 }
 
-// Get retrieves an annotation from cache by ID.
-func (c *RedisCache) Get(ctx context.Context, id string) (*models.Annotation, error) {
-	key := annotationKeyPrefix + id
+// annotationKey returns the cache key of a single annotation in a generation.
+func annotationKey(pointCloudID, generation, id string) string {
+	return pointCloudKeyPrefix + pointCloudID + annotationKeyInfix + generation + ":" + id
+}
+
+// generationKey returns the key of the counter that versions the cached
+// annotations and annotation pages of a point cloud. Bumping it orphans every
+// cached entry, which then expire through their TTL.
+func generationKey(pointCloudID string) string {
+	return pointCloudKeyPrefix + pointCloudID + annotationsGenerationKey
+}
+
+// generation returns the current cache generation of a point cloud's lists.
+func (c *RedisCache) generation(ctx context.Context, pointCloudID string) (string, error) {
+	generation, err := c.client.Get(ctx, generationKey(pointCloudID)).Result()
+	if err == redis.Nil {
+		return "0", nil
+	}
+	return generation, err
+}
+
+// annotationsKey returns the cache key of one page of a point cloud's
+// annotations, derived from the query shape and the generation.
+func annotationsKey(pointCloudID, generation string, query *models.AnnotationQuery) string {
+	hash := sha1.Sum([]byte(query.CacheKey()))
+	return pointCloudKeyPrefix + pointCloudID + annotationsKeyInfix + generation + ":" + hex.EncodeToString(hash[:])
+}
+
+// tagCountsKey returns the cache key of a point cloud's tag counts. It shares
+// the generation of the annotation pages, so every annotation change, tag
+// changes included, invalidates the counts as well.
+func tagCountsKey(pointCloudID, generation string) string {
+	return pointCloudKeyPrefix + pointCloudID + annotationsKeyInfix + generation + ":tags"
+}
+
+// Get retrieves an annotation of the given point cloud from cache by ID, along
+// with the generation it looked in. The generation is empty when it could not
+// be read, and nothing is to be cached then.
+func (c *RedisCache) Get(ctx context.Context, pointCloudID, id string) (*models.Annotation, string, error) {
+	generation, err := c.generation(ctx, pointCloudID)
+	if err != nil {
+		c.logger.Warn("Failed to resolve annotation cache generation", zap.Error(err))
+		return nil, "", nil
+	}
+
+	key := annotationKey(pointCloudID, generation, id)
 
 	data, err := c.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
-		return nil, nil // Cache miss
+		return nil, generation, nil // Cache miss
 	}
 	if err != nil {
 		c.logger.Warn("Failed to get from cache", zap.String("key", key), zap.Error(err))
-		return nil, nil // Treat errors as cache miss
+		return nil, generation, nil // Treat errors as cache miss
 	}
 
 	var annotation models.Annotation
 	if err := json.Unmarshal(data, &annotation); err != nil {
 		c.logger.Warn("Failed to unmarshal cached annotation", zap.Error(err))
-		return nil, nil
+		return nil, generation, nil
 	}
 
 	c.logger.Debug("Cache hit", zap.String("key", key))
-	return &annotation, nil
+	return &annotation, generation, nil
 }
 
-// GetAll retrieves all cached annotations.
-func (c *RedisCache) GetAll(ctx context.Context) ([]models.Annotation, bool, error) {
-	data, err := c.client.Get(ctx, allAnnotationsKey).Bytes()
+// GetAll retrieves a cached page of the given point cloud's annotations,
+// along with the generation of the lists it looked in. The generation is
+// empty when it could not be read, and nothing is to be cached then.
+func (c *RedisCache) GetAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery) (*models.AnnotationPage, string, bool, error) {
+	generation, err := c.generation(ctx, pointCloudID)
+	if err != nil {
+		c.logger.Warn("Failed to resolve annotations cache generation", zap.Error(err))
+		return nil, "", false, nil
+	}
+
+	key := annotationsKey(pointCloudID, generation, query)
+
+	data, err := c.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
-		return nil, false, nil // Cache miss
+		return nil, generation, false, nil // Cache miss
 	}
 	if err != nil {
-		c.logger.Warn("Failed to get all from cache", zap.Error(err))
-		return nil, false, nil
+		c.logger.Warn("Failed to get all from cache", zap.String("key", key), zap.Error(err))
+		return nil, generation, false, nil
 	}
 
-	var annotations []models.Annotation
-	if err := json.Unmarshal(data, &annotations); err != nil {
+	var page models.AnnotationPage
+	if err := json.Unmarshal(data, &page); err != nil {
 		c.logger.Warn("Failed to unmarshal cached annotations", zap.Error(err))
-		return nil, false, nil
+		return nil, generation, false, nil
 	}
 
-	c.logger.Debug("Cache hit for all annotations")
-	return annotations, true, nil
+	c.logger.Debug("Cache hit for annotations", zap.String("key", key))
+	return &page, generation, true, nil
 }
 
-// Set stores an annotation in cache.
-func (c *RedisCache) Set(ctx context.Context, annotation *models.Annotation) error {
-	key := annotationKeyPrefix + annotation.ID
+// Set stores an annotation in cache under the generation Get returned. The
+// generation is read before the annotation is, so an annotation read across a
+// write lands in the orphaned generation instead of outliving the write.
+func (c *RedisCache) Set(ctx context.Context, generation string, annotation *models.Annotation) error {
+	if generation == "" {
+		return nil
+	}
+
+	key := annotationKey(annotation.PointCloudID, generation, annotation.ID)
 
 	data, err := json.Marshal(annotation)
 	if err != nil {
@@ -138,55 +219,120 @@ func (c *RedisCache) Set(ctx context.Context, annotation *models.Annotation) err
 		return err
 	}
 
-	// Invalidate the "all" cache since data changed
-	_ = c.InvalidateAll(ctx)
-
 	c.logger.Debug("Cached annotation", zap.String("key", key))
 	return nil
 }
 
-// SetAll stores all annotations in cache.
-func (c *RedisCache) SetAll(ctx context.Context, annotations []models.Annotation) error {
-	data, err := json.Marshal(annotations)
+// SetAll stores a page of the given point cloud's annotations in cache under
+// the generation GetAll returned. The generation is read before the page is,
+// so a page read across a write lands in the orphaned generation.
+func (c *RedisCache) SetAll(ctx context.Context, pointCloudID, generation string, query *models.AnnotationQuery, page *models.AnnotationPage) error {
+	if generation == "" {
+		return nil
+	}
+
+	key := annotationsKey(pointCloudID, generation, query)
+
+	data, err := json.Marshal(page)
 	if err != nil {
 		c.logger.Warn("Failed to marshal annotations for cache", zap.Error(err))
 		return err
 	}
 
-	if err := c.client.Set(ctx, allAnnotationsKey, data, c.ttl).Err(); err != nil {
-		c.logger.Warn("Failed to set all cache", zap.Error(err))
+	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		c.logger.Warn("Failed to set all cache", zap.String("key", key), zap.Error(err))
 		return err
 	}
 
-	c.logger.Debug("Cached all annotations", zap.Int("count", len(annotations)))
+	c.logger.Debug("Cached annotations", zap.String("key", key), zap.Int("count", len(page.Annotations)))
 	return nil
 }
 
-// Delete removes an annotation from cache.
-func (c *RedisCache) Delete(ctx context.Context, id string) error {
-	key := annotationKeyPrefix + id
+// GetTagCounts retrieves the cached tag counts of the given point cloud, along
+// with the generation of the lists it looked in. The generation is empty when
+// it could not be read, and nothing is to be cached then.
+func (c *RedisCache) GetTagCounts(ctx context.Context, pointCloudID string) ([]models.TagCount, string, bool, error) {
+	generation, err := c.generation(ctx, pointCloudID)
+	if err != nil {
+		c.logger.Warn("Failed to resolve tag counts cache generation", zap.Error(err))
+		return nil, "", false, nil
+	}
+
+	key := tagCountsKey(pointCloudID, generation)
+
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, generation, false, nil // Cache miss
+	}
+	if err != nil {
+		c.logger.Warn("Failed to get tag counts from cache", zap.String("key", key), zap.Error(err))
+		return nil, generation, false, nil
+	}
+
+	var counts []models.TagCount
+	if err := json.Unmarshal(data, &counts); err != nil {
+		c.logger.Warn("Failed to unmarshal cached tag counts", zap.Error(err))
+		return nil, generation, false, nil
+	}
+
+	c.logger.Debug("Cache hit for tag counts", zap.String("key", key))
+	return counts, generation, true, nil
+}
+
+// SetTagCounts stores the tag counts of the given point cloud in cache under
+// the generation GetTagCounts returned.
+func (c *RedisCache) SetTagCounts(ctx context.Context, pointCloudID, generation string, counts []models.TagCount) error {
+	if generation == "" {
+		return nil
+	}
+
+	key := tagCountsKey(pointCloudID, generation)
 
-	if err := c.client.Del(ctx, key).Err(); err != nil {
-		c.logger.Warn("Failed to delete from cache", zap.String("key", key), zap.Error(err))
+	data, err := json.Marshal(counts)
+	if err != nil {
+		c.logger.Warn("Failed to marshal tag counts for cache", zap.Error(err))
 		return err
 	}
 
-	// Invalidate the "all" cache since data changed
-	_ = c.InvalidateAll(ctx)
+	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+		c.logger.Warn("Failed to set tag counts cache", zap.String("key", key), zap.Error(err))
+		return err
+	}
 
-	c.logger.Debug("Deleted from cache", zap.String("key", key))
+	c.logger.Debug("Cached tag counts", zap.String("key", key), zap.Int("count", len(counts)))
 	return nil
 }
 
-// InvalidateAll removes all cached annotations.
-func (c *RedisCache) InvalidateAll(ctx context.Context) error {
-	if err := c.client.Del(ctx, allAnnotationsKey).Err(); err != nil {
+// InvalidateAll removes all cached annotations and annotation lists of the
+// given point cloud by moving it to a new cache generation.
+func (c *RedisCache) InvalidateAll(ctx context.Context, pointCloudID string) error {
+	if err := c.client.Incr(ctx, generationKey(pointCloudID)).Err(); err != nil {
 		c.logger.Warn("Failed to invalidate all cache", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// InvalidatePointCloud removes every cached entry of the given point cloud.
+func (c *RedisCache) InvalidatePointCloud(ctx context.Context, pointCloudID string) error {
+	pattern := pointCloudKeyPrefix + pointCloudID + ":*"
+
+	iter := c.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		if err := c.client.Del(ctx, iter.Val()).Err(); err != nil {
+			c.logger.Warn("Failed to delete from cache", zap.String("key", iter.Val()), zap.Error(err))
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		c.logger.Warn("Failed to scan point cloud cache", zap.String("pattern", pattern), zap.Error(err))
+		return err
+	}
+
+	c.logger.Debug("Invalidated point cloud cache", zap.String("point_cloud_id", pointCloudID))
+	return nil
+}
+
 // Close closes the Redis connection.
 func (c *RedisCache) Close() error {
 	c.logger.Info("Closing Redis connection")
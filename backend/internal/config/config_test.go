@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,6 +29,10 @@ func TestNew_DefaultValues(t *testing.T) {
 	assert.Equal(t, "8080", cfg.ServerPort)
 	assert.Equal(t, "development", cfg.Environment)
 	assert.Equal(t, "http://handler:8081", cfg.HandlerURL)
+	assert.Equal(t, []string{"http://handler:8081"}, cfg.HandlerURLs)
+	assert.Equal(t, "round_robin", cfg.LoadBalancer)
+	assert.Equal(t, 3, cfg.RetryMaxAttempts)
+	assert.Equal(t, 0.2, cfg.RetryBudget)
 }
 
 func TestNew_EnvironmentOverrides(t *testing.T) {
@@ -108,6 +113,12 @@ func TestIsDevelopment(t *testing.T) {
 	}
 }
 
+func TestAuthEnabled(t *testing.T) {
+	assert.False(t, (&Config{}).AuthEnabled())
+	assert.True(t, (&Config{JWTSecret: "secret"}).AuthEnabled())
+	assert.True(t, (&Config{JWKS: "/etc/pca/jwks.json"}).AuthEnabled())
+}
+
 func TestGetEnv(t *testing.T) {
 	// Test with existing env var
 	os.Setenv("TEST_VAR", "test_value")
@@ -140,3 +151,50 @@ func TestGetEnvInt(t *testing.T) {
 	result = getEnvInt("NON_EXISTING_INT", 100)
 	assert.Equal(t, 100, result)
 }
+
+func TestGetEnvDuration(t *testing.T) {
+	os.Setenv("TEST_DURATION", "36h")
+	defer os.Unsetenv("TEST_DURATION")
+	assert.Equal(t, 36*time.Hour, getEnvDuration("TEST_DURATION", time.Hour))
+
+	// Zero is a valid setting, e.g. to keep the trash forever
+	os.Setenv("TEST_ZERO_DURATION", "0")
+	defer os.Unsetenv("TEST_ZERO_DURATION")
+	assert.Equal(t, time.Duration(0), getEnvDuration("TEST_ZERO_DURATION", time.Hour))
+
+	// Invalid and negative durations fall back to the default
+	os.Setenv("TEST_INVALID_DURATION", "a while")
+	defer os.Unsetenv("TEST_INVALID_DURATION")
+	assert.Equal(t, time.Hour, getEnvDuration("TEST_INVALID_DURATION", time.Hour))
+
+	os.Setenv("TEST_NEGATIVE_DURATION", "-5m")
+	defer os.Unsetenv("TEST_NEGATIVE_DURATION")
+	assert.Equal(t, time.Hour, getEnvDuration("TEST_NEGATIVE_DURATION", time.Hour))
+
+	assert.Equal(t, time.Minute, getEnvDuration("NON_EXISTING_DURATION", time.Minute))
+}
+
+func TestGetEnvFloat(t *testing.T) {
+	os.Setenv("TEST_FLOAT", "0.25")
+	defer os.Unsetenv("TEST_FLOAT")
+	assert.Equal(t, 0.25, getEnvFloat("TEST_FLOAT", 0.5))
+
+	os.Setenv("TEST_INVALID_FLOAT", "a quarter")
+	defer os.Unsetenv("TEST_INVALID_FLOAT")
+	assert.Equal(t, 0.5, getEnvFloat("TEST_INVALID_FLOAT", 0.5))
+
+	assert.Equal(t, 0.5, getEnvFloat("NON_EXISTING_FLOAT", 0.5))
+}
+
+func TestGetEnvList(t *testing.T) {
+	os.Setenv("TEST_LIST", "http://handler-1:8081, http://handler-2:8081,,")
+	defer os.Unsetenv("TEST_LIST")
+	assert.Equal(t, []string{"http://handler-1:8081", "http://handler-2:8081"}, getEnvList("TEST_LIST", nil))
+
+	// An empty list falls back to the default
+	os.Setenv("TEST_EMPTY_LIST", " , ")
+	defer os.Unsetenv("TEST_EMPTY_LIST")
+	assert.Equal(t, []string{"default"}, getEnvList("TEST_EMPTY_LIST", []string{"default"}))
+
+	assert.Equal(t, []string{"default"}, getEnvList("NON_EXISTING_LIST", []string{"default"}))
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// copyThreshold is the number of consecutive creates from which a batch
+// inserts them with COPY instead of one statement each.
+const copyThreshold = 100
+
+// BatchRepository defines the batch write operation.
+type BatchRepository interface {
+	// Batch applies annotation writes in order in one transaction and returns
+	// the outcome of every operation attempted. An atomic batch stops at the
+	// first failing operation and applies nothing; otherwise failing
+	// operations are skipped and the others applied.
+	Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]BatchItem, error)
+}
+
+// BatchItem is the outcome of one operation of a batch.
+type BatchItem struct {
+	// Annotation is the created or updated annotation.
+	Annotation *models.Annotation
+
+	// Err is why the operation failed. Operations fail with the errors of
+	// the corresponding single writes; updates of missing annotations with
+	// ErrAnnotationNotFound, like deletes.
+	Err error
+}
+
+// Batch applies annotation writes in order in one transaction. Runs of at
+// least copyThreshold creates are inserted with COPY; if the copy fails, the
+// run is inserted one by one to tell which creates fail. Without atomic every
+// operation runs in a savepoint, so that its failure leaves the transaction
+// usable for the others.
+func (r *PostgresRepository) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]BatchItem, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now().UTC()
+	items := make([]BatchItem, 0, len(ops))
+	failed := 0
+
+	for i := 0; i < len(ops); {
+		if n := createRun(ops[i:]); n >= copyThreshold {
+			annotations, err := r.copyAnnotations(ctx, tx, ops[i:i+n], now)
+			if err == nil {
+				for _, annotation := range annotations {
+					items = append(items, BatchItem{Annotation: annotation})
+				}
+				i += n
+				continue
+			}
+			r.logger.Warn("Failed to copy annotations, inserting them one by one", zap.Int("count", n), zap.Error(err))
+		}
+
+		var item BatchItem
+		if atomic {
+			item.Annotation, item.Err = r.applyOperation(ctx, tx, &ops[i], now)
+		} else if item, err = r.applyIsolated(ctx, tx, &ops[i], now); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		i++
+
+		if item.Err != nil {
+			if atomic {
+				r.logger.Info("Rolled back atomic batch", zap.Int("failed_operation", i-1))
+				return items, nil
+			}
+			failed++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	r.logger.Info("Applied batch",
+		zap.Int("operations", len(ops)),
+		zap.Int("failed", failed),
+	)
+	return items, nil
+}
+
+// createRun returns the number of create operations at the start of ops.
+func createRun(ops []models.BatchOperation) int {
+	n := 0
+	for n < len(ops) && ops[n].Op == models.BatchCreate {
+		n++
+	}
+	return n
+}
+
+// applyOperation applies a single operation of a batch as part of the
+// transaction.
+func (r *PostgresRepository) applyOperation(ctx context.Context, tx pgx.Tx, op *models.BatchOperation, now time.Time) (*models.Annotation, error) {
+	switch op.Op {
+	case models.BatchCreate:
+		annotation := newAnnotation(op.PointCloudID, op.Create, now)
+		if err := r.insertAnnotation(ctx, tx, annotation); err != nil {
+			return nil, err
+		}
+		return annotation, nil
+	case models.BatchUpdate:
+		annotation, err := r.updateAnnotation(ctx, tx, op.PointCloudID, op.ID, op.Update, op.Versions())
+		if err == nil && annotation == nil {
+			err = ErrAnnotationNotFound
+		}
+		return annotation, err
+	default:
+		return nil, r.deleteAnnotation(ctx, tx, op.PointCloudID, op.ID, op.Versions())
+	}
+}
+
+// applyIsolated applies a single operation of a batch in a savepoint. Only
+// failures to manage the savepoint are returned as errors; the operation's
+// own failure is reported in the item.
+func (r *PostgresRepository) applyIsolated(ctx context.Context, tx pgx.Tx, op *models.BatchOperation, now time.Time) (BatchItem, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return BatchItem{}, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	annotation, opErr := r.applyOperation(ctx, savepoint, op, now)
+	if opErr != nil {
+		if err := savepoint.Rollback(ctx); err != nil {
+			return BatchItem{}, fmt.Errorf("failed to roll back to savepoint: %w", err)
+		}
+		return BatchItem{Err: opErr}, nil
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		return BatchItem{}, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return BatchItem{Annotation: annotation}, nil
+}
+
+// copyAnnotations inserts the annotations of a run of create operations and
+// their first revisions with COPY, in a savepoint so that a failed copy can be
+// retried one by one.
+func (r *PostgresRepository) copyAnnotations(ctx context.Context, tx pgx.Tx, ops []models.BatchOperation, now time.Time) ([]*models.Annotation, error) {
+	annotations := make([]*models.Annotation, len(ops))
+	rows := make([][]any, len(ops))
+	revisions := make([][]any, len(ops))
+	author := AuthorFrom(ctx)
+
+	for i := range ops {
+		annotation := newAnnotation(ops[i].PointCloudID, ops[i].Create, now)
+		annotations[i] = annotation
+
+		// COPY encodes UUIDs in binary, which takes no strings
+		var ids [4]pgtype.UUID
+		for j, id := range []*string{&annotation.ID, &annotation.PointCloudID, annotation.LabelID, annotation.TrackID} {
+			if id == nil {
+				continue
+			}
+			parsed, err := uuid.Parse(*id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid id %q: %w", *id, err)
+			}
+			ids[j] = pgtype.UUID{Bytes: parsed, Valid: true}
+		}
+
+		rows[i] = []any{
+			ids[0],
+			ids[1],
+			annotation.X,
+			annotation.Y,
+			annotation.Z,
+			annotation.Title,
+			annotation.Description,
+			annotation.Geometry,
+			ids[2],
+			annotation.Attributes,
+			ids[3],
+			annotation.Version,
+			annotation.CreatedAt,
+			annotation.UpdatedAt,
+		}
+		revisions[i] = []any{ids[0], 1, ids[1], models.RevisionCreate, annotation, author, annotation.CreatedAt}
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer func() { _ = savepoint.Rollback(ctx) }()
+
+	_, err = savepoint.CopyFrom(ctx,
+		pgx.Identifier{"annotations"},
+		[]string{"id", "point_cloud_id", "x", "y", "z", "title", "description", "geometry", "label_id", "attributes", "track_id", "version", "created_at", "updated_at"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy annotations: %w", err)
+	}
+
+	_, err = savepoint.CopyFrom(ctx,
+		pgx.Identifier{"annotation_revisions"},
+		[]string{"annotation_id", "revision", "point_cloud_id", "operation", "data", "author", "created_at"},
+		pgx.CopyFromRows(revisions),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy annotation revisions: %w", err)
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import "errors"
+
+// Errors the repositories return for requests the data does not allow. Their
+// messages are shown to clients; match them with errors.Is.
+var (
+	// ErrAnnotationNotFound is returned for writes to an annotation that does
+	// not exist in the point cloud.
+	ErrAnnotationNotFound = errors.New("annotation not found")
+
+	// ErrPointCloudNotFound is returned for writes to a point cloud that does
+	// not exist.
+	ErrPointCloudNotFound = errors.New("point cloud not found")
+
+	// ErrLabelNotFound is returned for writes to or references of a label
+	// that does not exist.
+	ErrLabelNotFound = errors.New("label not found")
+
+	// ErrParentLabelNotFound is returned when a label names a parent that does
+	// not exist.
+	ErrParentLabelNotFound = errors.New("parent label not found")
+
+	// ErrLabelExists is returned when a label's name is already taken.
+	ErrLabelExists = errors.New("label already exists")
+
+	// ErrLabelCycle is returned when a label would become its own ancestor.
+	ErrLabelCycle = errors.New("label cannot be its own ancestor")
+
+	// ErrNonconforming is returned, wrapping the reason, when a write leaves an
+	// annotation not conforming to its label.
+	ErrNonconforming = errors.New("annotation does not conform to its label")
+
+	// ErrLabelInUse is returned for deletes of a label that annotations,
+	// tracks or child labels still refer to.
+	ErrLabelInUse = errors.New("label in use")
+
+	// ErrSequenceNotFound is returned for writes to or references of a
+	// sequence that does not exist.
+	ErrSequenceNotFound = errors.New("sequence not found")
+
+	// ErrFrameWithoutSequence is returned when a point cloud gets a frame
+	// timestamp without belonging to a sequence.
+	ErrFrameWithoutSequence = errors.New("frame_timestamp requires sequence_id")
+
+	// ErrTrackNotFound is returned for writes to or references of a track that
+	// does not exist.
+	ErrTrackNotFound = errors.New("track not found")
+
+	// ErrTrackFrameTaken is returned when a track would be observed twice in
+	// the same frame.
+	ErrTrackFrameTaken = errors.New("track already observed in this frame")
+
+	// ErrRevisionIsDeletion is returned for reverts to a revision that deleted
+	// the annotation.
+	ErrRevisionIsDeletion = errors.New("revision is a deletion")
+
+	// ErrRevisionReferenceGone is returned for reverts to a revision whose
+	// label or track has been deleted since.
+	ErrRevisionReferenceGone = errors.New("revision refers to a deleted label or track")
+)
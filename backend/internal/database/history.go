@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// HistoryRepository reads and restores the revisions that every annotation
+// write appends to the annotation's history.
+type HistoryRepository interface {
+	// GetHistory retrieves the revisions of an annotation of the given point
+	// cloud, oldest first.
+	GetHistory(ctx context.Context, pointCloudID, id string) ([]models.AnnotationRevision, error)
+
+	// Revert restores an annotation of the given point cloud to the state of
+	// one of its revisions, recreating it if it has been deleted since.
+	Revert(ctx context.Context, pointCloudID, id string, revision int) (*models.Annotation, error)
+
+	// GetByIDAsOf retrieves an annotation of the given point cloud as it was
+	// at the given time.
+	GetByIDAsOf(ctx context.Context, pointCloudID, id string, asOf time.Time) (*models.Annotation, error)
+}
+
+type authorKey struct{}
+
+// WithAuthor returns a context attributing the annotation writes made with it
+// to author in the annotations' history.
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// AuthorFrom returns the author attached to the context by WithAuthor.
+func AuthorFrom(ctx context.Context) string {
+	author, _ := ctx.Value(authorKey{}).(string)
+	return author
+}
+
+// recordRevision appends the state of an annotation to its history as part of
+// the transaction that changed it. Revisions of an annotation are numbered
+// from 1; the row lock the write holds on the annotation serializes them.
+func (r *PostgresRepository) recordRevision(ctx context.Context, tx pgx.Tx, operation string, annotation *models.Annotation, revertedFrom *int, at time.Time) error {
+	// Tags are not versioned
+	snapshot := *annotation
+	snapshot.Tags = nil
+
+	query := `
+		INSERT INTO annotation_revisions (annotation_id, revision, point_cloud_id, operation, data, reverted_from, author, created_at)
+		SELECT $1, coalesce(max(revision), 0) + 1, $2, $3, $4, $5, $6, $7
+		FROM annotation_revisions
+		WHERE annotation_id = $1
+	`
+
+	_, err := tx.Exec(ctx, query,
+		annotation.ID,
+		annotation.PointCloudID,
+		operation,
+		snapshot,
+		revertedFrom,
+		AuthorFrom(ctx),
+		at,
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to record annotation revision", zap.String("id", annotation.ID), zap.Error(err))
+		return fmt.Errorf("failed to record annotation revision: %w", err)
+	}
+	return nil
+}
+
+// asOfSource returns a WITH clause that shadows the annotations table with the
+// point cloud's annotations as they were at q.AsOf, or "" for current reads.
+// The reconstructed rows carry every column the annotation queries refer to.
+func asOfSource(b *queryBuilder, pointCloudID string, q *models.AnnotationQuery) string {
+	if q.AsOf == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`WITH annotations AS (
+			SELECT a.id, a.point_cloud_id, a.x, a.y, a.z, a.title, a.description, a.geometry,
+				a.label_id, a.attributes, a.track_id, coalesce(a.version, 1) AS version,
+				a.created_at, a.updated_at, NULL::timestamptz AS deleted_at,
+				a.geometry->>'type' AS geometry_type,
+				cube(ARRAY[a.x, a.y, a.z]) AS position
+			FROM (
+				SELECT DISTINCT ON (annotation_id) operation, data
+				FROM annotation_revisions
+				WHERE point_cloud_id = %s AND created_at <= %s
+				ORDER BY annotation_id, revision DESC
+			) r
+			CROSS JOIN LATERAL jsonb_populate_record(NULL::annotations, r.data) a
+			WHERE r.operation <> '%s'
+		)`, b.arg(pointCloudID), b.arg(*q.AsOf), models.RevisionDelete)
+}
+
+const revisionColumns = `annotation_id, revision, operation, data, reverted_from, author, created_at`
+
+// scanRevision reads a row selected with revisionColumns.
+func scanRevision(row pgx.Row, revision *models.AnnotationRevision) error {
+	return row.Scan(
+		&revision.AnnotationID,
+		&revision.Revision,
+		&revision.Operation,
+		&revision.Annotation,
+		&revision.RevertedFrom,
+		&revision.Author,
+		&revision.CreatedAt,
+	)
+}
+
+// GetHistory retrieves the revisions of an annotation of the given point
+// cloud, oldest first.
+func (r *PostgresRepository) GetHistory(ctx context.Context, pointCloudID, id string) ([]models.AnnotationRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM annotation_revisions WHERE annotation_id = $1 AND point_cloud_id = $2 ORDER BY revision`
+
+	rows, err := r.pool.Query(ctx, query, id, pointCloudID)
+	if err != nil {
+		r.logger.Error("Failed to get annotation history", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get annotation history: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.AnnotationRevision{}
+	for rows.Next() {
+		var revision models.AnnotationRevision
+		if err := scanRevision(rows, &revision); err != nil {
+			r.logger.Error("Failed to scan annotation revision row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan annotation revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetByIDAsOf retrieves an annotation of the given point cloud as it was at
+// the given time. It returns nil if the annotation did not exist then.
+func (r *PostgresRepository) GetByIDAsOf(ctx context.Context, pointCloudID, id string, asOf time.Time) (*models.Annotation, error) {
+	query := `
+		SELECT operation, data
+		FROM annotation_revisions
+		WHERE annotation_id = $1 AND point_cloud_id = $2 AND created_at <= $3
+		ORDER BY revision DESC
+		LIMIT 1
+	`
+
+	var operation string
+	var annotation models.Annotation
+	err := r.pool.QueryRow(ctx, query, id, pointCloudID, asOf).Scan(&operation, &annotation)
+
+	if err == pgx.ErrNoRows || operation == models.RevisionDelete {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get annotation revision", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get annotation revision: %w", err)
+	}
+
+	return &annotation, nil
+}
+
+// Revert restores an annotation of the given point cloud to the state of one
+// of its revisions as a new revision, recreating the annotation if it has been
+// deleted since. It returns nil if the revision does not exist.
+func (r *PostgresRepository) Revert(ctx context.Context, pointCloudID, id string, revision int) (*models.Annotation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var target models.AnnotationRevision
+	err = scanRevision(tx.QueryRow(ctx,
+		`SELECT `+revisionColumns+` FROM annotation_revisions WHERE annotation_id = $1 AND point_cloud_id = $2 AND revision = $3`,
+		id, pointCloudID, revision,
+	), &target)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get annotation revision", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get annotation revision: %w", err)
+	}
+	if target.Operation == models.RevisionDelete {
+		return nil, ErrRevisionIsDeletion
+	}
+
+	annotation := &target.Annotation
+	annotation.UpdatedAt = time.Now().UTC()
+	annotation.DeletedAt = nil
+
+	// An annotation in the trash is restored by the revert, one purged since
+	// is recreated
+	var exists int
+	err = tx.QueryRow(ctx,
+		`SELECT 1 FROM annotations WHERE id = $1 AND point_cloud_id = $2 FOR UPDATE`,
+		id, annotation.PointCloudID,
+	).Scan(&exists)
+	found := err == nil
+	if err == pgx.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get annotation", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+
+	// The label may have changed its geometry types or attributes since the
+	// revision
+	if err := r.checkConforming(ctx, tx, annotation); err != nil {
+		if errors.Is(err, ErrLabelNotFound) {
+			return nil, ErrRevisionReferenceGone
+		}
+		return nil, err
+	}
+
+	// The version keeps increasing across deletion, so that versions read
+	// before the annotation was deleted do not match the recreated one
+	if found {
+		err = tx.QueryRow(ctx, `
+			UPDATE annotations
+			SET x = $2, y = $3, z = $4, title = $5, description = $6, geometry = $7,
+				label_id = $8, attributes = $9, track_id = $10, version = version + 1, updated_at = $11,
+				deleted_at = NULL
+			WHERE id = $1
+			RETURNING version
+		`,
+			annotation.ID,
+			annotation.X,
+			annotation.Y,
+			annotation.Z,
+			annotation.Title,
+			annotation.Description,
+			annotation.Geometry,
+			annotation.LabelID,
+			annotation.Attributes,
+			annotation.TrackID,
+			annotation.UpdatedAt,
+		).Scan(&annotation.Version)
+	} else {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO annotations (id, point_cloud_id, x, y, z, title, description, geometry, label_id, attributes, track_id, version, created_at, updated_at)
+			SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, coalesce(max((data->>'version')::bigint), 0) + 1, $12, $13
+			FROM annotation_revisions
+			WHERE annotation_id = $1
+			RETURNING version
+		`,
+			annotation.ID,
+			annotation.PointCloudID,
+			annotation.X,
+			annotation.Y,
+			annotation.Z,
+			annotation.Title,
+			annotation.Description,
+			annotation.Geometry,
+			annotation.LabelID,
+			annotation.Attributes,
+			annotation.TrackID,
+			annotation.CreatedAt,
+			annotation.UpdatedAt,
+		).Scan(&annotation.Version)
+	}
+	if err != nil {
+		if errors.Is(trackWriteError(err), ErrTrackFrameTaken) {
+			return nil, ErrTrackFrameTaken
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return nil, ErrRevisionReferenceGone
+		}
+		r.logger.Error("Failed to revert annotation", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to revert annotation: %w", err)
+	}
+
+	if err := r.recordRevision(ctx, tx, models.RevisionRevert, annotation, &revision, annotation.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit annotation: %w", err)
+	}
+
+	r.logger.Info("Reverted annotation", zap.String("id", id), zap.Int("revision", revision))
+	return r.GetByID(ctx, annotation.PointCloudID, id)
+}
@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// PostgreSQL error codes of constraint violations.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// LabelRepository manages the label taxonomy.
+type LabelRepository interface {
+	// CreateLabel adds a class to the taxonomy.
+	CreateLabel(ctx context.Context, req *models.CreateLabelRequest) (*models.Label, error)
+
+	// GetLabel retrieves a label by its ID.
+	GetLabel(ctx context.Context, id string) (*models.Label, error)
+
+	// GetAllLabels retrieves the whole taxonomy ordered by name.
+	GetAllLabels(ctx context.Context) ([]models.Label, error)
+
+	// UpdateLabel updates an existing label.
+	UpdateLabel(ctx context.Context, id string, req *models.UpdateLabelRequest) (*models.Label, error)
+
+	// DeleteLabel removes a label that no annotation, track or child label refers to.
+	DeleteLabel(ctx context.Context, id string) error
+}
+
+const labelColumns = `id, name, color, parent_id, geometry_types, attributes, created_at, updated_at`
+
+// scanLabel reads a row selected with labelColumns.
+func scanLabel(row pgx.Row, label *models.Label) error {
+	var geometryTypes []string
+	err := row.Scan(
+		&label.ID,
+		&label.Name,
+		&label.Color,
+		&label.ParentID,
+		&geometryTypes,
+		&label.Attributes,
+		&label.CreatedAt,
+		&label.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	label.GeometryTypes = make([]models.GeometryType, len(geometryTypes))
+	for i, t := range geometryTypes {
+		label.GeometryTypes[i] = models.GeometryType(t)
+	}
+	if label.Attributes == nil {
+		label.Attributes = []models.AttributeDefinition{}
+	}
+	return nil
+}
+
+// labelWriteError translates constraint violations of label writes into the
+// errors reported to clients.
+func labelWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return ErrLabelExists
+		case foreignKeyViolation:
+			return ErrParentLabelNotFound
+		}
+	}
+	return nil
+}
+
+// CreateLabel adds a class to the taxonomy.
+func (r *PostgresRepository) CreateLabel(ctx context.Context, req *models.CreateLabelRequest) (*models.Label, error) {
+	label := &models.Label{
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		Color:         req.Color,
+		ParentID:      req.ParentID,
+		GeometryTypes: req.GeometryTypes,
+		Attributes:    req.Attributes,
+		CreatedAt:     time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
+	}
+	if label.ParentID != nil && *label.ParentID == "" {
+		label.ParentID = nil
+	}
+	if label.GeometryTypes == nil {
+		label.GeometryTypes = []models.GeometryType{}
+	}
+	if label.Attributes == nil {
+		label.Attributes = []models.AttributeDefinition{}
+	}
+
+	query := `
+		INSERT INTO labels (id, name, color, parent_id, geometry_types, attributes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		label.ID,
+		label.Name,
+		label.Color,
+		label.ParentID,
+		geometryTypeNames(label.GeometryTypes),
+		label.Attributes,
+		label.CreatedAt,
+		label.UpdatedAt,
+	)
+
+	if err != nil {
+		if labelErr := labelWriteError(err); labelErr != nil {
+			return nil, labelErr
+		}
+		r.logger.Error("Failed to create label", zap.Error(err))
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+
+	r.logger.Info("Created label", zap.String("id", label.ID), zap.String("name", label.Name))
+	return label, nil
+}
+
+// GetLabel retrieves a label by its ID.
+func (r *PostgresRepository) GetLabel(ctx context.Context, id string) (*models.Label, error) {
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE id = $1`
+
+	var label models.Label
+	err := scanLabel(r.pool.QueryRow(ctx, query, id), &label)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get label", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get label: %w", err)
+	}
+
+	return &label, nil
+}
+
+// checkConforming checks that an annotation written in the transaction
+// conforms to its label, returning ErrNonconforming otherwise. The label is
+// locked until the transaction ends, so that it cannot change in between.
+func (r *PostgresRepository) checkConforming(ctx context.Context, tx pgx.Tx, annotation *models.Annotation) error {
+	if annotation.LabelID == nil {
+		return nil
+	}
+
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE id = $1 FOR SHARE`
+
+	var label models.Label
+	err := scanLabel(tx.QueryRow(ctx, query, *annotation.LabelID), &label)
+
+	if err == pgx.ErrNoRows {
+		return ErrLabelNotFound
+	}
+	if err != nil {
+		r.logger.Error("Failed to get label", zap.String("id", *annotation.LabelID), zap.Error(err))
+		return fmt.Errorf("failed to get label: %w", err)
+	}
+
+	if err := label.Check(annotation.Geometry, annotation.Attributes); err != nil {
+		return fmt.Errorf("%w: %w", ErrNonconforming, err)
+	}
+	return nil
+}
+
+// GetAllLabels retrieves the whole taxonomy ordered by name.
+func (r *PostgresRepository) GetAllLabels(ctx context.Context) ([]models.Label, error) {
+	query := `SELECT ` + labelColumns + ` FROM labels ORDER BY lower(name)`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get labels", zap.Error(err))
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := []models.Label{}
+	for rows.Next() {
+		var label models.Label
+		if err := scanLabel(rows, &label); err != nil {
+			r.logger.Error("Failed to scan label row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, rows.Err()
+}
+
+// UpdateLabel updates an existing label. Moving a label below one of its own
+// descendants is rejected.
+func (r *PostgresRepository) UpdateLabel(ctx context.Context, id string, req *models.UpdateLabelRequest) (*models.Label, error) {
+	existing, err := r.GetLabel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Color != nil {
+		existing.Color = *req.Color
+	}
+	if req.ParentID != nil {
+		existing.ParentID = nil
+		if *req.ParentID != "" {
+			if err := r.checkLabelParent(ctx, id, *req.ParentID); err != nil {
+				return nil, err
+			}
+			existing.ParentID = req.ParentID
+		}
+	}
+	if req.GeometryTypes != nil {
+		existing.GeometryTypes = *req.GeometryTypes
+	}
+	if req.Attributes != nil {
+		existing.Attributes = *req.Attributes
+	}
+	existing.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE labels
+		SET name = $2, color = $3, parent_id = $4, geometry_types = $5, attributes = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	_, err = r.pool.Exec(ctx, query,
+		existing.ID,
+		existing.Name,
+		existing.Color,
+		existing.ParentID,
+		geometryTypeNames(existing.GeometryTypes),
+		existing.Attributes,
+		existing.UpdatedAt,
+	)
+
+	if err != nil {
+		if labelErr := labelWriteError(err); labelErr != nil {
+			return nil, labelErr
+		}
+		r.logger.Error("Failed to update label", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to update label: %w", err)
+	}
+
+	r.logger.Info("Updated label", zap.String("id", id))
+	return existing, nil
+}
+
+// checkLabelParent verifies that parentID exists and is not the label itself
+// or one of its descendants.
+func (r *PostgresRepository) checkLabelParent(ctx context.Context, id, parentID string) error {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM labels WHERE id = $1
+			UNION
+			SELECT l.id, l.parent_id FROM labels l JOIN ancestors a ON l.id = a.parent_id
+		)
+		SELECT count(*) > 0, coalesce(bool_or(id = $2), false) FROM ancestors
+	`
+
+	var exists, cycle bool
+	if err := r.pool.QueryRow(ctx, query, parentID, id).Scan(&exists, &cycle); err != nil {
+		r.logger.Error("Failed to check label parent", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to check label parent: %w", err)
+	}
+
+	if !exists {
+		return ErrParentLabelNotFound
+	}
+	if cycle {
+		return ErrLabelCycle
+	}
+	return nil
+}
+
+// DeleteLabel removes a label that no annotation, track or child label refers to.
+func (r *PostgresRepository) DeleteLabel(ctx context.Context, id string) error {
+	query := `DELETE FROM labels WHERE id = $1`
+
+	result, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return ErrLabelInUse
+		}
+		r.logger.Error("Failed to delete label", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrLabelNotFound
+	}
+
+	r.logger.Info("Deleted label", zap.String("id", id))
+	return nil
+}
+
+func geometryTypeNames(types []models.GeometryType) []string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return names
+}
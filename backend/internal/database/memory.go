@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// MemorySpatialRepository implements SpatialRepository over annotations held
+// in memory. It answers the same queries as the PostgreSQL implementation and
+// is meant for tests.
+type MemorySpatialRepository struct {
+	mu          sync.RWMutex
+	annotations map[string]map[string]models.Annotation
+}
+
+// NewMemorySpatialRepository creates an empty in-memory spatial repository.
+func NewMemorySpatialRepository() *MemorySpatialRepository {
+	return &MemorySpatialRepository{
+		annotations: make(map[string]map[string]models.Annotation),
+	}
+}
+
+// Put adds or replaces an annotation.
+func (m *MemorySpatialRepository) Put(annotation models.Annotation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scene, ok := m.annotations[annotation.PointCloudID]
+	if !ok {
+		scene = make(map[string]models.Annotation)
+		m.annotations[annotation.PointCloudID] = scene
+	}
+	scene[annotation.ID] = annotation
+}
+
+// Remove deletes an annotation.
+func (m *MemorySpatialRepository) Remove(pointCloudID, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.annotations[pointCloudID], id)
+}
+
+// GetWithinBox retrieves annotations inside the axis-aligned box, newest first.
+func (m *MemorySpatialRepository) GetWithinBox(_ context.Context, pointCloudID string, box models.BoundingBox, q *models.AnnotationQuery) ([]models.Annotation, error) {
+	matches := m.filter(pointCloudID, q, func(a *models.Annotation) bool {
+		return box.Contains(a.Position())
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	return truncate(matches, q.Limit), nil
+}
+
+// GetWithinRadius retrieves annotations within the radius of the center, closest first.
+func (m *MemorySpatialRepository) GetWithinRadius(_ context.Context, pointCloudID string, center models.Vec3, radius float64, q *models.AnnotationQuery) ([]models.Annotation, error) {
+	matches := m.filter(pointCloudID, q, func(a *models.Annotation) bool {
+		return a.Position().Distance(center) <= radius
+	})
+
+	sortByDistance(matches, center)
+	return truncate(matches, q.Limit), nil
+}
+
+// GetNearest retrieves the k annotations closest to the center, closest first.
+func (m *MemorySpatialRepository) GetNearest(_ context.Context, pointCloudID string, center models.Vec3, k int, q *models.AnnotationQuery) ([]models.Annotation, error) {
+	matches := m.filter(pointCloudID, q, func(*models.Annotation) bool { return true })
+
+	sortByDistance(matches, center)
+	return truncate(matches, k), nil
+}
+
+// filter returns the annotations of the point cloud that pass both the query
+// filters and the region predicate. Annotations in the trash are skipped, as
+// in the Postgres queries.
+func (m *MemorySpatialRepository) filter(pointCloudID string, q *models.AnnotationQuery, inRegion func(*models.Annotation) bool) []models.Annotation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := []models.Annotation{}
+	for _, annotation := range m.annotations[pointCloudID] {
+		if annotation.DeletedAt == nil && inRegion(&annotation) && q.Matches(&annotation) {
+			matches = append(matches, annotation)
+		}
+	}
+	return matches
+}
+
+// sortByDistance orders annotations by distance to the center, breaking ties by ID.
+func sortByDistance(annotations []models.Annotation, center models.Vec3) {
+	sort.Slice(annotations, func(i, j int) bool {
+		di := annotations[i].Position().Distance(center)
+		dj := annotations[j].Position().Distance(center)
+		if di != dj {
+			return di < dj
+		}
+		return annotations[i].ID < annotations[j].ID
+	})
+}
+
+// truncate returns at most n annotations.
+func truncate(annotations []models.Annotation, n int) []models.Annotation {
+	if len(annotations) > n {
+		return annotations[:n]
+	}
+	return annotations
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func newTestSpatialRepository() *MemorySpatialRepository {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewMemorySpatialRepository()
+	repo.Put(models.Annotation{ID: "origin", PointCloudID: "pc", Title: "car", CreatedAt: base})
+	repo.Put(models.Annotation{ID: "near", PointCloudID: "pc", X: 1, Title: "car", CreatedAt: base.Add(time.Minute)})
+	repo.Put(models.Annotation{ID: "edge", PointCloudID: "pc", X: 2, Y: 2, Z: 2, Title: "tree", CreatedAt: base.Add(2 * time.Minute)})
+	repo.Put(models.Annotation{ID: "far", PointCloudID: "pc", X: 10, Y: 10, Z: 10, Title: "car", CreatedAt: base.Add(3 * time.Minute)})
+	repo.Put(models.Annotation{ID: "other-scene", PointCloudID: "other", Title: "car", CreatedAt: base})
+	return repo
+}
+
+func ids(annotations []models.Annotation) []string {
+	result := make([]string, len(annotations))
+	for i, annotation := range annotations {
+		result[i] = annotation.ID
+	}
+	return result
+}
+
+func TestMemorySpatialRepository_GetWithinBox(t *testing.T) {
+	repo := newTestSpatialRepository()
+	box := models.BoundingBox{Max: models.Vec3{X: 2, Y: 2, Z: 2}}
+
+	found, err := repo.GetWithinBox(context.Background(), "pc", box, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	// Boundary is inclusive; results are newest first
+	assert.Equal(t, []string{"edge", "near", "origin"}, ids(found))
+
+	q := models.NewAnnotationQuery()
+	q.TitlePrefix = "car"
+	q.Limit = 1
+	found, err = repo.GetWithinBox(context.Background(), "pc", box, q)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"near"}, ids(found))
+}
+
+func TestMemorySpatialRepository_GetWithinRadius(t *testing.T) {
+	repo := newTestSpatialRepository()
+
+	found, err := repo.GetWithinRadius(context.Background(), "pc", models.Vec3{X: 0.9}, 1, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"near", "origin"}, ids(found))
+
+	found, err = repo.GetWithinRadius(context.Background(), "pc", models.Vec3{X: 100}, 1, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestMemorySpatialRepository_GetNearest(t *testing.T) {
+	repo := newTestSpatialRepository()
+
+	found, err := repo.GetNearest(context.Background(), "pc", models.Vec3{X: 9, Y: 9, Z: 9}, 2, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"far", "edge"}, ids(found))
+
+	repo.Remove("pc", "far")
+	found, err = repo.GetNearest(context.Background(), "pc", models.Vec3{X: 9, Y: 9, Z: 9}, 10, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"edge", "near", "origin"}, ids(found))
+}
+
+func TestMemorySpatialRepository_SkipsTrash(t *testing.T) {
+	repo := newTestSpatialRepository()
+	deleted := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	repo.Put(models.Annotation{ID: "deleted", PointCloudID: "pc", X: 1, Y: 1, Title: "car", DeletedAt: &deleted})
+
+	found, err := repo.GetWithinBox(context.Background(), "pc", models.BoundingBox{Max: models.Vec3{X: 2, Y: 2, Z: 2}}, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.NotContains(t, ids(found), "deleted")
+
+	found, err = repo.GetWithinRadius(context.Background(), "pc", models.Vec3{X: 1, Y: 1}, 0.5, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+
+	found, err = repo.GetNearest(context.Background(), "pc", models.Vec3{X: 1, Y: 1}, 1, models.NewAnnotationQuery())
+	assert.NoError(t, err)
+	assert.NotEqual(t, []string{"deleted"}, ids(found))
+}
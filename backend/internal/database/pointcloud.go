@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+const pointCloudColumns = `id, name, description, source_url, sequence_id, frame_timestamp, created_at, updated_at`
+
+// scanPointCloud reads a row selected with pointCloudColumns.
+func scanPointCloud(row pgx.Row, pointCloud *models.PointCloud) error {
+	return row.Scan(
+		&pointCloud.ID,
+		&pointCloud.Name,
+		&pointCloud.Description,
+		&pointCloud.SourceURL,
+		&pointCloud.SequenceID,
+		&pointCloud.FrameTimestamp,
+		&pointCloud.CreatedAt,
+		&pointCloud.UpdatedAt,
+	)
+}
+
+// pointCloudWriteError translates constraint violations of point cloud writes
+// into the errors reported to clients.
+func pointCloudWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return ErrSequenceNotFound
+	}
+	return nil
+}
+
+// CreatePointCloud registers a new point cloud.
+func (r *PostgresRepository) CreatePointCloud(ctx context.Context, req *models.CreatePointCloudRequest) (*models.PointCloud, error) {
+	pointCloud := &models.PointCloud{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Description: req.Description,
+		SourceURL:   req.SourceURL,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if req.SequenceID != nil && *req.SequenceID != "" {
+		pointCloud.SequenceID = req.SequenceID
+		pointCloud.FrameTimestamp = req.FrameTimestamp
+	}
+
+	query := `
+		INSERT INTO point_clouds (id, name, description, source_url, sequence_id, frame_timestamp, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		pointCloud.ID,
+		pointCloud.Name,
+		pointCloud.Description,
+		pointCloud.SourceURL,
+		pointCloud.SequenceID,
+		pointCloud.FrameTimestamp,
+		pointCloud.CreatedAt,
+		pointCloud.UpdatedAt,
+	)
+
+	if err != nil {
+		if writeErr := pointCloudWriteError(err); writeErr != nil {
+			return nil, writeErr
+		}
+		r.logger.Error("Failed to create point cloud", zap.Error(err))
+		return nil, fmt.Errorf("failed to create point cloud: %w", err)
+	}
+
+	r.logger.Info("Created point cloud", zap.String("id", pointCloud.ID))
+	return pointCloud, nil
+}
+
+// GetPointCloud retrieves a point cloud by its ID.
+func (r *PostgresRepository) GetPointCloud(ctx context.Context, id string) (*models.PointCloud, error) {
+	query := `
+		SELECT ` + pointCloudColumns + `
+		FROM point_clouds
+		WHERE id = $1
+	`
+
+	var pointCloud models.PointCloud
+	err := scanPointCloud(r.pool.QueryRow(ctx, query, id), &pointCloud)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get point cloud", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get point cloud: %w", err)
+	}
+
+	return &pointCloud, nil
+}
+
+// GetAllPointClouds retrieves all point clouds.
+func (r *PostgresRepository) GetAllPointClouds(ctx context.Context) ([]models.PointCloud, error) {
+	query := `
+		SELECT ` + pointCloudColumns + `
+		FROM point_clouds
+		ORDER BY created_at DESC
+	`
+
+	return r.queryPointClouds(ctx, query)
+}
+
+// queryPointClouds runs a query selecting pointCloudColumns and collects the rows.
+func (r *PostgresRepository) queryPointClouds(ctx context.Context, query string, args ...interface{}) ([]models.PointCloud, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to get point clouds", zap.Error(err))
+		return nil, fmt.Errorf("failed to get point clouds: %w", err)
+	}
+	defer rows.Close()
+
+	var pointClouds []models.PointCloud
+	for rows.Next() {
+		var pointCloud models.PointCloud
+		if err := scanPointCloud(rows, &pointCloud); err != nil {
+			r.logger.Error("Failed to scan point cloud row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan point cloud: %w", err)
+		}
+		pointClouds = append(pointClouds, pointCloud)
+	}
+
+	if pointClouds == nil {
+		pointClouds = []models.PointCloud{}
+	}
+
+	return pointClouds, rows.Err()
+}
+
+// UpdatePointCloud updates an existing point cloud.
+func (r *PostgresRepository) UpdatePointCloud(ctx context.Context, id string, req *models.UpdatePointCloudRequest) (*models.PointCloud, error) {
+	existing, err := r.GetPointCloud(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Description != nil {
+		existing.Description = *req.Description
+	}
+	if req.SourceURL != nil {
+		existing.SourceURL = *req.SourceURL
+	}
+	if req.SequenceID != nil {
+		existing.SequenceID, existing.FrameTimestamp = nil, nil
+		if *req.SequenceID != "" {
+			existing.SequenceID = req.SequenceID
+		}
+	}
+	if req.FrameTimestamp != nil {
+		if existing.SequenceID == nil {
+			return nil, ErrFrameWithoutSequence
+		}
+		existing.FrameTimestamp = req.FrameTimestamp
+	}
+	existing.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE point_clouds
+		SET name = $2, description = $3, source_url = $4, sequence_id = $5, frame_timestamp = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	_, err = r.pool.Exec(ctx, query,
+		existing.ID,
+		existing.Name,
+		existing.Description,
+		existing.SourceURL,
+		existing.SequenceID,
+		existing.FrameTimestamp,
+		existing.UpdatedAt,
+	)
+
+	if err != nil {
+		if writeErr := pointCloudWriteError(err); writeErr != nil {
+			return nil, writeErr
+		}
+		r.logger.Error("Failed to update point cloud", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to update point cloud: %w", err)
+	}
+
+	r.logger.Info("Updated point cloud", zap.String("id", id))
+	return existing, nil
+}
+
+// DeletePointCloud removes a point cloud and, through the foreign key cascade,
+// all of its annotations.
+func (r *PostgresRepository) DeletePointCloud(ctx context.Context, id string) error {
+	query := `DELETE FROM point_clouds WHERE id = $1`
+
+	result, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		r.logger.Error("Failed to delete point cloud", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete point cloud: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrPointCloudNotFound
+	}
+
+	r.logger.Info("Deleted point cloud", zap.String("id", id))
+	return nil
+}
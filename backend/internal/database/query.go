@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// sortColumns maps the annotation sort keys to their columns.
+var sortColumns = map[string]string{
+	models.SortByCreatedAt: "created_at",
+	models.SortByUpdatedAt: "updated_at",
+	models.SortByTitle:     "title",
+}
+
+// queryBuilder accumulates WHERE conditions together with their positional arguments.
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg registers a query argument and returns its placeholder.
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// where adds a condition; all conditions are combined with AND.
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+// whereClause returns the WHERE clause of all conditions added so far.
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// applyAnnotationFilters adds the filter conditions of the query. Annotations
+// in the trash never match.
+func applyAnnotationFilters(b *queryBuilder, q *models.AnnotationQuery) {
+	b.where("deleted_at IS NULL")
+	if q.TitlePrefix != "" {
+		b.where("starts_with(title, " + b.arg(q.TitlePrefix) + ")")
+	}
+	if q.CreatedAfter != nil {
+		b.where("created_at >= " + b.arg(*q.CreatedAfter))
+	}
+	if q.CreatedBefore != nil {
+		b.where("created_at < " + b.arg(*q.CreatedBefore))
+	}
+	if q.UpdatedAfter != nil {
+		b.where("updated_at >= " + b.arg(*q.UpdatedAfter))
+	}
+	if q.UpdatedBefore != nil {
+		b.where("updated_at < " + b.arg(*q.UpdatedBefore))
+	}
+	for _, filter := range q.Attributes {
+		b.where(attributeCondition(b, filter))
+	}
+	if len(q.Tags) > 0 {
+		b.where(tagCondition(b, q.Tags, q.TagMode))
+	}
+}
+
+// tagCondition selects annotations carrying any or all of the tags.
+func tagCondition(b *queryBuilder, tags []string, mode string) string {
+	matching := `
+		SELECT count(*) FROM annotation_tags j JOIN tags t ON t.id = j.tag_id
+		WHERE j.annotation_id = annotations.id AND t.name = ANY(` + b.arg(tags) + `)`
+
+	if mode == models.TagModeAll {
+		return "(" + matching + ") = " + b.arg(len(tags))
+	}
+	return "(" + matching + ") > 0"
+}
+
+// attributeCondition translates an attribute filter into a condition served
+// by the GIN index on attributes: containment for equality and a JSON path
+// predicate for numeric comparisons.
+func attributeCondition(b *queryBuilder, f models.AttributeFilter) string {
+	switch f.Operator {
+	case models.AttributeEqual:
+		return "attributes @> " + b.arg(models.Attributes{f.Name: f.Value}) + "::jsonb"
+	case models.AttributeNotEqual:
+		return "NOT attributes @> " + b.arg(models.Attributes{f.Name: f.Value}) + "::jsonb"
+	}
+
+	// The name is restricted to [a-z0-9_] and the bound is a formatted number,
+	// so the path can be assembled safely
+	path := fmt.Sprintf(`strict $.%q ? (@.type() == "number" && @ %s %s)`,
+		f.Name, f.Operator, strconv.FormatFloat(f.Value.(float64), 'f', -1, 64))
+	return "attributes @? " + b.arg(path) + "::jsonpath"
+}
+
+// applyAnnotationCursor restricts the query to annotations behind the cursor
+// and returns the ORDER BY clause matching the keyset comparison.
+func applyAnnotationCursor(b *queryBuilder, q *models.AnnotationQuery) (string, error) {
+	column, ok := sortColumns[q.SortBy]
+	if !ok {
+		return "", fmt.Errorf("unsupported sort key %q", q.SortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+	if q.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if q.After != nil {
+		var value interface{} = q.After.Value
+		if q.SortBy != models.SortByTitle {
+			t, err := q.After.Time()
+			if err != nil {
+				return "", fmt.Errorf("invalid cursor: %w", err)
+			}
+			value = t
+		}
+
+		b.where(fmt.Sprintf("(%s, id) %s (%s, %s)", column, comparison, b.arg(value), b.arg(q.After.ID)))
+	}
+
+	return fmt.Sprintf("ORDER BY %s %s, id %s", column, direction, direction), nil
+}
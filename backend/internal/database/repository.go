@@ -15,22 +15,57 @@ import (
 	"github.com/pointcloud-annotator/backend/internal/models"
 )
 
-// Repository defines the interface for annotation data operations.
+// legacyPointCloudID is the scene that annotations created before point clouds
+// were introduced are migrated into.
+const legacyPointCloudID = "00000000-0000-0000-0000-000000000001"
+
+// Repository defines the interface for point cloud and annotation data operations.
 type Repository interface {
-	// Create creates a new annotation and returns its ID.
-	Create(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error)
+	// CreatePointCloud registers a new point cloud.
+	CreatePointCloud(ctx context.Context, req *models.CreatePointCloudRequest) (*models.PointCloud, error)
+
+	// GetPointCloud retrieves a point cloud by its ID.
+	GetPointCloud(ctx context.Context, id string) (*models.PointCloud, error)
+
+	// GetAllPointClouds retrieves all point clouds.
+	GetAllPointClouds(ctx context.Context) ([]models.PointCloud, error)
+
+	// UpdatePointCloud updates an existing point cloud.
+	UpdatePointCloud(ctx context.Context, id string, req *models.UpdatePointCloudRequest) (*models.PointCloud, error)
+
+	// DeletePointCloud removes a point cloud and all of its annotations.
+	DeletePointCloud(ctx context.Context, id string) error
+
+	// Create creates a new annotation in the given point cloud.
+	Create(ctx context.Context, pointCloudID string, req *models.CreateAnnotationRequest) (*models.Annotation, error)
 
-	// GetByID retrieves an annotation by its ID.
-	GetByID(ctx context.Context, id string) (*models.Annotation, error)
+	// GetByID retrieves an annotation of the given point cloud by its ID.
+	GetByID(ctx context.Context, pointCloudID, id string) (*models.Annotation, error)
 
-	// GetAll retrieves all annotations.
-	GetAll(ctx context.Context) ([]models.Annotation, error)
+	// GetAll retrieves one page of the given point cloud's annotations.
+	GetAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery) (*models.AnnotationPage, error)
 
-	// Update updates an existing annotation.
-	Update(ctx context.Context, id string, req *models.UpdateAnnotationRequest) (*models.Annotation, error)
+	// Update updates an existing annotation of the given point cloud. If
+	// versions are given, one must be the annotation's current version.
+	Update(ctx context.Context, pointCloudID, id string, req *models.UpdateAnnotationRequest, versions []int64) (*models.Annotation, error)
 
-	// Delete removes an annotation by its ID.
-	Delete(ctx context.Context, id string) error
+	// Delete moves an annotation of the given point cloud to the trash. If
+	// versions are given, one must be the annotation's current version.
+	Delete(ctx context.Context, pointCloudID, id string, versions []int64) error
+
+	SpatialRepository
+	LabelRepository
+	TagRepository
+	SegmentationRepository
+	SequenceRepository
+	TrackRepository
+	HistoryRepository
+	TrashRepository
+	StreamRepository
+	BatchRepository
+
+	// Ping checks that the database answers.
+	Ping(ctx context.Context) error
 
 	// Close closes the database connection.
 	Close()
@@ -80,8 +115,18 @@ func NewPostgresRepository(cfg *config.Config, logger *zap.Logger) (Repository,
 // migrate creates the necessary database tables if they don't exist.
 func (r *PostgresRepository) migrate(ctx context.Context) error {
 	query := `
+		CREATE TABLE IF NOT EXISTS point_clouds (
+			id UUID PRIMARY KEY,
+			name VARCHAR(256) NOT NULL,
+			description VARCHAR(256) DEFAULT '',
+			source_url VARCHAR(1024) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
 		CREATE TABLE IF NOT EXISTS annotations (
 			id UUID PRIMARY KEY,
+			point_cloud_id UUID REFERENCES point_clouds(id) ON DELETE CASCADE,
 			x DOUBLE PRECISION NOT NULL,
 			y DOUBLE PRECISION NOT NULL,
 			z DOUBLE PRECISION NOT NULL,
@@ -92,69 +137,357 @@ func (r *PostgresRepository) migrate(ctx context.Context) error {
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_annotations_created_at ON annotations(created_at);
+
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS point_cloud_id UUID REFERENCES point_clouds(id) ON DELETE CASCADE;
+
+		CREATE INDEX IF NOT EXISTS idx_annotations_point_cloud_id ON annotations(point_cloud_id);
+
+		-- Keyset pagination indexes, one per sort key
+		CREATE INDEX IF NOT EXISTS idx_annotations_page_created_at ON annotations(point_cloud_id, created_at, id);
+		CREATE INDEX IF NOT EXISTS idx_annotations_page_updated_at ON annotations(point_cloud_id, updated_at, id);
+		CREATE INDEX IF NOT EXISTS idx_annotations_page_title ON annotations(point_cloud_id, title, id);
+
+		-- Spatial index over the marker positions
+		CREATE EXTENSION IF NOT EXISTS cube;
+
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS position cube GENERATED ALWAYS AS (cube(ARRAY[x, y, z])) STORED;
+
+		CREATE INDEX IF NOT EXISTS idx_annotations_position ON annotations USING gist(position);
+
+		-- Annotation shape; geometry_type mirrors geometry->>'type' for filtering
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS geometry JSONB NOT NULL DEFAULT '{"type": "point"}';
+
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS geometry_type VARCHAR(32) GENERATED ALWAYS AS (geometry->>'type') STORED;
+
+		-- Label taxonomy; names are unique regardless of case
+		CREATE TABLE IF NOT EXISTS labels (
+			id UUID PRIMARY KEY,
+			name VARCHAR(64) NOT NULL,
+			color VARCHAR(7) NOT NULL,
+			parent_id UUID REFERENCES labels(id) ON DELETE RESTRICT,
+			geometry_types TEXT[] NOT NULL DEFAULT '{}',
+			attributes JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_labels_name ON labels(lower(name));
+
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS label_id UUID REFERENCES labels(id) ON DELETE RESTRICT;
+
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+		CREATE INDEX IF NOT EXISTS idx_annotations_label_id ON annotations(label_id);
+
+		-- Serves attribute containment and JSON path filters
+		CREATE INDEX IF NOT EXISTS idx_annotations_attributes ON annotations USING gin(attributes);
+
+		-- Tags, shared by all point clouds and attached to annotations many-to-many
+		CREATE TABLE IF NOT EXISTS tags (
+			id UUID PRIMARY KEY,
+			name VARCHAR(64) NOT NULL UNIQUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS annotation_tags (
+			annotation_id UUID NOT NULL REFERENCES annotations(id) ON DELETE CASCADE,
+			tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (annotation_id, tag_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_annotation_tags_tag_id ON annotation_tags(tag_id);
+
+		-- Per-point class labels, run-length encoded
+		CREATE TABLE IF NOT EXISTS segmentations (
+			point_cloud_id UUID PRIMARY KEY REFERENCES point_clouds(id) ON DELETE CASCADE,
+			point_count INTEGER NOT NULL,
+			runs BYTEA NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Sequences of LiDAR frames; a frame is a point cloud of a sequence
+		CREATE TABLE IF NOT EXISTS sequences (
+			id UUID PRIMARY KEY,
+			name VARCHAR(256) NOT NULL,
+			description VARCHAR(256) DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		ALTER TABLE point_clouds
+			ADD COLUMN IF NOT EXISTS sequence_id UUID REFERENCES sequences(id) ON DELETE SET NULL;
+
+		ALTER TABLE point_clouds
+			ADD COLUMN IF NOT EXISTS frame_timestamp TIMESTAMP WITH TIME ZONE;
+
+		CREATE INDEX IF NOT EXISTS idx_point_clouds_frames ON point_clouds(sequence_id, frame_timestamp);
+
+		-- Objects tracked across the frames of a sequence
+		CREATE TABLE IF NOT EXISTS tracks (
+			id UUID PRIMARY KEY,
+			sequence_id UUID NOT NULL REFERENCES sequences(id) ON DELETE CASCADE,
+			name VARCHAR(256) DEFAULT '',
+			label_id UUID REFERENCES labels(id) ON DELETE RESTRICT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_tracks_sequence_id ON tracks(sequence_id, created_at);
+
+		ALTER TABLE annotations
+			ADD COLUMN IF NOT EXISTS track_id UUID REFERENCES tracks(id) ON DELETE SET NULL;
+
+		-- Deleted annotations stay in the trash until they are restored or purged
+		ALTER TABLE annotations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+		CREATE INDEX IF NOT EXISTS idx_annotations_trash
+			ON annotations(deleted_at) WHERE deleted_at IS NOT NULL;
+
+		-- A track is observed at most once per frame; annotations in the trash
+		-- do not count
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_annotations_live_track_frame
+			ON annotations(track_id, point_cloud_id) WHERE track_id IS NOT NULL AND deleted_at IS NULL;
+
+		-- Incremented by every change, for optimistic concurrency control
+		ALTER TABLE annotations ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+		-- Append-only history of every annotation write; it outlives deleted
+		-- annotations and goes with their point cloud
+		CREATE TABLE IF NOT EXISTS annotation_revisions (
+			annotation_id UUID NOT NULL,
+			revision INTEGER NOT NULL,
+			point_cloud_id UUID NOT NULL REFERENCES point_clouds(id) ON DELETE CASCADE,
+			operation VARCHAR(16) NOT NULL,
+			data JSONB NOT NULL,
+			reverted_from INTEGER,
+			author VARCHAR(256) NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			PRIMARY KEY (annotation_id, revision)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_annotation_revisions_as_of
+			ON annotation_revisions(point_cloud_id, created_at);
+
+		CREATE OR REPLACE FUNCTION reject_revision_update() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'annotation revisions are append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS annotation_revisions_append_only ON annotation_revisions;
+		CREATE TRIGGER annotation_revisions_append_only
+			BEFORE UPDATE ON annotation_revisions
+			FOR EACH ROW EXECUTE FUNCTION reject_revision_update();
 	`
 
-	_, err := r.pool.Exec(ctx, query)
-	return err
+	if _, err := r.pool.Exec(ctx, query); err != nil {
+		return err
+	}
+
+	if err := r.migrateLegacyAnnotations(ctx); err != nil {
+		return err
+	}
+
+	return r.backfillRevisions(ctx)
+}
+
+// backfillRevisions gives annotations created before their history was
+// recorded a first revision, so that point-in-time reads include them.
+func (r *PostgresRepository) backfillRevisions(ctx context.Context) error {
+	result, err := r.pool.Exec(ctx, `
+		INSERT INTO annotation_revisions (annotation_id, revision, point_cloud_id, operation, data, created_at)
+		SELECT a.id, 1, a.point_cloud_id, $1, to_jsonb(a) - 'position' - 'geometry_type', a.updated_at
+		FROM annotations a
+		WHERE NOT EXISTS (SELECT 1 FROM annotation_revisions r WHERE r.annotation_id = a.id)
+	`, models.RevisionCreate)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() > 0 {
+		r.logger.Info("Recorded initial annotation revisions", zap.Int64("annotations", result.RowsAffected()))
+	}
+	return nil
 }
 
-// Create creates a new annotation.
-func (r *PostgresRepository) Create(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+// migrateLegacyAnnotations moves annotations created before point clouds were
+// introduced into a dedicated scene and makes the point cloud reference mandatory.
+func (r *PostgresRepository) migrateLegacyAnnotations(ctx context.Context) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO point_clouds (id, name, description, source_url)
+		SELECT $1, 'lion_takanawa', 'Annotations created before scenes were introduced',
+			'/potree/pointclouds/lion_takanawa/cloud.js'
+		WHERE EXISTS (SELECT 1 FROM annotations WHERE point_cloud_id IS NULL)
+		ON CONFLICT (id) DO NOTHING
+	`, legacyPointCloudID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() > 0 {
+		r.logger.Info("Migrating legacy annotations", zap.String("point_cloud_id", legacyPointCloudID))
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE annotations SET point_cloud_id = $1 WHERE point_cloud_id IS NULL`, legacyPointCloudID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `ALTER TABLE annotations ALTER COLUMN point_cloud_id SET NOT NULL`); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Create creates a new annotation in the given point cloud.
+func (r *PostgresRepository) Create(ctx context.Context, pointCloudID string, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	annotation := newAnnotation(pointCloudID, req, time.Now().UTC())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := r.insertAnnotation(ctx, tx, annotation); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit annotation: %w", err)
+	}
+
+	r.logger.Info("Created annotation",
+		zap.String("id", annotation.ID),
+		zap.String("point_cloud_id", pointCloudID),
+	)
+	return annotation, nil
+}
+
+// newAnnotation returns the annotation described by a create request, at its
+// first version.
+func newAnnotation(pointCloudID string, req *models.CreateAnnotationRequest, now time.Time) *models.Annotation {
 	annotation := &models.Annotation{
-		ID:          uuid.New().String(),
-		X:           req.X,
-		Y:           req.Y,
-		Z:           req.Z,
-		Title:       req.Title,
-		Description: req.Description,
-		CreatedAt:   time.Now().UTC(),
-		UpdatedAt:   time.Now().UTC(),
+		ID:           uuid.New().String(),
+		PointCloudID: pointCloudID,
+		X:            req.X,
+		Y:            req.Y,
+		Z:            req.Z,
+		Title:        req.Title,
+		Description:  req.Description,
+		Geometry:     models.PointGeometry(),
+		LabelID:      req.LabelID,
+		Attributes:   req.Attributes,
+		Version:      1,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if req.Geometry != nil {
+		annotation.Geometry = *req.Geometry
 	}
+	if annotation.Attributes == nil {
+		annotation.Attributes = models.Attributes{}
+	}
+	if req.TrackID != nil && *req.TrackID != "" {
+		annotation.TrackID = req.TrackID
+	}
+	return annotation
+}
 
+// insertAnnotation inserts a new annotation and records its creation as part
+// of the transaction.
+func (r *PostgresRepository) insertAnnotation(ctx context.Context, tx pgx.Tx, annotation *models.Annotation) error {
 	query := `
-		INSERT INTO annotations (id, x, y, z, title, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO annotations (id, point_cloud_id, x, y, z, title, description, geometry, label_id, attributes, track_id, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	_, err := tx.Exec(ctx, query,
 		annotation.ID,
+		annotation.PointCloudID,
 		annotation.X,
 		annotation.Y,
 		annotation.Z,
 		annotation.Title,
 		annotation.Description,
+		annotation.Geometry,
+		annotation.LabelID,
+		annotation.Attributes,
+		annotation.TrackID,
+		annotation.Version,
 		annotation.CreatedAt,
 		annotation.UpdatedAt,
 	)
 
 	if err != nil {
+		if trackErr := trackWriteError(err); trackErr != nil {
+			return trackErr
+		}
 		r.logger.Error("Failed to create annotation", zap.Error(err))
-		return nil, fmt.Errorf("failed to create annotation: %w", err)
+		return fmt.Errorf("failed to create annotation: %w", err)
 	}
 
-	r.logger.Info("Created annotation", zap.String("id", annotation.ID))
-	return annotation, nil
+	return r.recordRevision(ctx, tx, models.RevisionCreate, annotation, nil, annotation.CreatedAt)
 }
 
-// GetByID retrieves an annotation by its ID.
-func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*models.Annotation, error) {
-	query := `
-		SELECT id, x, y, z, title, description, created_at, updated_at
-		FROM annotations
-		WHERE id = $1
-	`
+// annotationColumns lists the annotation columns in the order scanAnnotation
+// reads them, including the names of the annotation's tags.
+const annotationColumns = `id, point_cloud_id, x, y, z, title, description, geometry, label_id, attributes,
+	ARRAY(
+		SELECT t.name FROM annotation_tags j JOIN tags t ON t.id = j.tag_id
+		WHERE j.annotation_id = annotations.id ORDER BY t.name
+	) AS tags,
+	track_id, version, created_at, updated_at, deleted_at`
+
+// scanAnnotation reads a row selected with annotationColumns.
+func scanAnnotation(row pgx.Row, annotation *models.Annotation) error {
+	return row.Scan(annotationFields(annotation)...)
+}
 
-	var annotation models.Annotation
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+// annotationFields returns the scan destinations of annotationColumns.
+func annotationFields(annotation *models.Annotation) []any {
+	return []any{
 		&annotation.ID,
+		&annotation.PointCloudID,
 		&annotation.X,
 		&annotation.Y,
 		&annotation.Z,
 		&annotation.Title,
 		&annotation.Description,
+		&annotation.Geometry,
+		&annotation.LabelID,
+		&annotation.Attributes,
+		&annotation.Tags,
+		&annotation.TrackID,
+		&annotation.Version,
 		&annotation.CreatedAt,
 		&annotation.UpdatedAt,
-	)
+		&annotation.DeletedAt,
+	}
+}
+
+// GetByID retrieves an annotation of the given point cloud by its ID.
+func (r *PostgresRepository) GetByID(ctx context.Context, pointCloudID, id string) (*models.Annotation, error) {
+	query := `
+		SELECT ` + annotationColumns + `
+		FROM annotations
+		WHERE id = $1 AND point_cloud_id = $2 AND deleted_at IS NULL
+	`
+
+	var annotation models.Annotation
+	err := scanAnnotation(r.pool.QueryRow(ctx, query, id, pointCloudID), &annotation)
 
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -167,15 +500,44 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*models.An
 	return &annotation, nil
 }
 
-// GetAll retrieves all annotations.
-func (r *PostgresRepository) GetAll(ctx context.Context) ([]models.Annotation, error) {
-	query := `
-		SELECT id, x, y, z, title, description, created_at, updated_at
+// GetAll retrieves one page of the given point cloud's annotations. One
+// annotation more than the limit is fetched to detect whether a next page exists.
+func (r *PostgresRepository) GetAll(ctx context.Context, pointCloudID string, q *models.AnnotationQuery) (*models.AnnotationPage, error) {
+	b := &queryBuilder{}
+	b.where("point_cloud_id = " + b.arg(pointCloudID))
+	applyAnnotationFilters(b, q)
+
+	orderBy, err := applyAnnotationCursor(b, q)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT %s
 		FROM annotations
-		ORDER BY created_at DESC
-	`
+		%s
+		%s
+		LIMIT %s
+	`, asOfSource(b, pointCloudID, q), annotationColumns, b.whereClause(), orderBy, b.arg(q.Limit+1))
 
-	rows, err := r.pool.Query(ctx, query)
+	annotations, err := r.queryAnnotations(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AnnotationPage{Annotations: annotations}
+	if len(annotations) > q.Limit {
+		page.Annotations = annotations[:q.Limit]
+		page.NextCursor = models.NewAnnotationCursor(q, &page.Annotations[q.Limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+// queryAnnotations runs a query selecting annotationColumns and collects the rows.
+func (r *PostgresRepository) queryAnnotations(ctx context.Context, query string, args ...interface{}) ([]models.Annotation, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to get annotations", zap.Error(err))
 		return nil, fmt.Errorf("failed to get annotations: %w", err)
@@ -185,23 +547,18 @@ func (r *PostgresRepository) GetAll(ctx context.Context) ([]models.Annotation, e
 	var annotations []models.Annotation
 	for rows.Next() {
 		var annotation models.Annotation
-		err := rows.Scan(
-			&annotation.ID,
-			&annotation.X,
-			&annotation.Y,
-			&annotation.Z,
-			&annotation.Title,
-			&annotation.Description,
-			&annotation.CreatedAt,
-			&annotation.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanAnnotation(rows, &annotation); err != nil {
 			r.logger.Error("Failed to scan annotation row", zap.Error(err))
 			return nil, fmt.Errorf("failed to scan annotation: %w", err)
 		}
 		annotations = append(annotations, annotation)
 	}
 
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to read annotation rows", zap.Error(err))
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+
 	if annotations == nil {
 		annotations = []models.Annotation{}
 	}
@@ -209,78 +566,186 @@ func (r *PostgresRepository) GetAll(ctx context.Context) ([]models.Annotation, e
 	return annotations, nil
 }
 
-// Update updates an existing annotation.
-func (r *PostgresRepository) Update(ctx context.Context, id string, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
-	// First, get the existing annotation
-	existing, err := r.GetByID(ctx, id)
+// Update updates an existing annotation of the given point cloud in a single
+// conditional statement. If versions are given, one must be the annotation's
+// current version, otherwise models.ErrVersionMismatch is returned.
+func (r *PostgresRepository) Update(ctx context.Context, pointCloudID, id string, req *models.UpdateAnnotationRequest, versions []int64) (*models.Annotation, error) {
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return nil, err
-	}
-	if existing == nil {
-		return nil, nil
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Apply updates
-	if req.X != nil {
-		existing.X = *req.X
-	}
-	if req.Y != nil {
-		existing.Y = *req.Y
+	annotation, err := r.updateAnnotation(ctx, tx, pointCloudID, id, req, versions)
+	if err != nil || annotation == nil {
+		return nil, err
 	}
-	if req.Z != nil {
-		existing.Z = *req.Z
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit annotation: %w", err)
 	}
-	if req.Title != nil {
-		existing.Title = *req.Title
+
+	r.logger.Info("Updated annotation", zap.String("id", id), zap.Int64("version", annotation.Version))
+	return annotation, nil
+}
+
+// updateAnnotation applies an update request and records it as part of the
+// transaction. It returns nil if there is no such annotation. An update of the
+// label, geometry or attributes is checked against the label in the same
+// transaction, so that the check sees the annotation it changes.
+func (r *PostgresRepository) updateAnnotation(ctx context.Context, tx pgx.Tx, pointCloudID, id string, req *models.UpdateAnnotationRequest, versions []int64) (*models.Annotation, error) {
+	// Fields left out of the request are passed as NULL and keep their value;
+	// an empty label or track ID clears the reference
+	var geometry, attributes any
+	if req.Geometry != nil {
+		geometry = *req.Geometry
 	}
-	if req.Description != nil {
-		existing.Description = *req.Description
+	if req.Attributes != nil {
+		attributes = req.Attributes
 	}
-	existing.UpdatedAt = time.Now().UTC()
+	x, y, z := req.Position()
 
 	query := `
 		UPDATE annotations
-		SET x = $2, y = $3, z = $4, title = $5, description = $6, updated_at = $7
-		WHERE id = $1
-	`
+		SET x = coalesce($4, x),
+			y = coalesce($5, y),
+			z = coalesce($6, z),
+			title = coalesce($7, title),
+			description = coalesce($8, description),
+			geometry = coalesce($9, geometry),
+			label_id = CASE WHEN $10::text IS NULL THEN label_id ELSE nullif($10, '')::uuid END,
+			attributes = coalesce($11, attributes),
+			track_id = CASE WHEN $12::text IS NULL THEN track_id ELSE nullif($12, '')::uuid END,
+			version = version + 1,
+			updated_at = $13
+		WHERE id = $1 AND point_cloud_id = $2 AND deleted_at IS NULL
+			AND ` + versionCondition + `
+		RETURNING ` + annotationColumns
 
-	_, err = r.pool.Exec(ctx, query,
-		existing.ID,
-		existing.X,
-		existing.Y,
-		existing.Z,
-		existing.Title,
-		existing.Description,
-		existing.UpdatedAt,
-	)
+	var annotation models.Annotation
+	err := scanAnnotation(tx.QueryRow(ctx, query,
+		id,
+		pointCloudID,
+		versions,
+		x,
+		y,
+		z,
+		req.Title,
+		req.Description,
+		geometry,
+		req.LabelID,
+		attributes,
+		req.TrackID,
+		time.Now().UTC(),
+	), &annotation)
 
+	if err == pgx.ErrNoRows {
+		return nil, r.versionMismatch(ctx, tx, pointCloudID, id, versions)
+	}
 	if err != nil {
+		if trackErr := trackWriteError(err); trackErr != nil {
+			return nil, trackErr
+		}
 		r.logger.Error("Failed to update annotation", zap.String("id", id), zap.Error(err))
 		return nil, fmt.Errorf("failed to update annotation: %w", err)
 	}
 
-	r.logger.Info("Updated annotation", zap.String("id", id))
-	return existing, nil
+	if req.LabelID != nil || req.Geometry != nil || req.Attributes != nil {
+		if err := r.checkConforming(ctx, tx, &annotation); err != nil {
+			return nil, err
+		}
+	}
+
+	// Moving a cuboid's position without its geometry would leave the box
+	// behind
+	if err := annotation.Geometry.CheckPosition(annotation.Position()); err != nil {
+		return nil, err
+	}
+
+	if err := r.recordRevision(ctx, tx, models.RevisionUpdate, &annotation, nil, annotation.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &annotation, nil
 }
 
-// Delete removes an annotation by its ID.
-func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM annotations WHERE id = $1`
+// versionCondition restricts a conditional write, given the versions it
+// applies to as its third argument, to annotations having one of them. No
+// versions apply it to any version.
+const versionCondition = `(coalesce(cardinality($3::bigint[]), 0) = 0 OR version = ANY($3::bigint[]))`
 
-	result, err := r.pool.Exec(ctx, query, id)
+// versionMismatch explains why a conditional write matched no annotation: it
+// returns models.ErrVersionMismatch if the annotation exists, nil otherwise.
+func (r *PostgresRepository) versionMismatch(ctx context.Context, tx pgx.Tx, pointCloudID, id string, versions []int64) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	found, err := lockAnnotation(ctx, tx, pointCloudID, id)
 	if err != nil {
-		r.logger.Error("Failed to delete annotation", zap.String("id", id), zap.Error(err))
-		return fmt.Errorf("failed to delete annotation: %w", err)
+		r.logger.Error("Failed to get annotation", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to get annotation: %w", err)
+	}
+	if found {
+		return models.ErrVersionMismatch
+	}
+	return nil
+}
+
+// Delete moves an annotation of the given point cloud to the trash, from
+// where it can be restored until it is purged. If versions are given, one
+// must be the annotation's current version, otherwise models.ErrVersionMismatch
+// is returned. Its history keeps the state it was deleted in.
+func (r *PostgresRepository) Delete(ctx context.Context, pointCloudID, id string, versions []int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := r.deleteAnnotation(ctx, tx, pointCloudID, id, versions); err != nil {
+		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("annotation not found")
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit annotation deletion: %w", err)
 	}
 
-	r.logger.Info("Deleted annotation", zap.String("id", id))
+	r.logger.Info("Moved annotation to trash", zap.String("id", id))
 	return nil
 }
 
+// deleteAnnotation moves an annotation to the trash and records its deletion
+// as part of the transaction.
+func (r *PostgresRepository) deleteAnnotation(ctx context.Context, tx pgx.Tx, pointCloudID, id string, versions []int64) error {
+	query := `
+		UPDATE annotations
+		SET deleted_at = $4, version = version + 1
+		WHERE id = $1 AND point_cloud_id = $2 AND deleted_at IS NULL
+			AND ` + versionCondition + `
+		RETURNING ` + annotationColumns
+
+	var existing models.Annotation
+	err := scanAnnotation(tx.QueryRow(ctx, query, id, pointCloudID, versions, time.Now().UTC()), &existing)
+
+	if err == pgx.ErrNoRows {
+		if err := r.versionMismatch(ctx, tx, pointCloudID, id, versions); err != nil {
+			return err
+		}
+		return ErrAnnotationNotFound
+	}
+	if err != nil {
+		r.logger.Error("Failed to delete annotation", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete annotation: %w", err)
+	}
+
+	return r.recordRevision(ctx, tx, models.RevisionDelete, &existing, nil, *existing.DeletedAt)
+}
+
+// Ping checks that the database answers.
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
 // Close closes the database connection pool.
 func (r *PostgresRepository) Close() {
 	r.pool.Close()
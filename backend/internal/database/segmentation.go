@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// SegmentationRepository stores the per-point class labels of point clouds.
+type SegmentationRepository interface {
+	// GetSegmentation retrieves the segmentation of a point cloud.
+	GetSegmentation(ctx context.Context, pointCloudID string) (*models.Segmentation, error)
+
+	// PutSegmentation creates or replaces the segmentation of a point cloud.
+	PutSegmentation(ctx context.Context, segmentation *models.Segmentation) error
+
+	// PatchSegmentation applies a sparse patch to the segmentation of a point cloud.
+	PatchSegmentation(ctx context.Context, pointCloudID string, patch *models.SegmentationPatch) (*models.Segmentation, error)
+}
+
+// segmentationQuerier is implemented by both the pool and transactions.
+type segmentationQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// getSegmentation reads a segmentation, optionally locking its row.
+func getSegmentation(ctx context.Context, q segmentationQuerier, pointCloudID string, forUpdate bool) (*models.Segmentation, error) {
+	query := `
+		SELECT point_count, runs, updated_at
+		FROM segmentations
+		WHERE point_cloud_id = $1
+	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	segmentation := &models.Segmentation{PointCloudID: pointCloudID}
+	var data []byte
+	err := q.QueryRow(ctx, query, pointCloudID).Scan(&segmentation.PointCount, &data, &segmentation.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	segmentation.Runs, err = models.DecodeRuns(data)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt segmentation: %w", err)
+	}
+	return segmentation, nil
+}
+
+// GetSegmentation retrieves the segmentation of a point cloud.
+func (r *PostgresRepository) GetSegmentation(ctx context.Context, pointCloudID string) (*models.Segmentation, error) {
+	segmentation, err := getSegmentation(ctx, r.pool, pointCloudID, false)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get segmentation", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get segmentation: %w", err)
+	}
+
+	return segmentation, nil
+}
+
+// PutSegmentation creates or replaces the segmentation of a point cloud.
+func (r *PostgresRepository) PutSegmentation(ctx context.Context, segmentation *models.Segmentation) error {
+	segmentation.UpdatedAt = time.Now().UTC()
+
+	query := `
+		INSERT INTO segmentations (point_cloud_id, point_count, runs, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (point_cloud_id) DO UPDATE
+		SET point_count = EXCLUDED.point_count, runs = EXCLUDED.runs, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		segmentation.PointCloudID,
+		segmentation.PointCount,
+		models.EncodeRuns(segmentation.Runs),
+		segmentation.UpdatedAt,
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to store segmentation", zap.String("point_cloud_id", segmentation.PointCloudID), zap.Error(err))
+		return fmt.Errorf("failed to store segmentation: %w", err)
+	}
+
+	r.logger.Info("Stored segmentation",
+		zap.String("point_cloud_id", segmentation.PointCloudID),
+		zap.Int("point_count", segmentation.PointCount),
+		zap.Int("runs", len(segmentation.Runs)),
+	)
+	return nil
+}
+
+// PatchSegmentation applies a sparse patch to the segmentation of a point
+// cloud. The row stays locked between reading and writing it back, so
+// concurrent patches are applied one after the other.
+func (r *PostgresRepository) PatchSegmentation(ctx context.Context, pointCloudID string, patch *models.SegmentationPatch) (*models.Segmentation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	segmentation, err := getSegmentation(ctx, tx, pointCloudID, true)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get segmentation", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get segmentation: %w", err)
+	}
+
+	if err := segmentation.Apply(patch); err != nil {
+		return nil, err
+	}
+	segmentation.UpdatedAt = time.Now().UTC()
+
+	_, err = tx.Exec(ctx,
+		`UPDATE segmentations SET runs = $2, updated_at = $3 WHERE point_cloud_id = $1`,
+		pointCloudID, models.EncodeRuns(segmentation.Runs), segmentation.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to patch segmentation", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		return nil, fmt.Errorf("failed to patch segmentation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit segmentation: %w", err)
+	}
+
+	r.logger.Info("Patched segmentation", zap.String("point_cloud_id", pointCloudID), zap.Int("runs", len(segmentation.Runs)))
+	return segmentation, nil
+}
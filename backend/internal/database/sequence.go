@@ -0,0 +1,210 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// SequenceRepository manages sequences of frames.
+type SequenceRepository interface {
+	// CreateSequence creates a new, empty sequence.
+	CreateSequence(ctx context.Context, req *models.CreateSequenceRequest) (*models.Sequence, error)
+
+	// GetSequence retrieves a sequence by its ID.
+	GetSequence(ctx context.Context, id string) (*models.Sequence, error)
+
+	// GetAllSequences retrieves all sequences.
+	GetAllSequences(ctx context.Context) ([]models.Sequence, error)
+
+	// UpdateSequence updates an existing sequence.
+	UpdateSequence(ctx context.Context, id string, req *models.UpdateSequenceRequest) (*models.Sequence, error)
+
+	// DeleteSequence removes a sequence and its tracks, keeping its frames as
+	// standalone point clouds.
+	DeleteSequence(ctx context.Context, id string) error
+
+	// GetFrames retrieves the point clouds of a sequence ordered by frame timestamp.
+	GetFrames(ctx context.Context, sequenceID string) ([]models.PointCloud, error)
+}
+
+const sequenceColumns = `id, name, description, created_at, updated_at`
+
+// scanSequence reads a row selected with sequenceColumns.
+func scanSequence(row pgx.Row, sequence *models.Sequence) error {
+	return row.Scan(
+		&sequence.ID,
+		&sequence.Name,
+		&sequence.Description,
+		&sequence.CreatedAt,
+		&sequence.UpdatedAt,
+	)
+}
+
+// CreateSequence creates a new, empty sequence.
+func (r *PostgresRepository) CreateSequence(ctx context.Context, req *models.CreateSequenceRequest) (*models.Sequence, error) {
+	sequence := &models.Sequence{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	query := `
+		INSERT INTO sequences (id, name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		sequence.ID,
+		sequence.Name,
+		sequence.Description,
+		sequence.CreatedAt,
+		sequence.UpdatedAt,
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to create sequence", zap.Error(err))
+		return nil, fmt.Errorf("failed to create sequence: %w", err)
+	}
+
+	r.logger.Info("Created sequence", zap.String("id", sequence.ID))
+	return sequence, nil
+}
+
+// GetSequence retrieves a sequence by its ID.
+func (r *PostgresRepository) GetSequence(ctx context.Context, id string) (*models.Sequence, error) {
+	query := `SELECT ` + sequenceColumns + ` FROM sequences WHERE id = $1`
+
+	var sequence models.Sequence
+	err := scanSequence(r.pool.QueryRow(ctx, query, id), &sequence)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get sequence", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get sequence: %w", err)
+	}
+
+	return &sequence, nil
+}
+
+// GetAllSequences retrieves all sequences.
+func (r *PostgresRepository) GetAllSequences(ctx context.Context) ([]models.Sequence, error) {
+	query := `SELECT ` + sequenceColumns + ` FROM sequences ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get sequences", zap.Error(err))
+		return nil, fmt.Errorf("failed to get sequences: %w", err)
+	}
+	defer rows.Close()
+
+	sequences := []models.Sequence{}
+	for rows.Next() {
+		var sequence models.Sequence
+		if err := scanSequence(rows, &sequence); err != nil {
+			r.logger.Error("Failed to scan sequence row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan sequence: %w", err)
+		}
+		sequences = append(sequences, sequence)
+	}
+
+	return sequences, rows.Err()
+}
+
+// UpdateSequence updates an existing sequence.
+func (r *PostgresRepository) UpdateSequence(ctx context.Context, id string, req *models.UpdateSequenceRequest) (*models.Sequence, error) {
+	existing, err := r.GetSequence(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Description != nil {
+		existing.Description = *req.Description
+	}
+	existing.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE sequences
+		SET name = $2, description = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	_, err = r.pool.Exec(ctx, query, existing.ID, existing.Name, existing.Description, existing.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Failed to update sequence", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to update sequence: %w", err)
+	}
+
+	r.logger.Info("Updated sequence", zap.String("id", id))
+	return existing, nil
+}
+
+// DeleteSequence removes a sequence and, through the foreign key cascade, its
+// tracks. Its frames are kept as standalone point clouds.
+func (r *PostgresRepository) DeleteSequence(ctx context.Context, id string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// The foreign key only clears sequence_id; the frame timestamps go as well
+	_, err = tx.Exec(ctx, `
+		UPDATE point_clouds SET sequence_id = NULL, frame_timestamp = NULL
+		WHERE sequence_id = $1
+	`, id)
+	if err != nil {
+		r.logger.Error("Failed to detach frames", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete sequence: %w", err)
+	}
+
+	// Deleting the tracks unlinks their annotations, which changes them
+	if err := r.unlinkTracks(ctx, tx, `sequence_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete sequence: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM sequences WHERE id = $1`, id)
+	if err != nil {
+		r.logger.Error("Failed to delete sequence", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete sequence: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSequenceNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit sequence deletion: %w", err)
+	}
+
+	r.logger.Info("Deleted sequence", zap.String("id", id))
+	return nil
+}
+
+// GetFrames retrieves the point clouds of a sequence ordered by frame timestamp.
+func (r *PostgresRepository) GetFrames(ctx context.Context, sequenceID string) ([]models.PointCloud, error) {
+	query := `
+		SELECT ` + pointCloudColumns + `
+		FROM point_clouds
+		WHERE sequence_id = $1
+		ORDER BY frame_timestamp, id
+	`
+
+	return r.queryPointClouds(ctx, query, sequenceID)
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// SpatialRepository defines region queries over annotation positions. The
+// filters of the annotation query apply; its sort order and cursor do not.
+type SpatialRepository interface {
+	// GetWithinBox retrieves annotations inside the axis-aligned box, newest first.
+	GetWithinBox(ctx context.Context, pointCloudID string, box models.BoundingBox, query *models.AnnotationQuery) ([]models.Annotation, error)
+
+	// GetWithinRadius retrieves annotations within the radius of the center, closest first.
+	GetWithinRadius(ctx context.Context, pointCloudID string, center models.Vec3, radius float64, query *models.AnnotationQuery) ([]models.Annotation, error)
+
+	// GetNearest retrieves the k annotations closest to the center, closest first.
+	GetNearest(ctx context.Context, pointCloudID string, center models.Vec3, k int, query *models.AnnotationQuery) ([]models.Annotation, error)
+}
+
+// cubePoint returns the SQL expression of a cube point at the given position.
+func cubePoint(b *queryBuilder, p models.Vec3) string {
+	return "cube(" + b.arg([]float64{p.X, p.Y, p.Z}) + "::float8[])"
+}
+
+// cubeBox returns the SQL expression of a cube spanning the given box.
+func cubeBox(b *queryBuilder, box models.BoundingBox) string {
+	return fmt.Sprintf("cube(%s::float8[], %s::float8[])",
+		b.arg([]float64{box.Min.X, box.Min.Y, box.Min.Z}),
+		b.arg([]float64{box.Max.X, box.Max.Y, box.Max.Z}),
+	)
+}
+
+// GetWithinBox retrieves annotations inside the axis-aligned box, newest first.
+func (r *PostgresRepository) GetWithinBox(ctx context.Context, pointCloudID string, box models.BoundingBox, q *models.AnnotationQuery) ([]models.Annotation, error) {
+	b := &queryBuilder{}
+	b.where("point_cloud_id = " + b.arg(pointCloudID))
+	b.where("position <@ " + cubeBox(b, box))
+	applyAnnotationFilters(b, q)
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT %s
+		FROM annotations
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT %s
+	`, asOfSource(b, pointCloudID, q), annotationColumns, b.whereClause(), b.arg(q.Limit))
+
+	return r.queryAnnotations(ctx, query, b.args...)
+}
+
+// GetWithinRadius retrieves annotations within the radius of the center,
+// closest first. The enclosing box lets the GiST index prune candidates before
+// the exact distance check.
+func (r *PostgresRepository) GetWithinRadius(ctx context.Context, pointCloudID string, center models.Vec3, radius float64, q *models.AnnotationQuery) ([]models.Annotation, error) {
+	box := models.BoundingBox{
+		Min: models.Vec3{X: center.X - radius, Y: center.Y - radius, Z: center.Z - radius},
+		Max: models.Vec3{X: center.X + radius, Y: center.Y + radius, Z: center.Z + radius},
+	}
+
+	b := &queryBuilder{}
+	b.where("point_cloud_id = " + b.arg(pointCloudID))
+	b.where("position <@ " + cubeBox(b, box))
+	point := cubePoint(b, center)
+	b.where(fmt.Sprintf("position <-> %s <= %s", point, b.arg(radius)))
+	applyAnnotationFilters(b, q)
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT %s
+		FROM annotations
+		%s
+		ORDER BY position <-> %s, id
+		LIMIT %s
+	`, asOfSource(b, pointCloudID, q), annotationColumns, b.whereClause(), point, b.arg(q.Limit))
+
+	return r.queryAnnotations(ctx, query, b.args...)
+}
+
+// GetNearest retrieves the k annotations closest to the center using the
+// GiST index's nearest-neighbor ordering.
+func (r *PostgresRepository) GetNearest(ctx context.Context, pointCloudID string, center models.Vec3, k int, q *models.AnnotationQuery) ([]models.Annotation, error) {
+	b := &queryBuilder{}
+	b.where("point_cloud_id = " + b.arg(pointCloudID))
+	applyAnnotationFilters(b, q)
+	point := cubePoint(b, center)
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT %s
+		FROM annotations
+		%s
+		ORDER BY position <-> %s, id
+		LIMIT %s
+	`, asOfSource(b, pointCloudID, q), annotationColumns, b.whereClause(), point, b.arg(k))
+
+	return r.queryAnnotations(ctx, query, b.args...)
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// StreamRepository reads annotations row by row, for responses too large to
+// collect in memory.
+type StreamRepository interface {
+	// StreamAll calls fn with each of the point cloud's annotations matching
+	// the query, in its order. The query's limit is ignored. An error returned
+	// by fn ends the stream and is returned.
+	StreamAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery, fn func(*models.Annotation) error) error
+
+	// GetAttributeColumns returns the attributes the point cloud's
+	// annotations carry, ordered by name.
+	GetAttributeColumns(ctx context.Context, pointCloudID string) ([]models.AttributeColumn, error)
+}
+
+// StreamAll calls fn with each of the point cloud's annotations matching the
+// query as its row arrives, so that only one annotation is held at a time.
+func (r *PostgresRepository) StreamAll(ctx context.Context, pointCloudID string, q *models.AnnotationQuery, fn func(*models.Annotation) error) error {
+	b := &queryBuilder{}
+	b.where("point_cloud_id = " + b.arg(pointCloudID))
+	applyAnnotationFilters(b, q)
+
+	orderBy, err := applyAnnotationCursor(b, q)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT %s
+		FROM annotations
+		%s
+		%s
+	`, asOfSource(b, pointCloudID, q), annotationColumns, b.whereClause(), orderBy)
+
+	rows, err := r.pool.Query(ctx, query, b.args...)
+	if err != nil {
+		r.logger.Error("Failed to stream annotations", zap.Error(err))
+		return fmt.Errorf("failed to get annotations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var annotation models.Annotation
+		if err := scanAnnotation(rows, &annotation); err != nil {
+			r.logger.Error("Failed to scan annotation row", zap.Error(err))
+			return fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		if err := fn(&annotation); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to read annotation rows", zap.Error(err))
+		return fmt.Errorf("failed to get annotations: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttributeColumns returns the attributes the point cloud's annotations
+// carry. An attribute is typed bool or number if all of its values are, and
+// string otherwise.
+func (r *PostgresRepository) GetAttributeColumns(ctx context.Context, pointCloudID string) ([]models.AttributeColumn, error) {
+	query := `
+		SELECT key, CASE WHEN count(DISTINCT jsonb_typeof(value)) = 1 THEN min(jsonb_typeof(value)) END
+		FROM annotations, jsonb_each(attributes)
+		WHERE point_cloud_id = $1 AND deleted_at IS NULL
+		GROUP BY key
+		ORDER BY key
+	`
+
+	rows, err := r.pool.Query(ctx, query, pointCloudID)
+	if err != nil {
+		r.logger.Error("Failed to get attribute columns", zap.Error(err))
+		return nil, fmt.Errorf("failed to get attribute columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []models.AttributeColumn
+	for rows.Next() {
+		var name string
+		var valueType *string
+		if err := rows.Scan(&name, &valueType); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute column: %w", err)
+		}
+
+		column := models.AttributeColumn{Name: name, Type: models.AttributeString}
+		if valueType != nil {
+			switch *valueType {
+			case "boolean":
+				column.Type = models.AttributeBool
+			case "number":
+				column.Type = models.AttributeNumber
+			}
+		}
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get attribute columns: %w", err)
+	}
+
+	return columns, nil
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// TagRepository manages the tags attached to annotations.
+type TagRepository interface {
+	// AddTags attaches tags to an annotation of the given point cloud,
+	// creating tags that do not exist yet.
+	AddTags(ctx context.Context, pointCloudID, id string, tags []string) (*models.Annotation, error)
+
+	// RemoveTag detaches a tag from an annotation of the given point cloud.
+	RemoveTag(ctx context.Context, pointCloudID, id, tag string) (*models.Annotation, error)
+
+	// GetTagCounts retrieves the tags used in the given point cloud together
+	// with the number of annotations carrying each.
+	GetTagCounts(ctx context.Context, pointCloudID string) ([]models.TagCount, error)
+}
+
+// lockAnnotation locks an annotation of the given point cloud for the rest of
+// the transaction, reporting whether it exists outside the trash.
+func lockAnnotation(ctx context.Context, tx pgx.Tx, pointCloudID, id string) (bool, error) {
+	var found int
+	err := tx.QueryRow(ctx,
+		`SELECT 1 FROM annotations WHERE id = $1 AND point_cloud_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		id, pointCloudID,
+	).Scan(&found)
+
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// AddTags attaches tags to an annotation of the given point cloud, creating
+// tags that do not exist yet. Tags the annotation already carries are ignored.
+func (r *PostgresRepository) AddTags(ctx context.Context, pointCloudID, id string, tags []string) (*models.Annotation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	found, err := lockAnnotation(ctx, tx, pointCloudID, id)
+	if err != nil {
+		r.logger.Error("Failed to get annotation", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	for _, tag := range tags {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO tags (id, name) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+			uuid.New().String(), tag,
+		)
+		if err != nil {
+			r.logger.Error("Failed to create tag", zap.String("tag", tag), zap.Error(err))
+			return nil, fmt.Errorf("failed to create tag: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO annotation_tags (annotation_id, tag_id)
+		SELECT $1, id FROM tags WHERE name = ANY($2)
+		ON CONFLICT DO NOTHING
+	`, id, tags)
+	if err != nil {
+		r.logger.Error("Failed to tag annotation", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to tag annotation: %w", err)
+	}
+
+	// Tags are part of the annotation, so attaching one changes its version
+	if result.RowsAffected() > 0 {
+		if _, err := tx.Exec(ctx, `UPDATE annotations SET version = version + 1 WHERE id = $1`, id); err != nil {
+			r.logger.Error("Failed to update annotation version", zap.String("id", id), zap.Error(err))
+			return nil, fmt.Errorf("failed to update annotation version: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit tags: %w", err)
+	}
+
+	r.logger.Info("Tagged annotation", zap.String("id", id), zap.Strings("tags", tags))
+	return r.GetByID(ctx, pointCloudID, id)
+}
+
+// RemoveTag detaches a tag from an annotation of the given point cloud,
+// changing its version. Removing a tag the annotation does not carry is not
+// an error.
+func (r *PostgresRepository) RemoveTag(ctx context.Context, pointCloudID, id, tag string) (*models.Annotation, error) {
+	query := `
+		WITH removed AS (
+			DELETE FROM annotation_tags j
+			USING tags t, annotations a
+			WHERE j.tag_id = t.id AND j.annotation_id = a.id
+				AND a.id = $1 AND a.point_cloud_id = $2 AND a.deleted_at IS NULL AND t.name = $3
+			RETURNING j.annotation_id
+		)
+		UPDATE annotations SET version = version + 1
+		WHERE id IN (SELECT annotation_id FROM removed)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, pointCloudID, tag); err != nil {
+		r.logger.Error("Failed to untag annotation", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to untag annotation: %w", err)
+	}
+
+	r.logger.Info("Untagged annotation", zap.String("id", id), zap.String("tag", tag))
+	return r.GetByID(ctx, pointCloudID, id)
+}
+
+// GetTagCounts retrieves the tags used in the given point cloud together with
+// the number of annotations carrying each, most used first.
+func (r *PostgresRepository) GetTagCounts(ctx context.Context, pointCloudID string) ([]models.TagCount, error) {
+	query := `
+		SELECT t.name, count(*)
+		FROM annotation_tags j
+		JOIN tags t ON t.id = j.tag_id
+		JOIN annotations a ON a.id = j.annotation_id
+		WHERE a.point_cloud_id = $1 AND a.deleted_at IS NULL
+		GROUP BY t.name
+		ORDER BY count(*) DESC, t.name
+	`
+
+	rows, err := r.pool.Query(ctx, query, pointCloudID)
+	if err != nil {
+		r.logger.Error("Failed to get tag counts", zap.Error(err))
+		return nil, fmt.Errorf("failed to get tag counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var count models.TagCount
+		if err := rows.Scan(&count.Name, &count.Count); err != nil {
+			r.logger.Error("Failed to scan tag count row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
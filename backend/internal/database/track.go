@@ -0,0 +1,324 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// TrackRepository manages objects tracked across the frames of a sequence.
+type TrackRepository interface {
+	// CreateTrack creates a new track in a sequence.
+	CreateTrack(ctx context.Context, req *models.CreateTrackRequest) (*models.Track, error)
+
+	// GetTrack retrieves a track by its ID.
+	GetTrack(ctx context.Context, id string) (*models.Track, error)
+
+	// GetAllTracks retrieves the tracks of a sequence, or of all sequences
+	// when sequenceID is empty.
+	GetAllTracks(ctx context.Context, sequenceID string) ([]models.Track, error)
+
+	// UpdateTrack updates an existing track.
+	UpdateTrack(ctx context.Context, id string, req *models.UpdateTrackRequest) (*models.Track, error)
+
+	// DeleteTrack removes a track, unlinking the annotations observing it.
+	DeleteTrack(ctx context.Context, id string) error
+
+	// GetTrackAnnotations retrieves the observations of a track in the frames
+	// of its sequence, ordered by frame timestamp.
+	GetTrackAnnotations(ctx context.Context, id string) ([]models.TrackObservation, error)
+}
+
+const trackColumns = `id, sequence_id, name, label_id, created_at, updated_at`
+
+// scanTrack reads a row selected with trackColumns.
+func scanTrack(row pgx.Row, track *models.Track) error {
+	return row.Scan(
+		&track.ID,
+		&track.SequenceID,
+		&track.Name,
+		&track.LabelID,
+		&track.CreatedAt,
+		&track.UpdatedAt,
+	)
+}
+
+// trackWriteError translates constraint violations of track writes and of
+// annotation writes linking tracks or labels into the errors reported to
+// clients.
+func trackWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	switch {
+	case pgErr.Code == uniqueViolation && pgErr.ConstraintName == "idx_annotations_live_track_frame":
+		return ErrTrackFrameTaken
+	case pgErr.Code == foreignKeyViolation && pgErr.ConstraintName == "annotations_track_id_fkey":
+		return ErrTrackNotFound
+	case pgErr.Code == foreignKeyViolation && pgErr.ConstraintName == "tracks_sequence_id_fkey":
+		return ErrSequenceNotFound
+	case pgErr.Code == foreignKeyViolation && (pgErr.ConstraintName == "tracks_label_id_fkey" || pgErr.ConstraintName == "annotations_label_id_fkey"):
+		return ErrLabelNotFound
+	}
+	return nil
+}
+
+// CreateTrack creates a new track in a sequence.
+func (r *PostgresRepository) CreateTrack(ctx context.Context, req *models.CreateTrackRequest) (*models.Track, error) {
+	track := &models.Track{
+		ID:         uuid.New().String(),
+		SequenceID: req.SequenceID,
+		Name:       req.Name,
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	if req.LabelID != nil && *req.LabelID != "" {
+		track.LabelID = req.LabelID
+	}
+
+	query := `
+		INSERT INTO tracks (id, sequence_id, name, label_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		track.ID,
+		track.SequenceID,
+		track.Name,
+		track.LabelID,
+		track.CreatedAt,
+		track.UpdatedAt,
+	)
+
+	if err != nil {
+		if trackErr := trackWriteError(err); trackErr != nil {
+			return nil, trackErr
+		}
+		r.logger.Error("Failed to create track", zap.Error(err))
+		return nil, fmt.Errorf("failed to create track: %w", err)
+	}
+
+	r.logger.Info("Created track", zap.String("id", track.ID), zap.String("sequence_id", track.SequenceID))
+	return track, nil
+}
+
+// GetTrack retrieves a track by its ID.
+func (r *PostgresRepository) GetTrack(ctx context.Context, id string) (*models.Track, error) {
+	query := `SELECT ` + trackColumns + ` FROM tracks WHERE id = $1`
+
+	var track models.Track
+	err := scanTrack(r.pool.QueryRow(ctx, query, id), &track)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get track", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get track: %w", err)
+	}
+
+	return &track, nil
+}
+
+// GetAllTracks retrieves the tracks of a sequence, or of all sequences when
+// sequenceID is empty, oldest first.
+func (r *PostgresRepository) GetAllTracks(ctx context.Context, sequenceID string) ([]models.Track, error) {
+	b := &queryBuilder{}
+	if sequenceID != "" {
+		b.where("sequence_id = " + b.arg(sequenceID))
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM tracks %s ORDER BY created_at, id`, trackColumns, b.whereClause())
+
+	rows, err := r.pool.Query(ctx, query, b.args...)
+	if err != nil {
+		r.logger.Error("Failed to get tracks", zap.Error(err))
+		return nil, fmt.Errorf("failed to get tracks: %w", err)
+	}
+	defer rows.Close()
+
+	tracks := []models.Track{}
+	for rows.Next() {
+		var track models.Track
+		if err := scanTrack(rows, &track); err != nil {
+			r.logger.Error("Failed to scan track row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
+
+// UpdateTrack updates an existing track.
+func (r *PostgresRepository) UpdateTrack(ctx context.Context, id string, req *models.UpdateTrackRequest) (*models.Track, error) {
+	existing, err := r.GetTrack(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.LabelID != nil {
+		existing.LabelID = req.LabelID
+		if *req.LabelID == "" {
+			existing.LabelID = nil
+		}
+	}
+	existing.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE tracks
+		SET name = $2, label_id = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	_, err = r.pool.Exec(ctx, query, existing.ID, existing.Name, existing.LabelID, existing.UpdatedAt)
+	if err != nil {
+		if trackErr := trackWriteError(err); trackErr != nil {
+			return nil, trackErr
+		}
+		r.logger.Error("Failed to update track", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to update track: %w", err)
+	}
+
+	r.logger.Info("Updated track", zap.String("id", id))
+	return existing, nil
+}
+
+// DeleteTrack removes a track, unlinking the annotations observing it as new
+// revisions.
+func (r *PostgresRepository) DeleteTrack(ctx context.Context, id string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := r.unlinkTracks(ctx, tx, `id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete track: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM tracks WHERE id = $1`, id)
+	if err != nil {
+		r.logger.Error("Failed to delete track", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to delete track: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTrackNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit track deletion: %w", err)
+	}
+
+	r.logger.Info("Deleted track", zap.String("id", id))
+	return nil
+}
+
+// unlinkTracks unlinks the annotations observing the tracks the condition on
+// the tracks table selects, given its argument as $1, ahead of deleting the
+// tracks in the same transaction. The foreign key would unlink them too, but
+// without changing their version or recording the change in their history.
+// Annotations in the trash get no revision; restoring them records their state.
+func (r *PostgresRepository) unlinkTracks(ctx context.Context, tx pgx.Tx, condition string, arg any) error {
+	query := `
+		UPDATE annotations
+		SET track_id = NULL, version = version + 1, updated_at = $2
+		WHERE track_id IN (SELECT id FROM tracks WHERE ` + condition + `)
+		RETURNING ` + annotationColumns
+
+	rows, err := tx.Query(ctx, query, arg, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("Failed to unlink track annotations", zap.Error(err))
+		return err
+	}
+
+	// The rows are read to the end before the revisions are written over the
+	// same connection
+	var unlinked []models.Annotation
+	for rows.Next() {
+		var annotation models.Annotation
+		if err := scanAnnotation(rows, &annotation); err != nil {
+			rows.Close()
+			r.logger.Error("Failed to scan annotation row", zap.Error(err))
+			return err
+		}
+		unlinked = append(unlinked, annotation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to unlink track annotations", zap.Error(err))
+		return err
+	}
+
+	for i := range unlinked {
+		annotation := &unlinked[i]
+		if annotation.DeletedAt != nil {
+			continue
+		}
+		if err := r.recordRevision(ctx, tx, models.RevisionUpdate, annotation, nil, annotation.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTrackAnnotations retrieves the observations of a track ordered by frame
+// timestamp. Annotations in point clouds that have since left the track's
+// sequence are not part of the trajectory.
+func (r *PostgresRepository) GetTrackAnnotations(ctx context.Context, id string) ([]models.TrackObservation, error) {
+	// The derived table keeps the name annotations, which annotationColumns
+	// refers to, while adding the frame timestamp
+	query := `
+		SELECT ` + annotationColumns + `, frame_timestamp
+		FROM (
+			SELECT a.*, f.frame_timestamp
+			FROM annotations a
+			JOIN point_clouds f ON f.id = a.point_cloud_id
+			JOIN tracks t ON t.id = a.track_id AND t.sequence_id = f.sequence_id
+			WHERE a.track_id = $1 AND a.deleted_at IS NULL AND f.frame_timestamp IS NOT NULL
+		) AS annotations
+		ORDER BY frame_timestamp, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, id)
+	if err != nil {
+		r.logger.Error("Failed to get track annotations", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to get track annotations: %w", err)
+	}
+	defer rows.Close()
+
+	observations := []models.TrackObservation{}
+	for rows.Next() {
+		var observation models.TrackObservation
+		if err := scanTrackObservation(rows, &observation); err != nil {
+			r.logger.Error("Failed to scan track annotation row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan track annotation: %w", err)
+		}
+		observations = append(observations, observation)
+	}
+
+	return observations, rows.Err()
+}
+
+// scanTrackObservation reads a row selected with annotationColumns followed by
+// the frame timestamp.
+func scanTrackObservation(row pgx.Row, observation *models.TrackObservation) error {
+	return row.Scan(append(annotationFields(&observation.Annotation), &observation.FrameTimestamp)...)
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// purgeBatchSize bounds the number of annotations one purge statement
+// removes, keeping its locks short.
+const purgeBatchSize = 1000
+
+// TrashRepository lists, restores and purges deleted annotations.
+type TrashRepository interface {
+	// GetTrash retrieves up to limit annotations of a point cloud in the
+	// trash, most recently deleted first.
+	GetTrash(ctx context.Context, pointCloudID string, limit int) ([]models.Annotation, error)
+
+	// Restore moves an annotation of the given point cloud out of the trash.
+	Restore(ctx context.Context, pointCloudID, id string) (*models.Annotation, error)
+
+	// PurgeTrash permanently removes the annotations deleted before the given
+	// time and returns how many were removed.
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+// GetTrash retrieves up to limit annotations of a point cloud in the trash,
+// most recently deleted first.
+func (r *PostgresRepository) GetTrash(ctx context.Context, pointCloudID string, limit int) ([]models.Annotation, error) {
+	b := &queryBuilder{}
+	b.where("point_cloud_id = " + b.arg(pointCloudID))
+	b.where("deleted_at IS NOT NULL")
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM annotations
+		%s
+		ORDER BY deleted_at DESC, id
+		LIMIT %s
+	`, annotationColumns, b.whereClause(), b.arg(limit))
+
+	return r.queryAnnotations(ctx, query, b.args...)
+}
+
+// Restore moves an annotation of the given point cloud out of the trash as a
+// new revision. It returns nil if the annotation is not in the trash.
+func (r *PostgresRepository) Restore(ctx context.Context, pointCloudID, id string) (*models.Annotation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+		UPDATE annotations
+		SET deleted_at = NULL, version = version + 1, updated_at = $3
+		WHERE id = $1 AND point_cloud_id = $2 AND deleted_at IS NOT NULL
+		RETURNING ` + annotationColumns
+
+	var annotation models.Annotation
+	err = scanAnnotation(tx.QueryRow(ctx, query, id, pointCloudID, time.Now().UTC()), &annotation)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		// The track may have been observed in the frame again meanwhile
+		if trackErr := trackWriteError(err); trackErr != nil {
+			return nil, trackErr
+		}
+		r.logger.Error("Failed to restore annotation", zap.String("id", id), zap.Error(err))
+		return nil, fmt.Errorf("failed to restore annotation: %w", err)
+	}
+
+	// The label may have changed its geometry types or attributes while the
+	// annotation was in the trash
+	if err := r.checkConforming(ctx, tx, &annotation); err != nil {
+		return nil, err
+	}
+
+	if err := r.recordRevision(ctx, tx, models.RevisionRestore, &annotation, nil, annotation.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit annotation: %w", err)
+	}
+
+	r.logger.Info("Restored annotation", zap.String("id", id))
+	return &annotation, nil
+}
+
+// PurgeTrash permanently removes the annotations deleted before the given
+// time, in batches. Their history is kept.
+func (r *PostgresRepository) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM annotations
+		WHERE id IN (
+			SELECT id FROM annotations
+			WHERE deleted_at < $1
+			LIMIT $2
+		)
+	`
+
+	var purged int64
+	for {
+		result, err := r.pool.Exec(ctx, query, before, purgeBatchSize)
+		if err != nil {
+			r.logger.Error("Failed to purge trash", zap.Error(err))
+			return purged, fmt.Errorf("failed to purge trash: %w", err)
+		}
+
+		purged += result.RowsAffected()
+		if result.RowsAffected() < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// TrashPurger periodically and permanently removes the annotations that have
+// been in the trash for longer than the retention period.
+type TrashPurger struct {
+	repo      TrashRepository
+	logger    *zap.Logger
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTrashPurger creates a purger with the configured retention period and
+// purge interval.
+func NewTrashPurger(repo TrashRepository, cfg *config.Config, logger *zap.Logger) *TrashPurger {
+	return &TrashPurger{
+		repo:      repo,
+		logger:    logger,
+		retention: cfg.TrashRetention,
+		interval:  cfg.TrashPurgeInterval,
+		now:       time.Now,
+	}
+}
+
+// Start purges the trash right away and then once every interval, until Stop
+// is called.
+func (p *TrashPurger) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	p.logger.Info("Starting trash purger",
+		zap.Duration("retention", p.retention),
+		zap.Duration("interval", p.interval),
+	)
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			_, _ = p.Purge(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the purger and waits for a running purge to finish.
+func (p *TrashPurger) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// Purge permanently removes the annotations whose retention period is over
+// and returns how many were removed.
+func (p *TrashPurger) Purge(ctx context.Context) (int64, error) {
+	purged, err := p.repo.PurgeTrash(ctx, p.now().Add(-p.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.Error("Failed to purge trash", zap.Error(err))
+		}
+		return purged, err
+	}
+
+	if purged > 0 {
+		p.logger.Info("Purged trash", zap.Int64("annotations", purged))
+	}
+	return purged, nil
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// fakeTrash records the purges of a TrashPurger.
+type fakeTrash struct {
+	mu      sync.Mutex
+	cutoffs []time.Time
+	purged  int64
+	err     error
+}
+
+func (f *fakeTrash) GetTrash(context.Context, string, int) ([]models.Annotation, error) {
+	return nil, nil
+}
+
+func (f *fakeTrash) Restore(context.Context, string, string) (*models.Annotation, error) {
+	return nil, nil
+}
+
+func (f *fakeTrash) PurgeTrash(_ context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cutoffs = append(f.cutoffs, before)
+	return f.purged, f.err
+}
+
+func (f *fakeTrash) purges() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.cutoffs)
+}
+
+func TestTrashPurger_Purge(t *testing.T) {
+	trash := &fakeTrash{purged: 3}
+	cfg := &config.Config{TrashRetention: 7 * 24 * time.Hour, TrashPurgeInterval: time.Hour}
+
+	purger := NewTrashPurger(trash, cfg, zap.NewNop())
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	purger.now = func() time.Time { return now }
+
+	purged, err := purger.Purge(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+	assert.Equal(t, []time.Time{time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)}, trash.cutoffs)
+
+	trash.err = errors.New("connection refused")
+	_, err = purger.Purge(context.Background())
+	assert.Error(t, err)
+}
+
+func TestTrashPurger_StartStop(t *testing.T) {
+	trash := &fakeTrash{}
+	cfg := &config.Config{TrashRetention: time.Hour, TrashPurgeInterval: 10 * time.Millisecond}
+
+	purger := NewTrashPurger(trash, cfg, zap.NewNop())
+	purger.Start()
+
+	assert.Eventually(t, func() bool { return trash.purges() >= 2 }, time.Second, 5*time.Millisecond,
+		"the trash is purged right away and then every interval")
+
+	purger.Stop()
+	purges := trash.purges()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, purges, trash.purges(), "no purge runs after Stop")
+}
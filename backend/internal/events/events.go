@@ -0,0 +1,133 @@
+// Package events delivers annotation change events to the clients watching a
+// point cloud, across handler replicas.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// SubscriptionBuffer is how many events a subscription holds for a client that
+// has not caught up yet. A subscriber falling further behind is dropped, and
+// its client reconnects and reloads instead, as after bulk imports.
+const SubscriptionBuffer = 1024
+
+// Bus defines the interface for publishing and subscribing to annotation
+// change events.
+type Bus interface {
+	// Publish announces changes to the subscribers of their point clouds on
+	// every handler replica.
+	Publish(ctx context.Context, events ...*models.AnnotationEvent) error
+
+	// Subscribe starts delivering the events of the given point cloud
+	// published from now on.
+	Subscribe(pointCloudID string) *Subscription
+
+	// Close ends every subscription and closes the bus connection.
+	Close() error
+}
+
+// Subscription receives the events of one point cloud.
+type Subscription struct {
+	pointCloudID string
+	events       chan *models.AnnotationEvent
+	hub          *hub
+}
+
+// Events returns the channel of the subscription's events. It is closed when
+// the subscription ends: when it is closed, when the bus closes, or when the
+// subscriber fell more than SubscriptionBuffer events behind.
+func (s *Subscription) Events() <-chan *models.AnnotationEvent {
+	return s.events
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// hub fans events out to the subscriptions of this process.
+type hub struct {
+	mu            sync.Mutex
+	subscriptions map[string]map[*Subscription]struct{}
+	logger        *zap.Logger
+}
+
+func newHub(logger *zap.Logger) *hub {
+	return &hub{
+		subscriptions: make(map[string]map[*Subscription]struct{}),
+		logger:        logger,
+	}
+}
+
+// subscribe adds a subscription to the events of the point cloud.
+func (h *hub) subscribe(pointCloudID string) *Subscription {
+	s := &Subscription{
+		pointCloudID: pointCloudID,
+		events:       make(chan *models.AnnotationEvent, SubscriptionBuffer),
+		hub:          h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscriptions[pointCloudID] == nil {
+		h.subscriptions[pointCloudID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[pointCloudID][s] = struct{}{}
+	return s
+}
+
+// remove ends a subscription, closing its channel. Removing it again does
+// nothing.
+func (h *hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(s)
+}
+
+func (h *hub) removeLocked(s *Subscription) {
+	subscriptions := h.subscriptions[s.pointCloudID]
+	if _, ok := subscriptions[s]; !ok {
+		return
+	}
+
+	delete(subscriptions, s)
+	if len(subscriptions) == 0 {
+		delete(h.subscriptions, s.pointCloudID)
+	}
+	close(s.events)
+}
+
+// dispatch hands an event to the subscriptions of its point cloud without
+// waiting for them. A subscription whose buffer is full is ended rather than
+// holding up everyone else; its client reconnects and reloads.
+func (h *hub) dispatch(event *models.AnnotationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscriptions[event.PointCloudID] {
+		select {
+		case s.events <- event:
+		default:
+			h.logger.Warn("Dropping slow event subscriber", zap.String("point_cloud_id", event.PointCloudID))
+			h.removeLocked(s)
+		}
+	}
+}
+
+// closeAll ends every subscription.
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subscriptions := range h.subscriptions {
+		for s := range subscriptions {
+			h.removeLocked(s)
+		}
+	}
+}
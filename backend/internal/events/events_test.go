@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestLocalBus_DeliversToPointCloud(t *testing.T) {
+	bus := NewLocalBus(zap.NewNop())
+	defer bus.Close()
+
+	subscription := bus.Subscribe("pc-1")
+	other := bus.Subscribe("pc-2")
+
+	err := bus.Publish(context.Background(),
+		&models.AnnotationEvent{Type: models.EventCreated, PointCloudID: "pc-1", AnnotationID: "a-1"},
+		&models.AnnotationEvent{Type: models.EventDeleted, PointCloudID: "pc-1", AnnotationID: "a-2"},
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "a-1", (<-subscription.Events()).AnnotationID)
+	assert.Equal(t, "a-2", (<-subscription.Events()).AnnotationID)
+	assert.Empty(t, other.Events())
+}
+
+func TestLocalBus_DropsSlowSubscriber(t *testing.T) {
+	bus := NewLocalBus(zap.NewNop())
+	defer bus.Close()
+
+	subscription := bus.Subscribe("pc-1")
+	for i := 0; i <= SubscriptionBuffer; i++ {
+		_ = bus.Publish(context.Background(), &models.AnnotationEvent{Type: models.EventUpdated, PointCloudID: "pc-1"})
+	}
+
+	// The buffered events are still delivered, then the channel closes
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+	assert.Equal(t, SubscriptionBuffer, received)
+
+	// Closing an ended subscription does nothing
+	subscription.Close()
+}
+
+func TestLocalBus_Close(t *testing.T) {
+	bus := NewLocalBus(zap.NewNop())
+	subscription := bus.Subscribe("pc-1")
+
+	subscription.Close()
+	_, ok := <-subscription.Events()
+	assert.False(t, ok)
+
+	open := bus.Subscribe("pc-1")
+	assert.NoError(t, bus.Close())
+	_, ok = <-open.Events()
+	assert.False(t, ok)
+}
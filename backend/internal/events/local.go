@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// LocalBus implements Bus within one process, for a single handler and tests.
+type LocalBus struct {
+	hub *hub
+}
+
+// NewLocalBus creates a bus delivering events to the subscribers of this
+// process only.
+func NewLocalBus(logger *zap.Logger) *LocalBus {
+	return &LocalBus{hub: newHub(logger)}
+}
+
+// Publish hands the events to the subscribers of their point clouds.
+func (b *LocalBus) Publish(ctx context.Context, events ...*models.AnnotationEvent) error {
+	for _, event := range events {
+		b.hub.dispatch(event)
+	}
+	return nil
+}
+
+// Subscribe starts delivering the events of the given point cloud.
+func (b *LocalBus) Subscribe(pointCloudID string) *Subscription {
+	return b.hub.subscribe(pointCloudID)
+}
+
+// Close ends every subscription.
+func (b *LocalBus) Close() error {
+	b.hub.closeAll()
+	return nil
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// channel is the Redis pub/sub channel of annotation events. Every replica
+// receives the events of all point clouds and delivers them to its own
+// subscribers, so a replica holds one Redis subscription however many clients
+// it serves.
+const channel = "annotations:events"
+
+// RedisBus implements Bus with Redis pub/sub. Delivery is at most once:
+// events published while a replica is disconnected from Redis are lost to its
+// subscribers.
+type RedisBus struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	hub    *hub
+	logger *zap.Logger
+	done   chan struct{}
+}
+
+// NewRedisBus creates a bus on Redis and subscribes to the event channel.
+func NewRedisBus(cfg *config.Config, logger *zap.Logger) (Bus, error) {
+	opt, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pubsub := client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to subscribe to annotation events: %w", err)
+	}
+
+	logger.Info("Subscribed to annotation events", zap.String("channel", channel))
+
+	b := &RedisBus{
+		client: client,
+		pubsub: pubsub,
+		hub:    newHub(logger),
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b, nil
+}
+
+// run delivers the events received from Redis until the subscription closes.
+// The client resubscribes by itself after connection failures.
+func (b *RedisBus) run() {
+	defer close(b.done)
+
+	for msg := range b.pubsub.Channel(redis.WithChannelSize(SubscriptionBuffer)) {
+		var event models.AnnotationEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			b.logger.Warn("Failed to unmarshal annotation event", zap.Error(err))
+			continue
+		}
+		b.hub.dispatch(&event)
+	}
+}
+
+// Publish sends the events to every replica in one round trip.
+func (b *RedisBus) Publish(ctx context.Context, events ...*models.AnnotationEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	pipe := b.client.Pipeline()
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal annotation event: %w", err)
+		}
+		pipe.Publish(ctx, channel, data)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish annotation events: %w", err)
+	}
+	return nil
+}
+
+// Subscribe starts delivering the events of the given point cloud.
+func (b *RedisBus) Subscribe(pointCloudID string) *Subscription {
+	return b.hub.subscribe(pointCloudID)
+}
+
+// Close ends every subscription and closes the Redis connection.
+func (b *RedisBus) Close() error {
+	b.logger.Info("Closing annotation event subscription")
+
+	err := b.pubsub.Close()
+	<-b.done
+	b.hub.closeAll()
+
+	if cerr := b.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
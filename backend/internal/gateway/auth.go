@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/identity"
+)
+
+// authRealm is the realm of the WWW-Authenticate challenges.
+const authRealm = "point-cloud-annotator"
+
+// authorHeader names the author of annotation writes when handlers run
+// without authentication.
+const authorHeader = "X-Author"
+
+// tokenClaims are the claims of a bearer token the gateway reads.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+}
+
+// Authenticator verifies bearer tokens: JWTs signed with HS256 by the
+// configured secret or a symmetric key of the key set, or with RS256 by an
+// RSA key of the key set. Tokens must expire, and match the configured
+// issuer and audience.
+type Authenticator struct {
+	secret []byte
+	keys   *KeySet
+	parser *jwt.Parser
+	logger *zap.Logger
+}
+
+// NewAuthenticator creates the authenticator of the configured secret and
+// key set.
+func NewAuthenticator(cfg *config.Config, logger *zap.Logger) (*Authenticator, error) {
+	a := &Authenticator{logger: logger}
+	if cfg.JWTSecret != "" {
+		a.secret = []byte(cfg.JWTSecret)
+	}
+	if cfg.JWKS != "" {
+		keys, err := NewKeySet(cfg.JWKS, cfg.JWKSRefreshInterval, logger)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	if a.secret == nil && a.keys == nil {
+		return nil, errors.New("JWT authentication needs a secret or a key set")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.JWTLeeway),
+	}
+	if cfg.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(cfg.JWTAudience))
+	}
+	a.parser = jwt.NewParser(options...)
+
+	return a, nil
+}
+
+// Start starts reloading the key set.
+func (a *Authenticator) Start() {
+	if a.keys != nil {
+		a.keys.Start()
+	}
+}
+
+// Stop stops reloading the key set.
+func (a *Authenticator) Stop() {
+	if a.keys != nil {
+		a.keys.Stop()
+	}
+}
+
+// Verify returns the identity of a valid token.
+func (a *Authenticator) Verify(ctx context.Context, token string) (*identity.Identity, error) {
+	claims := &tokenClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.key(ctx, t)
+	}); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	if name == "" {
+		name = claims.Email
+	}
+	return &identity.Identity{Subject: claims.Subject, Name: name}, nil
+}
+
+// key returns the key verifying a token. The key must be of the token's
+// algorithm, so that an RSA public key is never taken for an HS256 secret.
+func (a *Authenticator) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	kid, _ := token.Header["kid"].(string)
+
+	if a.keys != nil {
+		if key, ok := a.keys.Key(ctx, kid, alg); ok {
+			return key, nil
+		}
+	}
+	if alg == jwt.SigningMethodHS256.Alg() && a.secret != nil {
+		return a.secret, nil
+	}
+	return nil, fmt.Errorf("no %s key %q", alg, kid)
+}
+
+// bearerToken returns the token of an Authorization header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// authenticate rejects requests without a valid bearer token before they
+// are proxied, and attaches the identity of the others to their context.
+// Without authentication configured, every request passes.
+func (g *Gateway) authenticate(c *gin.Context) {
+	if g.auth == nil {
+		return
+	}
+
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok {
+		c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", authRealm))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "bearer token is required",
+		})
+		return
+	}
+
+	id, err := g.auth.Verify(c.Request.Context(), token)
+	if err != nil {
+		g.logger.Info("Rejected invalid bearer token", zap.String("path", c.Request.URL.Path), zap.Error(err))
+		c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\"", authRealm))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_token",
+			"message": "bearer token is invalid or has expired",
+		})
+		return
+	}
+
+	c.Request = c.Request.WithContext(identity.WithIdentity(c.Request.Context(), id))
+}
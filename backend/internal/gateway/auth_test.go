@@ -0,0 +1,246 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/identity"
+)
+
+const (
+	testJWTSecret      = "jwt-secret"
+	testIdentitySecret = "identity-secret"
+)
+
+// validClaims returns claims the test authenticators accept.
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":  "u-1",
+		"name": "Alice",
+		"iss":  "https://auth.pca.local",
+		"aud":  "point-cloud-annotator",
+		"iat":  time.Now().Unix(),
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims, secret string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func signRS256(t *testing.T, claims jwt.MapClaims, kid string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(testRSAKey)
+	require.NoError(t, err)
+	return signed
+}
+
+// authConfig returns a gateway configuration with authentication by the
+// HS256 secret and a key set of the RSA test key.
+func authConfig(t *testing.T, upstreams ...string) *config.Config {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksDocument(t, rsaJWK("rsa-1", &testRSAKey.PublicKey)), 0o600))
+
+	return &config.Config{
+		Role:           "gateway",
+		HandlerURLs:    upstreams,
+		JWTSecret:      testJWTSecret,
+		JWKS:           path,
+		JWTIssuer:      "https://auth.pca.local",
+		JWTAudience:    "point-cloud-annotator",
+		IdentitySecret: testIdentitySecret,
+	}
+}
+
+func TestAuthenticator_Verify(t *testing.T) {
+	auth, err := NewAuthenticator(authConfig(t, "http://handler:8081"), zap.NewNop())
+	require.NoError(t, err)
+
+	id, err := auth.Verify(context.Background(), signHS256(t, validClaims(), testJWTSecret))
+	require.NoError(t, err)
+	assert.Equal(t, &identity.Identity{Subject: "u-1", Name: "Alice"}, id)
+
+	claims := validClaims()
+	delete(claims, "name")
+	claims["preferred_username"] = "alice"
+	id, err = auth.Verify(context.Background(), signRS256(t, claims, "rsa-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", id.Author())
+}
+
+func TestAuthenticator_Rejects(t *testing.T) {
+	auth, err := NewAuthenticator(authConfig(t, "http://handler:8081"), zap.NewNop())
+	require.NoError(t, err)
+
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong secret", signHS256(t, validClaims(), "other-secret")},
+		{"expired", signHS256(t, with("exp", time.Now().Add(-time.Hour).Unix()), testJWTSecret)},
+		{"no expiry", signHS256(t, with("exp", nil), testJWTSecret)},
+		{"wrong issuer", signHS256(t, with("iss", "https://evil.example"), testJWTSecret)},
+		{"wrong audience", signHS256(t, with("aud", "other-service"), testJWTSecret)},
+		{"no subject", signHS256(t, with("sub", nil), testJWTSecret)},
+		{"unknown key", signRS256(t, validClaims(), "rsa-2")},
+		{"unsigned", func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return token
+		}()},
+		{"malformed", "not-a-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.Verify(context.Background(), tt.token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewGateway_AuthNeedsIdentitySecret(t *testing.T) {
+	cfg := authConfig(t, "http://handler:8081")
+	cfg.IdentitySecret = ""
+
+	_, err := NewGateway(cfg, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestProxyToHandler_Authentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var forwarded *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	gw, err := NewGateway(authConfig(t, upstream.URL), zap.NewNop())
+	require.NoError(t, err)
+	engine := gin.New()
+	gw.RegisterRoutes(engine.Group("/api/v1"))
+
+	// Unauthenticated calls never reach the handler
+	w := serve(engine, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer realm="point-cloud-annotator"`, w.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, validClaims(), "other-secret"))
+	w = serve(engine, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	assert.Nil(t, forwarded)
+
+	// The handler gets the identity the token proved, signed, in place of
+	// the token and of identity headers the client made up
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/labels/car", nil)
+	req.Header.Set("Authorization", "Bearer "+signRS256(t, validClaims(), "rsa-1"))
+	req.Header.Set(identity.SubjectHeader, "admin")
+	req.Header.Set("X-Author", "admin")
+	w = serve(engine, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	require.NotNil(t, forwarded)
+	assert.Empty(t, forwarded.Header.Get("Authorization"))
+	assert.Empty(t, forwarded.Header.Get("X-Author"))
+	id, err := identity.Verify(forwarded, []byte(testIdentitySecret), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, &identity.Identity{Subject: "u-1", Name: "Alice"}, id)
+}
+
+func TestProxyToHandler_SignsEveryAttempt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var mu sync.Mutex
+	var nonces []string
+	var forwarded *http.Request
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		nonces = append(nonces, r.Header.Get(identity.NonceHeader))
+	}
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		forwarded = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer working.Close()
+
+	cfg := authConfig(t, failing.URL, working.URL)
+	cfg.LoadBalancer = RoundRobin
+	cfg.UpstreamMaxFails = 5
+	cfg.UpstreamFailTimeout = time.Minute
+	cfg.RetryMaxAttempts = 3
+	cfg.RetryBudget = 0.2
+	gw, err := NewGateway(cfg, zap.NewNop())
+	require.NoError(t, err)
+	engine := gin.New()
+	gw.RegisterRoutes(engine.Group("/api/v1"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=kitti", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, validClaims(), testJWTSecret))
+	w := serve(engine, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// The retry carries a signature of its own, covering the query
+	require.Len(t, nonces, 2)
+	assert.NotEqual(t, nonces[0], nonces[1])
+	require.NotNil(t, forwarded)
+	_, err = identity.Verify(forwarded, []byte(testIdentitySecret), time.Now())
+	assert.NoError(t, err)
+}
+
+func TestProxyToHandler_WithoutAuthentication(t *testing.T) {
+	var forwarded http.Header
+	server := setupTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+	}))
+
+	// Without authentication requests pass, but client identity headers
+	// still do not
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/labels", nil)
+	req.Header.Set(identity.SubjectHeader, "admin")
+	req.Header.Set("Authorization", "Bearer whatever")
+	req.Header.Set("X-Author", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, forwarded.Get(identity.SubjectHeader))
+	assert.Equal(t, "Bearer whatever", forwarded.Get("Authorization"))
+	assert.Equal(t, "alice", forwarded.Get("X-Author"))
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Load balancing strategies.
+const (
+	RoundRobin       = "round_robin"
+	LeastOutstanding = "least_outstanding"
+	ConsistentHash   = "consistent_hash"
+)
+
+// hashReplicas is how many points each upstream has on the consistent hash
+// ring; more points spread the keys more evenly.
+const hashReplicas = 100
+
+// Balancer chooses the upstream of a request.
+type Balancer interface {
+	// Pick chooses one of the upstreams, which are never empty. key
+	// identifies what the request is about and may be empty.
+	Pick(upstreams []*Upstream, key string) *Upstream
+}
+
+// NewBalancer creates the balancer of a strategy.
+func NewBalancer(strategy string) (Balancer, error) {
+	switch strategy {
+	case RoundRobin, "":
+		return &roundRobinBalancer{}, nil
+	case LeastOutstanding:
+		return &leastOutstandingBalancer{}, nil
+	case ConsistentHash:
+		return &consistentHashBalancer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown load balancer %q", strategy)
+	}
+}
+
+// roundRobinBalancer takes the upstreams in turn.
+type roundRobinBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobinBalancer) Pick(upstreams []*Upstream, key string) *Upstream {
+	return upstreams[(b.next.Add(1)-1)%uint64(len(upstreams))]
+}
+
+// leastOutstandingBalancer takes the upstream with the fewest requests in
+// flight, which favors the fast ones. Ties go round-robin, so that idle
+// upstreams share the load.
+type leastOutstandingBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *leastOutstandingBalancer) Pick(upstreams []*Upstream, key string) *Upstream {
+	start := int((b.next.Add(1) - 1) % uint64(len(upstreams)))
+
+	best := upstreams[start]
+	for i := 1; i < len(upstreams); i++ {
+		upstream := upstreams[(start+i)%len(upstreams)]
+		if upstream.Outstanding() < best.Outstanding() {
+			best = upstream
+		}
+	}
+	return best
+}
+
+// consistentHashBalancer sends the requests of a key to the same upstream, so
+// that its cache and event subscriptions stay warm. When an upstream leaves
+// or joins, only its share of the keys moves. Requests without a key go
+// round-robin.
+type consistentHashBalancer struct {
+	roundRobin roundRobinBalancer
+
+	mu   sync.Mutex
+	ring *hashRing
+}
+
+func (b *consistentHashBalancer) Pick(upstreams []*Upstream, key string) *Upstream {
+	if key == "" {
+		return b.roundRobin.Pick(upstreams, key)
+	}
+
+	b.mu.Lock()
+	if b.ring == nil || !b.ring.matches(upstreams) {
+		b.ring = newHashRing(upstreams)
+	}
+	ring := b.ring
+	b.mu.Unlock()
+
+	return ring.get(key)
+}
+
+// hashRing places hashReplicas points per upstream on a ring of hashes; a key
+// belongs to the upstream of the first point at or after its hash.
+type hashRing struct {
+	upstreams []*Upstream
+	hashes    []uint32
+	owners    []*Upstream
+}
+
+func newHashRing(upstreams []*Upstream) *hashRing {
+	ring := &hashRing{upstreams: append([]*Upstream(nil), upstreams...)}
+
+	type point struct {
+		hash  uint32
+		owner *Upstream
+	}
+	points := make([]point, 0, len(upstreams)*hashReplicas)
+	for _, upstream := range upstreams {
+		for i := 0; i < hashReplicas; i++ {
+			points = append(points, point{hash: hashKey(upstream.URL.String() + "#" + strconv.Itoa(i)), owner: upstream})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return strings.Compare(points[i].owner.URL.String(), points[j].owner.URL.String()) < 0
+	})
+
+	ring.hashes = make([]uint32, len(points))
+	ring.owners = make([]*Upstream, len(points))
+	for i, p := range points {
+		ring.hashes[i] = p.hash
+		ring.owners[i] = p.owner
+	}
+	return ring
+}
+
+// matches reports whether the ring was built of exactly these upstreams.
+func (r *hashRing) matches(upstreams []*Upstream) bool {
+	if len(upstreams) != len(r.upstreams) {
+		return false
+	}
+	for i := range upstreams {
+		if upstreams[i] != r.upstreams[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *hashRing) get(key string) *Upstream {
+	hash := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
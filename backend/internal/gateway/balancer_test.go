@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testUpstreams(n int) []*Upstream {
+	upstreams := make([]*Upstream, n)
+	for i := range upstreams {
+		upstreams[i] = &Upstream{URL: &url.URL{Scheme: "http", Host: fmt.Sprintf("handler-%d:8081", i)}}
+	}
+	return upstreams
+}
+
+func TestNewBalancer(t *testing.T) {
+	for _, strategy := range []string{RoundRobin, LeastOutstanding, ConsistentHash} {
+		_, err := NewBalancer(strategy)
+		assert.NoError(t, err, strategy)
+	}
+
+	_, err := NewBalancer("random")
+	assert.Error(t, err)
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	upstreams := testUpstreams(3)
+	b := &roundRobinBalancer{}
+
+	for i := 0; i < 6; i++ {
+		assert.Same(t, upstreams[i%3], b.Pick(upstreams, ""))
+	}
+}
+
+func TestLeastOutstandingBalancer(t *testing.T) {
+	upstreams := testUpstreams(3)
+	upstreams[0].outstanding.Store(5)
+	upstreams[1].outstanding.Store(1)
+	upstreams[2].outstanding.Store(3)
+	b := &leastOutstandingBalancer{}
+
+	for i := 0; i < 3; i++ {
+		assert.Same(t, upstreams[1], b.Pick(upstreams, ""))
+	}
+
+	// Idle upstreams take turns
+	idle := testUpstreams(2)
+	assert.NotSame(t, b.Pick(idle, ""), b.Pick(idle, ""))
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	upstreams := testUpstreams(4)
+	b := &consistentHashBalancer{}
+
+	owners := make(map[string]*Upstream)
+	used := make(map[*Upstream]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("annotation-%d", i)
+		owners[key] = b.Pick(upstreams, key)
+		used[owners[key]] = true
+
+		// A key sticks to its upstream
+		assert.Same(t, owners[key], b.Pick(upstreams, key))
+	}
+	assert.Len(t, used, 4)
+
+	// Only the keys of a removed upstream move
+	remaining := append([]*Upstream{upstreams[0]}, upstreams[2:]...)
+	moved := 0
+	for key, owner := range owners {
+		picked := b.Pick(remaining, key)
+		if owner == upstreams[1] {
+			require.NotSame(t, upstreams[1], picked)
+			moved++
+		} else {
+			assert.Same(t, owner, picked, key)
+		}
+	}
+	assert.Greater(t, moved, 0)
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import "time"
+
+// BreakerState is the state of an upstream's circuit breaker.
+type BreakerState string
+
+// Circuit breaker states. A closed breaker lets every request through; an
+// open one none, until its timeout has passed and it turns half-open; a
+// half-open one lets a few trial requests through, which close it again or
+// open it for another timeout.
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// breakerStates lists the states, for the state metric.
+var breakerStates = []BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen}
+
+// circuitBreaker keeps requests away from an upstream that keeps failing, so
+// that a struggling replica is not hammered while it recovers. It is guarded
+// by the mutex of its upstream.
+type circuitBreaker struct {
+	maxFails    int
+	openTimeout time.Duration
+	maxTrials   int
+
+	state    BreakerState
+	failures int
+	openedAt time.Time
+
+	// trials counts the trial requests in flight while half-open.
+	trials int
+}
+
+func newCircuitBreaker(maxFails int, openTimeout time.Duration, maxTrials int) circuitBreaker {
+	if maxFails < 1 {
+		maxFails = 1
+	}
+	if maxTrials < 1 {
+		maxTrials = 1
+	}
+	return circuitBreaker{maxFails: maxFails, openTimeout: openTimeout, maxTrials: maxTrials, state: BreakerClosed}
+}
+
+// current returns the state at now: an open breaker whose timeout has passed
+// is half-open.
+func (b *circuitBreaker) current(now time.Time) BreakerState {
+	if b.state == BreakerOpen && !now.Before(b.openedAt.Add(b.openTimeout)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// ready reports whether a request could be let through at now.
+func (b *circuitBreaker) ready(now time.Time) bool {
+	switch b.current(now) {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		return b.state == BreakerOpen || b.trials < b.maxTrials
+	default:
+		return false
+	}
+}
+
+// allow lets a request through if the breaker is ready, counting it as a
+// trial while half-open. It returns the previous state.
+func (b *circuitBreaker) allow(now time.Time) (bool, BreakerState) {
+	previous := b.state
+	if !b.ready(now) {
+		return false, previous
+	}
+
+	if b.current(now) == BreakerHalfOpen {
+		if b.state == BreakerOpen {
+			b.state = BreakerHalfOpen
+			b.trials = 0
+		}
+		b.trials++
+	}
+	return true, previous
+}
+
+// record applies the result of a request that was let through: a success
+// closes the breaker, maxFails failures in a row or a failed trial open it.
+// It returns the previous state.
+func (b *circuitBreaker) record(failed bool, now time.Time) BreakerState {
+	previous := b.state
+	if b.state == BreakerHalfOpen && b.trials > 0 {
+		b.trials--
+	}
+
+	if !failed {
+		b.failures = 0
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerClosed
+		}
+		return previous
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.maxFails) {
+		b.state = BreakerOpen
+		b.openedAt = now
+		b.trials = 0
+	}
+	return previous
+}
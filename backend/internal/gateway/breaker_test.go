@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(3, 30*time.Second, 2)
+	assert.Equal(t, BreakerClosed, b.current(now))
+
+	// Failures open it only once they come in a row
+	b.record(true, now)
+	b.record(true, now)
+	b.record(false, now)
+	b.record(true, now)
+	b.record(true, now)
+	assert.Equal(t, BreakerClosed, b.current(now))
+	assert.Equal(t, BreakerClosed, b.record(true, now))
+	assert.Equal(t, BreakerOpen, b.current(now))
+
+	ok, _ := b.allow(now.Add(29 * time.Second))
+	assert.False(t, ok)
+
+	// Half-open after the timeout, it lets two trials through at a time
+	later := now.Add(30 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, b.current(later))
+	ok, previous := b.allow(later)
+	assert.True(t, ok)
+	assert.Equal(t, BreakerOpen, previous)
+	ok, _ = b.allow(later)
+	assert.True(t, ok)
+	ok, _ = b.allow(later)
+	assert.False(t, ok)
+
+	// A failed trial opens it again for another timeout
+	assert.Equal(t, BreakerHalfOpen, b.record(true, later))
+	assert.Equal(t, BreakerOpen, b.current(later.Add(29*time.Second)))
+
+	// A successful one closes it
+	later = later.Add(30 * time.Second)
+	ok, _ = b.allow(later)
+	assert.True(t, ok)
+	assert.Equal(t, BreakerHalfOpen, b.record(false, later))
+	assert.Equal(t, BreakerClosed, b.current(later))
+	assert.True(t, b.ready(later))
+}
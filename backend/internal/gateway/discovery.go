@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// Discoverer finds the handler replicas.
+type Discoverer interface {
+	// Discover returns the base URLs of the replicas.
+	Discover(ctx context.Context) ([]*url.URL, error)
+}
+
+// NewDiscoverer creates the discoverer of the configuration: DNS discovery if
+// HandlerDiscovery is set, otherwise the static HandlerURLs.
+func NewDiscoverer(cfg *config.Config) (Discoverer, error) {
+	if cfg.HandlerDiscovery == "" {
+		urls := cfg.HandlerURLs
+		if len(urls) == 0 {
+			urls = []string{cfg.HandlerURL}
+		}
+
+		static := make(staticDiscoverer, len(urls))
+		for i, raw := range urls {
+			u, err := url.Parse(raw)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return nil, fmt.Errorf("invalid handler URL %q", raw)
+			}
+			static[i] = u
+		}
+		return static, nil
+	}
+
+	scheme, target, ok := strings.Cut(cfg.HandlerDiscovery, "://")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("invalid handler discovery %q: expected srv://<name> or dns://<host>:<port>", cfg.HandlerDiscovery)
+	}
+
+	switch scheme {
+	case "srv":
+		return &srvDiscoverer{name: target, lookupSRV: net.DefaultResolver.LookupSRV}, nil
+	case "dns":
+		host, port, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid handler discovery %q: %w", cfg.HandlerDiscovery, err)
+		}
+		return &dnsDiscoverer{host: host, port: port, lookupHost: net.DefaultResolver.LookupHost}, nil
+	default:
+		return nil, fmt.Errorf("invalid handler discovery %q: expected srv://<name> or dns://<host>:<port>", cfg.HandlerDiscovery)
+	}
+}
+
+// staticDiscoverer returns a fixed list of replicas.
+type staticDiscoverer []*url.URL
+
+func (d staticDiscoverer) Discover(ctx context.Context) ([]*url.URL, error) {
+	return d, nil
+}
+
+// srvDiscoverer finds the replicas in the SRV records of a name, such as
+// _http._tcp.handler.pca.local. Only the targets of the lowest priority are
+// used; weights are ignored.
+type srvDiscoverer struct {
+	name      string
+	lookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+func (d *srvDiscoverer) Discover(ctx context.Context) ([]*url.URL, error) {
+	_, records, err := d.lookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SRV records of %s: %w", d.name, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	priority := records[0].Priority
+	for _, record := range records {
+		if record.Priority < priority {
+			priority = record.Priority
+		}
+	}
+
+	var urls []*url.URL
+	for _, record := range records {
+		if record.Priority != priority {
+			continue
+		}
+		host := strings.TrimSuffix(record.Target, ".")
+		urls = append(urls, &url.URL{Scheme: "http", Host: net.JoinHostPort(host, strconv.Itoa(int(record.Port)))})
+	}
+	sortURLs(urls)
+	return urls, nil
+}
+
+// dnsDiscoverer finds the replicas in the addresses of a host name, such as
+// the name of a scaled Docker Compose service, all serving on one port.
+type dnsDiscoverer struct {
+	host       string
+	port       string
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+func (d *dnsDiscoverer) Discover(ctx context.Context) ([]*url.URL, error) {
+	addrs, err := d.lookupHost(ctx, d.host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", d.host, err)
+	}
+
+	urls := make([]*url.URL, len(addrs))
+	for i, addr := range addrs {
+		urls[i] = &url.URL{Scheme: "http", Host: net.JoinHostPort(addr, d.port)}
+	}
+	sortURLs(urls)
+	return urls, nil
+}
+
+// sortURLs orders discovered URLs, as DNS answers come in any order.
+func sortURLs(urls []*url.URL) {
+	sort.Slice(urls, func(i, j int) bool { return urls[i].String() < urls[j].String() })
+}
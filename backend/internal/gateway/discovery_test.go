@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+func TestNewDiscoverer_Static(t *testing.T) {
+	d, err := NewDiscoverer(&config.Config{HandlerURLs: []string{"http://handler-1:8081", "http://handler-2:8081"}})
+	require.NoError(t, err)
+
+	urls, err := d.Discover(context.Background())
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.Equal(t, "handler-2:8081", urls[1].Host)
+
+	// Without a list, the single handler URL is used
+	d, err = NewDiscoverer(&config.Config{HandlerURL: "http://handler:8081"})
+	require.NoError(t, err)
+	urls, _ = d.Discover(context.Background())
+	require.Len(t, urls, 1)
+	assert.Equal(t, "handler:8081", urls[0].Host)
+}
+
+func TestNewDiscoverer_Invalid(t *testing.T) {
+	for _, cfg := range []*config.Config{
+		{HandlerURLs: []string{"handler:8081"}},
+		{HandlerDiscovery: "handler"},
+		{HandlerDiscovery: "dns://handler"},
+		{HandlerDiscovery: "consul://handler"},
+	} {
+		_, err := NewDiscoverer(cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestSRVDiscoverer(t *testing.T) {
+	d := &srvDiscoverer{
+		name: "_http._tcp.handler.pca.local",
+		lookupSRV: func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			assert.Equal(t, "_http._tcp.handler.pca.local", name)
+			return "", []*net.SRV{
+				{Target: "handler-2.pca.local.", Port: 8081, Priority: 10},
+				{Target: "backup.pca.local.", Port: 8081, Priority: 20},
+				{Target: "handler-1.pca.local.", Port: 9000, Priority: 10},
+			}, nil
+		},
+	}
+
+	urls, err := d.Discover(context.Background())
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.Equal(t, "http://handler-1.pca.local:9000", urls[0].String())
+	assert.Equal(t, "http://handler-2.pca.local:8081", urls[1].String())
+}
+
+func TestDNSDiscoverer(t *testing.T) {
+	d := &dnsDiscoverer{
+		host: "handler",
+		port: "8081",
+		lookupHost: func(ctx context.Context, host string) ([]string, error) {
+			return []string{"172.18.0.5", "172.18.0.4", "fd00::4"}, nil
+		},
+	}
+
+	urls, err := d.Discover(context.Background())
+	require.NoError(t, err)
+	require.Len(t, urls, 3)
+	assert.Equal(t, "http://172.18.0.4:8081", urls[0].String())
+	assert.Equal(t, "http://[fd00::4]:8081", urls[2].String())
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// ProxyError is a failure to proxy a request, with the response the client
+// gets for it.
+type ProxyError struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *ProxyError) Error() string {
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError maps a failed round trip to the response of the client:
+//   - 503 when no replica is available or the chosen one cannot be
+//     connected to, as when it is down or restarting,
+//   - 504 when the replica does not answer within UPSTREAM_TIMEOUT,
+//   - 502 for any other failure, such as a connection reset mid-request or
+//     a malformed response.
+func classifyError(err error) *ProxyError {
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return proxyErr
+	}
+
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrNoUpstream),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.As(err, &opErr) && opErr.Op == "dial":
+		return &ProxyError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    "service_unavailable",
+			Message: "handler service is not available",
+			Err:     err,
+		}
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return &ProxyError{
+			Status:  http.StatusGatewayTimeout,
+			Code:    "gateway_timeout",
+			Message: "handler service did not answer in time",
+			Err:     err,
+		}
+	default:
+		return &ProxyError{
+			Status:  http.StatusBadGateway,
+			Code:    "bad_gateway",
+			Message: "failed to reach handler service",
+			Err:     err,
+		}
+	}
+}
+
+// proxyError answers a request the proxy failed to forward.
+func (g *Gateway) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		// The client went away; there is no one to answer
+		return
+	}
+
+	proxyErr := classifyError(err)
+	if errors.Is(err, ErrNoUpstream) {
+		g.logger.Error("No handler service available", zap.Error(err))
+		w.Header().Set("Retry-After", g.retryAfter())
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(proxyErr.Status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":   proxyErr.Code,
+		"message": proxyErr.Message,
+	})
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// timeoutError is a network error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"no upstream", ErrNoUpstream, http.StatusServiceUnavailable, "service_unavailable"},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, http.StatusServiceUnavailable, "service_unavailable"},
+		{"unknown host", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "handler"}}, http.StatusServiceUnavailable, "service_unavailable"},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, http.StatusServiceUnavailable, "service_unavailable"},
+		{"response timeout", timeoutError{}, http.StatusGatewayTimeout, "gateway_timeout"},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, http.StatusBadGateway, "bad_gateway"},
+		{"malformed response", io.ErrUnexpectedEOF, http.StatusBadGateway, "bad_gateway"},
+		{"typed", &ProxyError{Status: http.StatusBadRequest, Code: "invalid_request", Err: io.ErrUnexpectedEOF}, http.StatusBadRequest, "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyErr := classifyError(tt.err)
+			assert.Equal(t, tt.status, proxyErr.Status)
+			assert.Equal(t, tt.code, proxyErr.Code)
+			assert.True(t, errors.Is(proxyErr, tt.err) || proxyErr == tt.err)
+		})
+	}
+}
+
+func TestProxyToHandler_TypedErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name     string
+		upstream string
+		status   int
+		code     string
+	}{
+		{"timeout", slow.URL, http.StatusGatewayTimeout, "gateway_timeout"},
+		{"refused", down.URL, http.StatusServiceUnavailable, "service_unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, err := NewGateway(&config.Config{
+				Role:            "gateway",
+				HandlerURLs:     []string{tt.upstream},
+				UpstreamTimeout: 50 * time.Millisecond,
+			}, zap.NewNop())
+			require.NoError(t, err)
+			engine := gin.New()
+			gw.RegisterRoutes(engine.Group("/api/v1"))
+
+			w := serve(engine, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+			assert.Equal(t, tt.status, w.Code)
+
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body["error"])
+		})
+	}
+}
@@ -2,154 +2,406 @@
 package gateway
 
 import (
-	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"net/http/httputil"
+	"net/netip"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/pointcloud-annotator/backend/internal/config"
+	"github.com/pointcloud-annotator/backend/internal/identity"
 )
 
 // Gateway provides the API gateway functionality.
 type Gateway struct {
-	cfg        *config.Config
-	logger     *zap.Logger
-	httpClient *http.Client
+	cfg       *config.Config
+	logger    *zap.Logger
+	transport http.RoundTripper
+	proxy     *httputil.ReverseProxy
+	pool      *Pool
+	health    *HealthChecker
+	retry     *RetryPolicy
+	metrics   *Metrics
+
+	// trustedProxies may set the forwarding headers of their requests
+	trustedProxies []netip.Prefix
+
+	// auth verifies bearer tokens; nil without authentication
+	auth           *Authenticator
+	identitySecret []byte
+}
+
+// NewGateway creates a new API gateway balancing across the configured
+// handler replicas.
+func NewGateway(cfg *config.Config, logger *zap.Logger) (*Gateway, error) {
+	metrics := NewMetrics()
+	pool, err := NewPool(cfg, metrics, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the wait for the response headers is bounded, as bodies are
+	// streamed and long exports take as long as they take
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cfg.UpstreamTimeout
+
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gateway{
+		cfg:            cfg,
+		logger:         logger,
+		transport:      transport,
+		pool:           pool,
+		health:         NewHealthChecker(pool, cfg, logger),
+		retry:          NewRetryPolicy(cfg),
+		metrics:        metrics,
+		trustedProxies: trustedProxies,
+	}
+
+	if cfg.AuthEnabled() {
+		if cfg.IdentitySecret == "" {
+			return nil, errors.New("JWT authentication needs IDENTITY_SECRET to sign the forwarded identity")
+		}
+		if g.auth, err = NewAuthenticator(cfg, logger); err != nil {
+			return nil, err
+		}
+		g.identitySecret = []byte(cfg.IdentitySecret)
+	} else {
+		logger.Warn("Authentication is disabled: set JWT_SECRET or JWT_JWKS to require bearer tokens")
+	}
+
+	// The reverse proxy takes care of the HTTP semantics: it strips the
+	// hop-by-hop headers, streams bodies in both directions, flushing every
+	// write, and hands upgraded connections such as WebSockets over. The
+	// round trips pick the replica and retry.
+	g.proxy = &httputil.ReverseProxy{
+		Rewrite:       g.rewrite,
+		Transport:     roundTripperFunc(g.roundTrip),
+		FlushInterval: -1,
+		ErrorHandler:  g.proxyError,
+		ErrorLog:      zap.NewStdLog(logger.Named("proxy")),
+	}
+	return g, nil
 }
 
-// NewGateway creates a new API gateway.
-func NewGateway(cfg *config.Config, logger *zap.Logger) *Gateway {
-	return &Gateway{
-		cfg:    cfg,
-		logger: logger,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+// Start starts the discovery of DNS discovered handler replicas, the health
+// checks and the reloading of the key set.
+func (g *Gateway) Start() {
+	g.pool.Start()
+	g.health.Start()
+	if g.auth != nil {
+		g.auth.Start()
 	}
 }
 
+// Stop stops the reloading of the key set, the health checks and the
+// discovery.
+func (g *Gateway) Stop() {
+	if g.auth != nil {
+		g.auth.Stop()
+	}
+	g.health.Stop()
+	g.pool.Stop()
+}
+
 // RegisterRoutes registers the gateway routes on the given router group.
 func (g *Gateway) RegisterRoutes(rg *gin.RouterGroup) {
-	// Proxy all annotation routes to the handler service
-	rg.Any("/annotations", g.proxyToHandler)
-	rg.Any("/annotations/*path", g.proxyToHandler)
+	// Authenticate every request before it reaches a handler
+	api := rg.Group("", g.authenticate)
+
+	// Proxy all point cloud, annotation and label routes to the handler service
+	api.Any("/pointclouds", g.proxyToHandler)
+	api.Any("/pointclouds/*path", g.proxyToHandler)
+	api.Any("/annotations/*path", g.proxyToHandler)
+	api.Any("/annotations:method", g.proxyToHandler)
+	api.Any("/labels", g.proxyToHandler)
+	api.Any("/labels/*path", g.proxyToHandler)
+	api.Any("/sequences", g.proxyToHandler)
+	api.Any("/sequences/*path", g.proxyToHandler)
+	api.Any("/tracks", g.proxyToHandler)
+	api.Any("/tracks/*path", g.proxyToHandler)
 }
 
-// proxyToHandler forwards requests to the handler service.
+// proxyToHandler forwards requests to a handler replica.
 func (g *Gateway) proxyToHandler(c *gin.Context) {
-	// Build the target URL
-	targetURL, err := url.Parse(g.cfg.HandlerURL)
+	// The proxy aborts a response it fails to stream in full by panicking
+	// with http.ErrAbortHandler. Close the connection here instead, so that
+	// the client sees the body cut short rather than gin's recovery logging
+	// a panic for every client that goes away.
+	defer func() {
+		if err := recover(); err != nil {
+			if err != http.ErrAbortHandler {
+				panic(err)
+			}
+			if conn, _, err := http.NewResponseController(c.Writer).Hijack(); err == nil {
+				conn.Close()
+			}
+			c.Abort()
+		}
+	}()
+
+	g.proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// rewrite prepares the request for the handler. The replica is only chosen
+// by the round trip, as each attempt may go to another one.
+func (g *Gateway) rewrite(pr *httputil.ProxyRequest) {
+	// Add the gateway's hop to the forwarding headers of a trusted proxy in
+	// front of it, such as the frontend's Nginx, which knows the client best.
+	// Anyone else could forge them, so theirs are replaced.
+	trusted := g.trusted(pr.In.RemoteAddr)
+	if trusted {
+		pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+	}
+	pr.SetXForwarded()
+	if trusted {
+		if proto := pr.In.Header.Get("X-Forwarded-Proto"); proto != "" {
+			pr.Out.Header.Set("X-Forwarded-Proto", proto)
+		}
+		if host := pr.In.Header.Get("X-Forwarded-Host"); host != "" {
+			pr.Out.Header.Set("X-Forwarded-Host", host)
+		}
+	}
+
+	// Address the replica by its own host name
+	pr.Out.Host = ""
+
+	// Replace the token with the identity it proved, which every attempt
+	// signs for the handler; identity headers of the client are never
+	// passed on, nor with authentication the author it names, which
+	// handlers without the identity secret would attribute writes to
+	identity.Strip(pr.Out.Header)
+	if g.auth != nil {
+		pr.Out.Header.Del(authorHeader)
+	}
+	if identity.FromContext(pr.In.Context()) != nil {
+		pr.Out.Header.Del("Authorization")
+	}
+
+	// Set content type if body exists
+	if pr.In.ContentLength != 0 && pr.Out.Header.Get("Content-Type") == "" {
+		pr.Out.Header.Set("Content-Type", "application/json")
+	}
+}
+
+// trusted reports whether the peer at the given address is a trusted proxy.
+func (g *Gateway) trusted(remoteAddr string) bool {
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
 	if err != nil {
-		g.logger.Error("Invalid handler URL", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "configuration_error",
-			"message": "invalid handler URL configuration",
-		})
-		return
+		return false
 	}
 
-	// Construct the path
-	path := c.Request.URL.Path
-	if extraPath := c.Param("path"); extraPath != "" {
-		// The path already includes /annotations, so we use the full path
+	addr := addrPort.Addr().Unmap().WithZone("")
+	for _, prefix := range g.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
 	}
-	targetURL.Path = path
-	targetURL.RawQuery = c.Request.URL.RawQuery
+	return false
+}
 
-	g.logger.Debug("Proxying request",
-		zap.String("method", c.Request.Method),
-		zap.String("target", targetURL.String()),
-	)
+// parseTrustedProxies parses the trusted proxies, given as addresses or CIDR
+// ranges.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			addr = addr.Unmap().WithZone("")
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
 
-	// Read the request body
-	var bodyBytes []byte
-	if c.Request.Body != nil {
-		bodyBytes, err = io.ReadAll(c.Request.Body)
+		prefix, err := netip.ParsePrefix(proxy)
 		if err != nil {
-			g.logger.Error("Failed to read request body", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": "failed to read request body",
-			})
-			return
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
 		}
+		prefixes = append(prefixes, prefix.Masked())
 	}
+	return prefixes, nil
+}
 
-	// Create the proxy request
-	proxyReq, err := http.NewRequestWithContext(
-		c.Request.Context(),
-		c.Request.Method,
-		targetURL.String(),
-		bytes.NewReader(bodyBytes),
-	)
-	if err != nil {
-		g.logger.Error("Failed to create proxy request", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "failed to create proxy request",
-		})
-		return
-	}
+// roundTrip sends a request to a handler replica. Idempotent requests that
+// fail are retried on another attempt, as the retry policy allows. The
+// replica's request is over once the response body is closed.
+func (g *Gateway) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
 
-	// Copy headers
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			proxyReq.Header.Add(key, value)
+	attempts := g.retry.attempts(req)
+	if attempts > 1 {
+		if err := bufferBody(req); err != nil {
+			return nil, &ProxyError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_request",
+				Message: "failed to read request body",
+				Err:     err,
+			}
 		}
 	}
+	g.retry.budget.deposit()
 
-	// Set content type if body exists
-	if len(bodyBytes) > 0 && proxyReq.Header.Get("Content-Type") == "" {
-		proxyReq.Header.Set("Content-Type", "application/json")
-	}
+	key := balanceKey(req)
+	for attempt := 1; ; attempt++ {
+		// Fail fast while no handler replica is available
+		upstream, err := g.pool.Pick(key)
+		if err != nil {
+			return nil, err
+		}
 
-	// Execute the request
-	resp, err := g.httpClient.Do(proxyReq)
-	if err != nil {
-		g.logger.Error("Failed to proxy request", zap.Error(err))
+		resp, err := g.send(req, upstream)
+		failure := upstreamFailure(resp, err)
+		if ctx.Err() != nil {
+			// A client going away is no fault of the handler
+			failure = nil
+		}
 
-		// Check if it's a connection error
-		if strings.Contains(err.Error(), "connection refused") {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error":   "service_unavailable",
-				"message": "handler service is not available",
-			})
-			return
+		last := failure == nil || attempt >= attempts
+		if !last && !g.retry.budget.withdraw() {
+			g.metrics.retriesDenied.Inc()
+			last = true
 		}
+		if last {
+			if err != nil {
+				g.pool.Done(upstream, failure)
+				if ctx.Err() == nil {
+					g.logger.Error("Failed to proxy request", zap.String("upstream", upstream.URL.String()), zap.Error(err))
+				}
+				return nil, err
+			}
+			resp.Body = newUpstreamBody(resp.Body, func() { g.pool.Done(upstream, failure) })
+			return resp, nil
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		g.pool.Done(upstream, failure)
 
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error":   "proxy_error",
-			"message": "failed to reach handler service",
-		})
-		return
+		delay := g.retry.backoff(attempt)
+		g.logger.Warn("Retrying failed request",
+			zap.String("method", req.Method),
+			zap.String("path", req.URL.Path),
+			zap.String("upstream", upstream.URL.String()),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", delay),
+			zap.Error(failure),
+		)
+		g.metrics.retries.WithLabelValues(req.Method).Inc()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt of a request on an upstream, sending the request
+// body again if it was buffered for retries. The identity is signed for each
+// attempt, as handlers accept a signature only once.
+func (g *Gateway) send(req *http.Request, upstream *Upstream) (*http.Response, error) {
+	attempt := req.Clone(req.Context())
+	attempt.URL.Scheme = upstream.URL.Scheme
+	attempt.URL.Host = upstream.URL.Host
+	if id := identity.FromContext(attempt.Context()); id != nil {
+		identity.Sign(attempt, id, g.identitySecret, time.Now())
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attempt.Body = body
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	respBody, err := io.ReadAll(resp.Body)
+	g.logger.Debug("Proxying request",
+		zap.String("method", req.Method),
+		zap.String("target", attempt.URL.String()),
+	)
+	return g.transport.RoundTrip(attempt)
+}
+
+// upstreamFailure returns why an attempt failed on the upstream's side, or
+// nil: the upstream could not be reached or answered in time, or it answered
+// that it or a service behind it is not available.
+func upstreamFailure(resp *http.Response, err error) error {
 	if err != nil {
-		g.logger.Error("Failed to read response body", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "failed to read response",
-		})
-		return
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("handler service answered %d", resp.StatusCode)
 	}
+	return nil
+}
 
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Header(key, value)
-		}
+// roundTripperFunc adapts a function to http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// upstreamBody reports the request as over once the proxy closes the
+// response body, after streaming it to the client.
+type upstreamBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *upstreamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// upgradedBody is the body of a 101 Switching Protocols response: the
+// upgraded connection, which the proxy also writes to.
+type upgradedBody struct {
+	*upstreamBody
+	conn io.ReadWriteCloser
+}
+
+func (b *upgradedBody) Write(p []byte) (int, error) {
+	return b.conn.Write(p)
+}
+
+func newUpstreamBody(body io.ReadCloser, done func()) io.ReadCloser {
+	wrapped := &upstreamBody{ReadCloser: body, done: done}
+	if conn, ok := body.(io.ReadWriteCloser); ok {
+		return &upgradedBody{upstreamBody: wrapped, conn: conn}
 	}
+	return wrapped
+}
 
-	// Return the response
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+// balanceKey returns what a request is about, for consistent hashing: the
+// annotation it addresses, otherwise its point cloud, otherwise nothing.
+func balanceKey(r *http.Request) string {
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+
+	switch {
+	case len(segments) >= 4 && segments[0] == "pointclouds" && segments[2] == "annotations":
+		return segments[3]
+	case len(segments) >= 2 && segments[0] == "pointclouds":
+		return segments[1]
+	}
+	return r.URL.Query().Get("point_cloud_id")
 }
 
 // HealthCheck returns a health check handler.
@@ -0,0 +1,352 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+func setupTestGateway(t *testing.T, upstream http.Handler) *httptest.Server {
+	return setupTestGatewayWith(t, &config.Config{Role: "gateway"}, upstream)
+}
+
+// setupTestGatewayWith starts a gateway with the given configuration in front
+// of the upstream.
+func setupTestGatewayWith(t *testing.T, cfg *config.Config, upstream http.Handler) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	handler := httptest.NewServer(upstream)
+	t.Cleanup(handler.Close)
+
+	cfg.HandlerURL = handler.URL
+
+	engine := gin.New()
+	gw, err := NewGateway(cfg, zap.NewNop())
+	require.NoError(t, err)
+	gw.RegisterRoutes(engine.Group("/api/v1"))
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serve runs a request through the engine, with a cancellable context like
+// the requests of a server.
+func serve(engine http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req.WithContext(ctx))
+	return w
+}
+
+func TestProxyToHandler_StreamsResponse(t *testing.T) {
+	release := make(chan struct{})
+	server := setupTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, `{"id":"a-1"}`+"\n")
+		w.(http.Flusher).Flush()
+
+		// The rest only follows once the client has read the first line
+		<-release
+		_, _ = io.WriteString(w, `{"id":"a-2"}`+"\n")
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/pointclouds/pc-1/annotations", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a-1"}`+"\n", line)
+
+	close(release)
+	rest, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a-2"}`+"\n", string(rest))
+}
+
+func TestProxyToHandler_StreamsRequest(t *testing.T) {
+	server := setupTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.URL.RawQuery + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+
+	// A body of unknown length is sent on chunked
+	body := io.MultiReader(strings.NewReader("Car 0.00 0"), strings.NewReader(" -1.57\n"))
+	resp, err := http.Post(server.URL+"/api/v1/annotations/import?format=kitti", "text/plain", body)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "format=kitti text/plain Car 0.00 0 -1.57\n", string(respBody))
+}
+
+func TestProxyToHandler_EventStream(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	server := setupTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		_, _ = io.WriteString(w, "event: created\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+
+		// Held open until the client behind the gateway goes away
+		<-r.Context().Done()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/annotations/events?point_cloud_id=pc-1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: created\n", line)
+
+	cancel()
+	select {
+	case <-upstreamDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream stream still open after the client went away")
+	}
+}
+
+func TestProxyToHandler_Headers(t *testing.T) {
+	var upstreamHost string
+	var received http.Header
+	cfg := &config.Config{Role: "gateway", TrustedProxies: []string{"127.0.0.0/8"}}
+	server := setupTestGatewayWith(t, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHost, received = r.Host, r.Header.Clone()
+
+		w.Header().Set("Connection", "X-Upstream-Hop")
+		w.Header().Set("X-Upstream-Hop", "1")
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/labels", nil)
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Hop-by-hop headers stop at the gateway, in both directions
+	assert.Empty(t, received.Get("X-Client-Hop"))
+	assert.Empty(t, received.Get("Keep-Alive"))
+	assert.Equal(t, "application/json", received.Get("Accept"))
+	assert.Empty(t, resp.Header.Get("X-Upstream-Hop"))
+	assert.Equal(t, "req-1", resp.Header.Get("X-Request-Id"))
+
+	// The gateway adds its hop to those of the proxies in front of it
+	assert.Equal(t, "203.0.113.7, 127.0.0.1", received.Get("X-Forwarded-For"))
+	assert.Equal(t, "https", received.Get("X-Forwarded-Proto"))
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), received.Get("X-Forwarded-Host"))
+	assert.NotEqual(t, received.Get("X-Forwarded-Host"), upstreamHost)
+}
+
+func TestProxyToHandler_UntrustedForwardingHeaders(t *testing.T) {
+	var received http.Header
+	cfg := &config.Config{Role: "gateway", TrustedProxies: []string{"10.0.0.0/8"}}
+	server := setupTestGatewayWith(t, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/labels", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "forged.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// A client that is not a trusted proxy cannot claim to forward another
+	assert.Equal(t, "127.0.0.1", received.Get("X-Forwarded-For"))
+	assert.Equal(t, "http", received.Get("X-Forwarded-Proto"))
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), received.Get("X-Forwarded-Host"))
+}
+
+func TestGateway_Trusted(t *testing.T) {
+	gw, err := NewGateway(&config.Config{
+		Role:           "gateway",
+		HandlerURL:     "http://handler:8081",
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	tests := []struct {
+		remoteAddr string
+		expected   bool
+	}{
+		{"10.1.2.3:41000", true},
+		{"192.168.1.10:41000", true},
+		{"192.168.1.11:41000", false},
+		{"[::ffff:10.1.2.3]:41000", true},
+		{"[fd12::1]:41000", true},
+		{"[2001:db8::1]:41000", false},
+		{"203.0.113.7:41000", false},
+		{"not-an-address", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, gw.trusted(tt.remoteAddr), tt.remoteAddr)
+	}
+}
+
+func TestNewGateway_InvalidTrustedProxy(t *testing.T) {
+	_, err := NewGateway(&config.Config{
+		Role:           "gateway",
+		HandlerURL:     "http://handler:8081",
+		TrustedProxies: []string{"10.0.0.0/33"},
+	}, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestProxyToHandler_Upgrade(t *testing.T) {
+	server := setupTestGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = rw.Flush()
+
+		line, _ := rw.ReadString('\n')
+		_, _ = rw.WriteString("echo: " + line)
+		_ = rw.Flush()
+	}))
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET /api/v1/annotations/events?point_cloud_id=pc-1 HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "echo", resp.Header.Get("Upgrade"))
+
+	// The connection is passed through both ways
+	_, err = io.WriteString(conn, "ping\n")
+	require.NoError(t, err)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo: ping\n", line)
+}
+
+func TestProxyToHandler_BalancesAcrossUpstreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var hosts []string
+	for _, name := range []string{"handler-1", "handler-2"} {
+		name := name
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		t.Cleanup(upstream.Close)
+		hosts = append(hosts, upstream.URL)
+	}
+
+	// A replica that is down is taken out of the rotation
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	hosts = append(hosts, down.URL)
+
+	engine := gin.New()
+	gw, err := NewGateway(&config.Config{
+		Role:                "gateway",
+		HandlerURLs:         hosts,
+		LoadBalancer:        RoundRobin,
+		UpstreamMaxFails:    1,
+		UpstreamFailTimeout: time.Minute,
+	}, zap.NewNop())
+	require.NoError(t, err)
+	gw.RegisterRoutes(engine.Group("/api/v1"))
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	answers := make(map[string]int)
+	unavailable := 0
+	for i := 0; i < 9; i++ {
+		resp, err := http.Get(server.URL + "/api/v1/labels")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			unavailable++
+			continue
+		}
+		answers[string(body)]++
+	}
+
+	assert.Equal(t, 1, unavailable)
+	assert.Equal(t, 4, answers["handler-1"])
+	assert.Equal(t, 4, answers["handler-2"])
+}
+
+func TestBalanceKey(t *testing.T) {
+	tests := []struct {
+		target string
+		key    string
+	}{
+		{"/api/v1/pointclouds/pc-1/annotations/a-1", "a-1"},
+		{"/api/v1/pointclouds/pc-1/annotations/a-1/tags/car", "a-1"},
+		{"/api/v1/pointclouds/pc-1/annotations/a-1/history", "a-1"},
+		{"/api/v1/pointclouds/pc-1/trash", "pc-1"},
+		{"/api/v1/pointclouds/pc-1/annotations?limit=10", "pc-1"},
+		{"/api/v1/pointclouds/pc-1", "pc-1"},
+		{"/api/v1/annotations/export?point_cloud_id=pc-1", "pc-1"},
+		{"/api/v1/labels", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.key, balanceKey(httptest.NewRequest(http.MethodGet, tt.target, nil)), tt.target)
+	}
+}
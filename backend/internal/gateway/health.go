@@ -0,0 +1,290 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// HealthState is the health of an upstream as found by the HealthChecker.
+type HealthState string
+
+// Health states. Upstreams start out unknown and stay in the rotation until a
+// check finds them unhealthy.
+const (
+	Unknown   HealthState = "unknown"
+	Healthy   HealthState = "healthy"
+	Unhealthy HealthState = "unhealthy"
+)
+
+// healthRecord is an upstream's health and the checks that led to it.
+type healthRecord struct {
+	state     HealthState
+	since     time.Time
+	lastCheck time.Time
+	lastError string
+
+	// passes and fails count the latest consecutive check results.
+	passes int
+	fails  int
+}
+
+// record applies a check result, moving to healthy after healthyThreshold
+// passes in a row and to unhealthy after unhealthyThreshold failures in a
+// row. An unknown upstream takes the state of its first check. It returns the
+// previous state.
+func (r *healthRecord) record(err error, now time.Time, healthyThreshold, unhealthyThreshold int) HealthState {
+	previous := r.state
+	r.lastCheck = now
+
+	next := r.state
+	if err == nil {
+		r.passes++
+		r.fails = 0
+		r.lastError = ""
+		if r.state == Unknown || r.passes >= healthyThreshold {
+			next = Healthy
+		}
+	} else {
+		r.fails++
+		r.passes = 0
+		r.lastError = err.Error()
+		if r.state == Unknown || r.fails >= unhealthyThreshold {
+			next = Unhealthy
+		}
+	}
+
+	if next != r.state {
+		r.state = next
+		r.since = now
+	}
+	return previous
+}
+
+// UpstreamStatus reports an upstream on /health/upstreams.
+type UpstreamStatus struct {
+	URL         string       `json:"url"`
+	Health      HealthState  `json:"health"`
+	Since       *time.Time   `json:"since,omitempty"`
+	LastCheck   *time.Time   `json:"last_check,omitempty"`
+	LastError   string       `json:"last_error,omitempty"`
+	Breaker     BreakerState `json:"breaker"`
+	Available   bool         `json:"available"`
+	Outstanding int64        `json:"outstanding"`
+}
+
+// UpstreamsStatus is the /health/upstreams response.
+type UpstreamsStatus struct {
+	Status    string           `json:"status"`
+	Upstreams []UpstreamStatus `json:"upstreams"`
+}
+
+// status reports the upstream.
+func (u *Upstream) status(now time.Time) UpstreamStatus {
+	u.mu.Lock()
+	health := u.health
+	breaker := u.breaker.current(now)
+	ready := u.breaker.ready(now)
+	u.mu.Unlock()
+
+	status := UpstreamStatus{
+		URL:         u.URL.String(),
+		Health:      health.state,
+		LastError:   health.lastError,
+		Breaker:     breaker,
+		Available:   health.state != Unhealthy && ready,
+		Outstanding: u.Outstanding(),
+	}
+	if !health.since.IsZero() {
+		status.Since = &health.since
+	}
+	if !health.lastCheck.IsZero() {
+		status.LastCheck = &health.lastCheck
+	}
+	return status
+}
+
+// HealthChecker polls the /health endpoint of every upstream of a pool in the
+// background, taking unhealthy upstreams out of the rotation before requests
+// fail on them, and back in once they recover.
+type HealthChecker struct {
+	pool               *Pool
+	client             *http.Client
+	interval           time.Duration
+	healthyThreshold   int
+	unhealthyThreshold int
+	logger             *zap.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewHealthChecker creates a health checker of the pool's upstreams with the
+// configured interval, timeout and thresholds.
+func NewHealthChecker(pool *Pool, cfg *config.Config, logger *zap.Logger) *HealthChecker {
+	healthyThreshold, unhealthyThreshold := cfg.HealthyThreshold, cfg.UnhealthyThreshold
+	if healthyThreshold < 1 {
+		healthyThreshold = 1
+	}
+	if unhealthyThreshold < 1 {
+		unhealthyThreshold = 1
+	}
+
+	return &HealthChecker{
+		pool:               pool,
+		client:             &http.Client{Timeout: cfg.HealthCheckTimeout},
+		interval:           cfg.HealthCheckInterval,
+		healthyThreshold:   healthyThreshold,
+		unhealthyThreshold: unhealthyThreshold,
+		logger:             logger,
+	}
+}
+
+// Start checks every upstream right away and then once every interval, until
+// Stop is called. A zero interval disables the checks.
+func (h *HealthChecker) Start() {
+	if h.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+
+	h.logger.Info("Starting upstream health checks", zap.Duration("interval", h.interval))
+
+	go func() {
+		defer close(h.done)
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			h.CheckAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the checks and waits for running ones to finish.
+func (h *HealthChecker) Stop() {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+}
+
+// CheckAll checks every upstream concurrently and records the results.
+func (h *HealthChecker) CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, upstream := range h.pool.Upstreams() {
+		wg.Add(1)
+		go func(upstream *Upstream) {
+			defer wg.Done()
+
+			err := h.check(ctx, upstream)
+			if ctx.Err() != nil {
+				// Stopped, not a verdict on the upstream
+				return
+			}
+			h.record(upstream, err)
+		}(upstream)
+	}
+	wg.Wait()
+}
+
+// check asks the upstream for its health; anything but a 200 fails.
+func (h *HealthChecker) check(ctx context.Context, upstream *Upstream) error {
+	target := *upstream.URL
+	target.Path = "/health"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// record applies a check result to the upstream and logs state transitions.
+func (h *HealthChecker) record(upstream *Upstream, err error) {
+	upstream.mu.Lock()
+	previous := upstream.health.record(err, h.pool.now(), h.healthyThreshold, h.unhealthyThreshold)
+	current := upstream.health.state
+	upstream.mu.Unlock()
+
+	if current == previous {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("upstream", upstream.URL.String()),
+		zap.String("from", string(previous)),
+		zap.String("to", string(current)),
+	}
+	if current == Unhealthy {
+		h.logger.Warn("Handler service became unhealthy", append(fields, zap.Error(err))...)
+		return
+	}
+	h.logger.Info("Handler service became healthy", fields...)
+}
+
+// RegisterHealthRoutes registers the upstream status route on the given
+// router, next to the /health route of the service itself.
+func (g *Gateway) RegisterHealthRoutes(r gin.IRoutes) {
+	r.GET("/health/upstreams", g.UpstreamsHealth)
+}
+
+// UpstreamsHealth handles reporting the health of every handler replica. It
+// answers 503 when none is available.
+func (g *Gateway) UpstreamsHealth(c *gin.Context) {
+	now := g.pool.now()
+
+	response := UpstreamsStatus{Status: string(Healthy), Upstreams: []UpstreamStatus{}}
+	available := false
+	for _, upstream := range g.pool.Upstreams() {
+		status := upstream.status(now)
+		available = available || status.Available
+		response.Upstreams = append(response.Upstreams, status)
+	}
+
+	if !available {
+		response.Status = string(Unhealthy)
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// retryAfter returns the Retry-After value of 503 responses for want of a
+// healthy upstream: the time until the next health check.
+func (g *Gateway) retryAfter() string {
+	seconds := int(g.health.interval.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
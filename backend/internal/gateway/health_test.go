@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+func TestHealthRecord(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("connection refused")
+	r := healthRecord{state: Unknown}
+
+	// The first check settles an unknown upstream
+	assert.Equal(t, Unknown, r.record(nil, now, 2, 3))
+	assert.Equal(t, Healthy, r.state)
+	assert.Equal(t, now, r.since)
+
+	// Failures below the threshold keep it healthy
+	r.record(failure, now.Add(time.Second), 2, 3)
+	r.record(failure, now.Add(2*time.Second), 2, 3)
+	assert.Equal(t, Healthy, r.state)
+	assert.Equal(t, "connection refused", r.lastError)
+
+	assert.Equal(t, Healthy, r.record(failure, now.Add(3*time.Second), 2, 3))
+	assert.Equal(t, Unhealthy, r.state)
+	assert.Equal(t, now.Add(3*time.Second), r.since)
+
+	// So do passes below the threshold, the other way round
+	r.record(nil, now.Add(4*time.Second), 2, 3)
+	assert.Equal(t, Unhealthy, r.state)
+	assert.Empty(t, r.lastError)
+	assert.Equal(t, Unhealthy, r.record(nil, now.Add(5*time.Second), 2, 3))
+	assert.Equal(t, Healthy, r.state)
+}
+
+// setupHealthGateway runs a gateway in front of the given handler replicas,
+// with health checks triggered by the test.
+func setupHealthGateway(t *testing.T, upstreams ...string) (*Gateway, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+
+	gw, err := NewGateway(&config.Config{
+		Role:                "gateway",
+		HandlerURLs:         upstreams,
+		HealthCheckInterval: 5 * time.Second,
+		HealthCheckTimeout:  time.Second,
+		HealthyThreshold:    1,
+		UnhealthyThreshold:  1,
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	engine := gin.New()
+	gw.RegisterRoutes(engine.Group("/api/v1"))
+	gw.RegisterHealthRoutes(engine)
+	return gw, engine
+}
+
+func TestHealthChecker_CheckAll(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/health", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	gw, engine := setupHealthGateway(t, healthy.URL, failing.URL, down.URL)
+	gw.health.CheckAll(context.Background())
+
+	w := serve(engine, httptest.NewRequest(http.MethodGet, "/health/upstreams", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response UpstreamsStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "healthy", response.Status)
+	require.Len(t, response.Upstreams, 3)
+
+	assert.Equal(t, Healthy, response.Upstreams[0].Health)
+	assert.True(t, response.Upstreams[0].Available)
+	assert.NotNil(t, response.Upstreams[0].LastCheck)
+
+	assert.Equal(t, Unhealthy, response.Upstreams[1].Health)
+	assert.False(t, response.Upstreams[1].Available)
+	assert.Equal(t, "health check answered 503", response.Upstreams[1].LastError)
+
+	assert.Equal(t, Unhealthy, response.Upstreams[2].Health)
+	assert.NotEmpty(t, response.Upstreams[2].LastError)
+}
+
+func TestProxyToHandler_FailsFastWithoutHealthyUpstream(t *testing.T) {
+	var proxied atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		proxied.Add(1)
+	}))
+	defer upstream.Close()
+
+	gw, engine := setupHealthGateway(t, upstream.URL)
+	gw.health.CheckAll(context.Background())
+
+	w := serve(engine, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	assert.Zero(t, proxied.Load())
+
+	w = serve(engine, httptest.NewRequest(http.MethodGet, "/health/upstreams", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"unhealthy"`)
+}
@@ -0,0 +1,290 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxJWKSSize bounds the size of a JSON Web Key Set.
+const maxJWKSSize = 1 << 20
+
+// jwksReloadInterval is how soon a token signed with an unknown key may load
+// the key set again, so that key rotations are picked up without letting
+// made-up key IDs hammer the issuer.
+const jwksReloadInterval = 30 * time.Second
+
+// verificationKey is a key of a key set and the algorithm it verifies.
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet holds the keys of a JSON Web Key Set, loaded from a file or an
+// HTTP(S) URL and loaded again every interval. RSA keys verify RS256 tokens,
+// symmetric ("oct") keys HS256 tokens.
+type KeySet struct {
+	source   string
+	client   *http.Client
+	interval time.Duration
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	keys     map[string]verificationKey
+	loadedAt time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewKeySet creates the key set of a file or URL, loading it right away. A
+// file that fails to load is a configuration error; a URL that fails is only
+// logged, as its server may not be up yet, and is loaded again on demand.
+func NewKeySet(source string, interval time.Duration, logger *zap.Logger) (*KeySet, error) {
+	s := &KeySet{
+		source:   source,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+		keys:     map[string]verificationKey{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Load(ctx); err != nil && !s.remote() {
+		return nil, err
+	}
+	return s, nil
+}
+
+// remote reports whether the key set is served over HTTP(S).
+func (s *KeySet) remote() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+// Start loads the key set again once every interval, until Stop is called.
+func (s *KeySet) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = s.Load(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the reloading.
+func (s *KeySet) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Load replaces the keys with those of the source. A failed load keeps the
+// current keys.
+func (s *KeySet) Load(ctx context.Context) error {
+	s.mu.Lock()
+	s.loadedAt = s.now()
+	s.mu.Unlock()
+
+	return s.load(ctx)
+}
+
+func (s *KeySet) load(ctx context.Context) error {
+	data, err := s.read(ctx)
+	if err == nil {
+		var keys map[string]verificationKey
+		if keys, err = parseJWKS(data); err == nil {
+			s.mu.Lock()
+			s.keys = keys
+			s.mu.Unlock()
+
+			s.logger.Info("Loaded JSON Web Key Set", zap.String("source", s.source), zap.Int("keys", len(keys)))
+			return nil
+		}
+	}
+
+	if ctx.Err() == nil {
+		s.logger.Warn("Failed to load JSON Web Key Set", zap.String("source", s.source), zap.Error(err))
+	}
+	return err
+}
+
+// read returns the key set document.
+func (s *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !s.remote() {
+		data, err := os.ReadFile(s.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key set: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key set request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch key set: server answered %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key set: %w", err)
+	}
+	return data, nil
+}
+
+// Key returns the key of a key ID for an algorithm. A token without key ID
+// uses the only key of its algorithm. An unknown key loads the key set again,
+// at most once every jwksReloadInterval, in case the issuer rotated its keys.
+func (s *KeySet) Key(ctx context.Context, kid, alg string) (interface{}, bool) {
+	if key, ok := s.lookup(kid, alg); ok {
+		return key, true
+	}
+
+	// Claim the reload, so that concurrent requests do not load it too
+	s.mu.Lock()
+	stale := s.now().Sub(s.loadedAt) >= jwksReloadInterval
+	if stale {
+		s.loadedAt = s.now()
+	}
+	s.mu.Unlock()
+
+	if !stale || s.load(ctx) != nil {
+		return nil, false
+	}
+	return s.lookup(kid, alg)
+}
+
+func (s *KeySet) lookup(kid, alg string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if key, ok := s.keys[kid]; ok {
+		if key.alg != alg {
+			return nil, false
+		}
+		return key.key, true
+	}
+	if kid != "" {
+		return nil, false
+	}
+
+	var found interface{}
+	count := 0
+	for _, key := range s.keys {
+		if key.alg == alg {
+			found = key.key
+			count++
+		}
+	}
+	return found, count == 1
+}
+
+// jsonWebKey is a key of a JSON Web Key Set (RFC 7517), with the members of
+// RSA and symmetric keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// parseJWKS returns the signature verification keys of a key set by key ID.
+// Encryption keys and key types other than RSA and oct are skipped.
+func parseJWKS(data []byte) (map[string]verificationKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse key set: %w", err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		switch jwk.Kty {
+		case "RSA":
+			if jwk.Alg != "" && jwk.Alg != "RS256" {
+				continue
+			}
+			key, err := rsaPublicKey(jwk.N, jwk.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA key %q: %w", jwk.Kid, err)
+			}
+			keys[jwk.Kid] = verificationKey{alg: "RS256", key: key}
+		case "oct":
+			if jwk.Alg != "" && jwk.Alg != "HS256" {
+				continue
+			}
+			secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("invalid symmetric key %q", jwk.Kid)
+			}
+			keys[jwk.Kid] = verificationKey{alg: "HS256", key: secret}
+		}
+	}
+	return keys, nil
+}
+
+// rsaPublicKey decodes the base64url modulus and exponent of an RSA key.
+func rsaPublicKey(n, e string) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil || len(modulus) == 0 {
+		return nil, fmt.Errorf("invalid modulus")
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil || len(exponent) == 0 || len(exponent) > 4 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
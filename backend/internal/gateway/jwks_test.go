@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testRSAKey signs the RS256 tokens of the tests.
+var testRSAKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// rsaJWK returns the JSON Web Key of an RSA public key.
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func jwksDocument(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys, err := parseJWKS(jwksDocument(t,
+		rsaJWK("rsa-1", &testRSAKey.PublicKey),
+		map[string]string{"kty": "oct", "kid": "hmac-1", "k": base64.RawURLEncoding.EncodeToString([]byte("secret"))},
+		map[string]string{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		map[string]string{"kty": "EC", "kid": "ec-1", "crv": "P-256"},
+	))
+	require.NoError(t, err)
+
+	require.Len(t, keys, 2)
+	assert.Equal(t, "RS256", keys["rsa-1"].alg)
+	assert.Equal(t, &testRSAKey.PublicKey, keys["rsa-1"].key)
+	assert.Equal(t, "HS256", keys["hmac-1"].alg)
+	assert.Equal(t, []byte("secret"), keys["hmac-1"].key)
+
+	_, err = parseJWKS([]byte(`{"keys":[{"kty":"RSA","kid":"bad","n":"","e":"AQAB"}]}`))
+	assert.Error(t, err)
+	_, err = parseJWKS([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestKeySet_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksDocument(t, rsaJWK("rsa-1", &testRSAKey.PublicKey)), 0o600))
+
+	keys, err := NewKeySet(path, 0, zap.NewNop())
+	require.NoError(t, err)
+
+	key, ok := keys.Key(context.Background(), "rsa-1", "RS256")
+	assert.True(t, ok)
+	assert.Equal(t, &testRSAKey.PublicKey, key)
+
+	// The only key of an algorithm serves tokens without key ID, and a key
+	// never serves another algorithm
+	_, ok = keys.Key(context.Background(), "", "RS256")
+	assert.True(t, ok)
+	_, ok = keys.Key(context.Background(), "rsa-1", "HS256")
+	assert.False(t, ok)
+
+	_, err = NewKeySet(filepath.Join(t.TempDir(), "missing.json"), 0, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestKeySet_URLReloadsUnknownKeys(t *testing.T) {
+	var fetches atomic.Int32
+	var document atomic.Value
+	document.Store(jwksDocument(t, rsaJWK("rsa-1", &testRSAKey.PublicKey)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	keys, err := NewKeySet(server.URL, 0, zap.NewNop())
+	require.NoError(t, err)
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	// The issuer rotates its key; an unknown key ID loads the set again,
+	// but not more than once per interval
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	document.Store(jwksDocument(t, rsaJWK("rsa-2", &rotated.PublicKey)))
+
+	_, ok := keys.Key(context.Background(), "rsa-2", "RS256")
+	assert.False(t, ok)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(jwksReloadInterval)
+	key, ok := keys.Key(context.Background(), "rsa-2", "RS256")
+	assert.True(t, ok)
+	assert.Equal(t, &rotated.PublicKey, key)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	_, ok = keys.Key(context.Background(), "made-up", "RS256")
+	assert.False(t, ok)
+	assert.Equal(t, int32(2), fetches.Load())
+}
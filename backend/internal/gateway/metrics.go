@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are the Prometheus metrics of the gateway's proxying.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	breakerState       *prometheus.GaugeVec
+	breakerTransitions *prometheus.CounterVec
+	attempts           *prometheus.CounterVec
+	retries            *prometheus.CounterVec
+	retriesDenied      prometheus.Counter
+}
+
+// NewMetrics creates the metrics in a registry of their own.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		breakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gateway_upstream_circuit_breaker_state",
+			Help: "Circuit breaker state of each handler replica: 1 for the current state, 0 for the others.",
+		}, []string{"upstream", "state"}),
+		breakerTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_upstream_circuit_breaker_transitions_total",
+			Help: "Circuit breaker state changes of each handler replica, by new state.",
+		}, []string{"upstream", "state"}),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_upstream_attempts_total",
+			Help: "Attempts to forward a request to each handler replica, by result.",
+		}, []string{"upstream", "result"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_retries_total",
+			Help: "Requests retried on another attempt, by method.",
+		}, []string{"method"}),
+		retriesDenied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gateway_retries_budget_exhausted_total",
+			Help: "Retries not made because the retry budget was exhausted.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.breakerState,
+		m.breakerTransitions,
+		m.attempts,
+		m.retries,
+		m.retriesDenied,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// setBreakerState sets the state gauge of an upstream.
+func (m *Metrics) setBreakerState(upstream string, current BreakerState) {
+	for _, state := range breakerStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		m.breakerState.WithLabelValues(upstream, string(state)).Set(value)
+	}
+}
+
+// breakerChanged records a state change of an upstream's circuit breaker.
+func (m *Metrics) breakerChanged(upstream string, current BreakerState) {
+	m.setBreakerState(upstream, current)
+	m.breakerTransitions.WithLabelValues(upstream, string(current)).Inc()
+}
+
+// forget drops the series of an upstream that left the pool.
+func (m *Metrics) forget(upstream string) {
+	labels := prometheus.Labels{"upstream": upstream}
+	m.breakerState.DeletePartialMatch(labels)
+	m.breakerTransitions.DeletePartialMatch(labels)
+	m.attempts.DeletePartialMatch(labels)
+}
+
+// attempt records the result of forwarding a request to an upstream.
+func (m *Metrics) attempt(upstream string, failed bool) {
+	result := "success"
+	if failed {
+		result = "failure"
+	}
+	m.attempts.WithLabelValues(upstream, result).Inc()
+}
+
+// RegisterMetricsRoutes registers the /metrics route on the given router.
+func (g *Gateway) RegisterMetricsRoutes(r gin.IRoutes) {
+	r.GET("/metrics", gin.WrapH(g.metrics.Handler()))
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterMetricsRoutes_BreakerState(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	_, engine := setupRetryGateway(t, failing.URL)
+
+	// The first request fails three attempts, the second two before they
+	// open the breaker; the third fails fast
+	for i := 0; i < 3; i++ {
+		serve(engine, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+	}
+
+	w := serve(engine, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	metrics := w.Body.String()
+	assert.Contains(t, metrics, `gateway_upstream_circuit_breaker_state{state="open",upstream="`+failing.URL+`"} 1`)
+	assert.Contains(t, metrics, `gateway_upstream_circuit_breaker_state{state="closed",upstream="`+failing.URL+`"} 0`)
+	assert.Contains(t, metrics, `gateway_upstream_circuit_breaker_transitions_total{state="open",upstream="`+failing.URL+`"} 1`)
+	assert.Contains(t, metrics, `gateway_upstream_attempts_total{result="failure",upstream="`+failing.URL+`"} 5`)
+	assert.Contains(t, metrics, `gateway_retries_total{method="GET"} 4`)
+}
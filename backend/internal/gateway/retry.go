@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// maxRetryBody is the largest request body kept in memory so that the
+// request can be retried; larger and streamed bodies are sent only once.
+const maxRetryBody = 1 << 20
+
+// retryBudgetReserve is how many retries the budget can save up, so that a
+// quiet gateway can still retry a burst of failures.
+const retryBudgetReserve = 10
+
+// idempotentMethods are the methods whose requests may be sent again without
+// changing their effect.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// RetryPolicy decides whether and when a failed request is tried again on
+// another attempt. Only idempotent requests are retried, after a jittered
+// exponential backoff, and only as long as the retry budget allows.
+type RetryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	budget      *retryBudget
+	random      func() float64
+}
+
+// NewRetryPolicy creates the retry policy of the configuration.
+func NewRetryPolicy(cfg *config.Config) *RetryPolicy {
+	maxAttempts := cfg.RetryMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &RetryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   cfg.RetryBaseDelay,
+		maxDelay:    cfg.RetryMaxDelay,
+		budget:      newRetryBudget(cfg.RetryBudget),
+		random:      rand.Float64,
+	}
+}
+
+// attempts returns how many attempts a request may get: one unless its
+// method is idempotent and its body, if any, is small enough to be sent
+// again.
+func (p *RetryPolicy) attempts(r *http.Request) int {
+	if !idempotentMethods[r.Method] {
+		return 1
+	}
+	if r.Body != nil && r.Body != http.NoBody && (r.ContentLength < 0 || r.ContentLength > maxRetryBody) {
+		return 1
+	}
+	return p.maxAttempts
+}
+
+// backoff returns the wait after a failed attempt, drawn at random up to the
+// exponential delay of the attempt ("full jitter"), so that the retries of
+// many clients spread out instead of arriving together.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.maxDelay
+	if attempt < 32 && p.baseDelay<<(attempt-1) < p.maxDelay {
+		delay = p.baseDelay << (attempt - 1)
+	}
+	return time.Duration(p.random() * float64(delay))
+}
+
+// bufferBody reads a request body into memory, so that every attempt can
+// send it again.
+func bufferBody(r *http.Request) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRetryBody+1))
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(data) > maxRetryBody {
+		return fmt.Errorf("request body exceeds %d bytes", maxRetryBody)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil
+}
+
+// retryBudget limits retries to a share of the requests: every request adds
+// ratio to the budget, every retry takes one from it. It holds at most
+// retryBudgetReserve retries, and starts full.
+type retryBudget struct {
+	ratio float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	return &retryBudget{ratio: ratio, tokens: retryBudgetReserve}
+}
+
+// deposit adds a request to the budget.
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > retryBudgetReserve {
+		b.tokens = retryBudgetReserve
+	}
+}
+
+// withdraw takes a retry from the budget, reporting false if none is left.
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+func TestRetryPolicy_Attempts(t *testing.T) {
+	p := NewRetryPolicy(&config.Config{RetryMaxAttempts: 3})
+
+	assert.Equal(t, 3, p.attempts(httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)))
+	assert.Equal(t, 3, p.attempts(httptest.NewRequest(http.MethodPut, "/api/v1/labels/car", strings.NewReader(`{"color":"red"}`))))
+	assert.Equal(t, 1, p.attempts(httptest.NewRequest(http.MethodPost, "/api/v1/labels", strings.NewReader(`{"name":"car"}`))))
+
+	// A streamed body cannot be sent again
+	streamed := httptest.NewRequest(http.MethodPut, "/api/v1/labels/car", strings.NewReader(`{"color":"red"}`))
+	streamed.ContentLength = -1
+	assert.Equal(t, 1, p.attempts(streamed))
+
+	assert.Equal(t, 1, NewRetryPolicy(&config.Config{}).attempts(httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)))
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := NewRetryPolicy(&config.Config{RetryMaxAttempts: 5, RetryBaseDelay: 100 * time.Millisecond, RetryMaxDelay: time.Second})
+
+	p.random = func() float64 { return 1 }
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, p.backoff(4))
+	assert.Equal(t, time.Second, p.backoff(5))
+	assert.Equal(t, time.Second, p.backoff(40))
+
+	p.random = func() float64 { return 0.5 }
+	assert.Equal(t, 100*time.Millisecond, p.backoff(2))
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(0.5)
+
+	// The reserve allows a burst of retries
+	for i := 0; i < retryBudgetReserve; i++ {
+		assert.True(t, b.withdraw())
+	}
+	assert.False(t, b.withdraw())
+
+	// After that, one retry per two requests
+	b.deposit()
+	assert.False(t, b.withdraw())
+	b.deposit()
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+}
+
+func TestBufferBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/labels/car", strings.NewReader(`{"color":"red"}`))
+	require.NoError(t, bufferBody(r))
+
+	for i := 0; i < 2; i++ {
+		body, err := r.GetBody()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(body)
+		assert.Equal(t, `{"color":"red"}`, string(data))
+	}
+
+	large := httptest.NewRequest(http.MethodPut, "/api/v1/labels/car", strings.NewReader(strings.Repeat("x", maxRetryBody+1)))
+	assert.Error(t, bufferBody(large))
+}
+
+// setupRetryGateway runs a gateway retrying across the given handler
+// replicas without backoff.
+func setupRetryGateway(t *testing.T, upstreams ...string) (*Gateway, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+
+	gw, err := NewGateway(&config.Config{
+		Role:                "gateway",
+		HandlerURLs:         upstreams,
+		LoadBalancer:        RoundRobin,
+		UpstreamMaxFails:    5,
+		UpstreamFailTimeout: time.Minute,
+		RetryMaxAttempts:    3,
+		RetryBudget:         0.2,
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	engine := gin.New()
+	gw.RegisterRoutes(engine.Group("/api/v1"))
+	gw.RegisterMetricsRoutes(engine)
+	return gw, engine
+}
+
+func TestProxyToHandler_RetriesIdempotentRequests(t *testing.T) {
+	var failed atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer working.Close()
+
+	_, engine := setupRetryGateway(t, failing.URL, working.URL)
+
+	// Round-robin sends every request to the failing replica first, and
+	// every one is retried on the other, the body included
+	for i := 0; i < 4; i++ {
+		w := serve(engine, httptest.NewRequest(http.MethodPut, "/api/v1/labels/car", strings.NewReader(`{"color":"red"}`)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"color":"red"}`, w.Body.String())
+	}
+	assert.Equal(t, int32(4), failed.Load())
+
+	// Creations are not retried
+	w := serve(engine, httptest.NewRequest(http.MethodPost, "/api/v1/labels", strings.NewReader(`{"name":"car"}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, int32(5), failed.Load())
+}
+
+func TestProxyToHandler_RetryBudget(t *testing.T) {
+	var attempts atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	gw, engine := setupRetryGateway(t, failing.URL)
+	gw.pool.maxFails = 1000
+	for _, upstream := range gw.pool.Upstreams() {
+		upstream.breaker.maxFails = 1000
+	}
+
+	// The reserve covers the first retries; then only a fifth of the
+	// requests is retried
+	for i := 0; i < 20; i++ {
+		w := serve(engine, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	}
+	assert.Less(t, int(attempts.Load()), 20+retryBudgetReserve+20/5+1)
+	assert.Greater(t, int(attempts.Load()), 20+retryBudgetReserve-1)
+}
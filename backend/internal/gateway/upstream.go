@@ -0,0 +1,275 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// ErrNoUpstream is returned when every handler replica is out of the rotation.
+var ErrNoUpstream = errors.New("no handler service is available")
+
+// Upstream is a handler replica the gateway forwards requests to.
+type Upstream struct {
+	URL *url.URL
+
+	// outstanding counts the requests in flight, bodies included.
+	outstanding atomic.Int64
+
+	mu      sync.Mutex
+	breaker circuitBreaker
+	health  healthRecord
+}
+
+// Outstanding returns the number of requests in flight to the upstream.
+func (u *Upstream) Outstanding() int64 {
+	return u.outstanding.Load()
+}
+
+// available reports whether the upstream is in the rotation: it is not
+// unhealthy, and its circuit breaker lets requests through.
+func (u *Upstream) available(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.health.state != Unhealthy && u.breaker.ready(now)
+}
+
+// Pool tracks the handler replicas and picks one per request. Every replica
+// has a circuit breaker: UpstreamMaxFails failures in a row open it, taking
+// the replica out of the rotation for UpstreamFailTimeout; after that it is
+// half-open and lets UpstreamHalfOpenRequests trial requests through at a
+// time. A successful trial closes it, a failed one opens it again. Replicas
+// the HealthChecker finds unhealthy stay out until they are healthy again.
+type Pool struct {
+	discoverer  Discoverer
+	balancer    Balancer
+	interval    time.Duration
+	maxFails    int
+	failTimeout time.Duration
+	maxTrials   int
+	metrics     *Metrics
+	logger      *zap.Logger
+	now         func() time.Time
+
+	mu        sync.RWMutex
+	upstreams []*Upstream
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPool creates a pool of the configured handler replicas, discovering them
+// once right away.
+func NewPool(cfg *config.Config, metrics *Metrics, logger *zap.Logger) (*Pool, error) {
+	discoverer, err := NewDiscoverer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	balancer, err := NewBalancer(cfg.LoadBalancer)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pool{
+		discoverer:  discoverer,
+		balancer:    balancer,
+		interval:    cfg.HandlerDiscoveryInterval,
+		maxFails:    cfg.UpstreamMaxFails,
+		failTimeout: cfg.UpstreamFailTimeout,
+		maxTrials:   cfg.UpstreamHalfOpenRequests,
+		metrics:     metrics,
+		logger:      logger,
+		now:         time.Now,
+	}
+
+	// A failed lookup is not fatal: the replicas may not be up yet, and the
+	// lookup is repeated
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = p.Refresh(ctx)
+
+	return p, nil
+}
+
+// Start repeats the discovery of DNS discovered replicas once every interval,
+// until Stop is called. Static lists are not refreshed.
+func (p *Pool) Start() {
+	if _, static := p.discoverer.(staticDiscoverer); static || p.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = p.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the discovery.
+func (p *Pool) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// Refresh replaces the replicas with the discovered ones. Replicas found
+// again keep their state, so a lookup does not take failing ones back in. A
+// failed or empty lookup keeps the current replicas.
+func (p *Pool) Refresh(ctx context.Context) error {
+	urls, err := p.discoverer.Discover(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.Warn("Failed to discover handler services", zap.Error(err))
+		}
+		return err
+	}
+	if len(urls) == 0 {
+		p.logger.Warn("Discovered no handler services, keeping the current ones")
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]*Upstream, len(p.upstreams))
+	for _, upstream := range p.upstreams {
+		current[upstream.URL.String()] = upstream
+	}
+
+	upstreams := make([]*Upstream, 0, len(urls))
+	for _, u := range urls {
+		upstream, ok := current[u.String()]
+		if ok {
+			delete(current, u.String())
+		} else {
+			upstream = &Upstream{URL: u, breaker: newCircuitBreaker(p.maxFails, p.failTimeout, p.maxTrials)}
+			p.metrics.setBreakerState(u.String(), BreakerClosed)
+			p.logger.Info("Added handler service", zap.String("upstream", u.String()))
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	for key := range current {
+		p.metrics.forget(key)
+		p.logger.Info("Removed handler service", zap.String("upstream", key))
+	}
+
+	p.upstreams = upstreams
+	return nil
+}
+
+// Upstreams returns the current replicas.
+func (p *Pool) Upstreams() []*Upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]*Upstream(nil), p.upstreams...)
+}
+
+// Pick chooses the replica of a request among those in the rotation; key
+// identifies what the request is about for consistent hashing. The request
+// must be reported to Done once it is over.
+func (p *Pool) Pick(key string) (*Upstream, error) {
+	now := p.now()
+
+	var available []*Upstream
+	for _, upstream := range p.Upstreams() {
+		if upstream.available(now) {
+			available = append(available, upstream)
+		}
+	}
+
+	// A half-open breaker may have run out of trials since; then the pick
+	// goes to one of the others
+	for len(available) > 0 {
+		upstream := p.balancer.Pick(available, key)
+		if p.allow(upstream, now) {
+			upstream.outstanding.Add(1)
+			return upstream, nil
+		}
+		available = without(available, upstream)
+	}
+	return nil, ErrNoUpstream
+}
+
+// allow asks the circuit breaker of an upstream to let a request through.
+func (p *Pool) allow(upstream *Upstream, now time.Time) bool {
+	upstream.mu.Lock()
+	ok, previous := upstream.breaker.allow(now)
+	current := upstream.breaker.state
+	upstream.mu.Unlock()
+
+	if current != previous {
+		p.breakerChanged(upstream, current, 0, nil)
+	}
+	return ok
+}
+
+// Done reports a request to a picked replica as over. An error means the
+// replica could not be reached or failed to answer.
+func (p *Pool) Done(upstream *Upstream, err error) {
+	upstream.outstanding.Add(-1)
+	p.metrics.attempt(upstream.URL.String(), err != nil)
+
+	upstream.mu.Lock()
+	previous := upstream.breaker.record(err != nil, p.now())
+	current := upstream.breaker.state
+	failures := upstream.breaker.failures
+	upstream.mu.Unlock()
+
+	if current != previous {
+		p.breakerChanged(upstream, current, failures, err)
+	}
+}
+
+// breakerChanged logs and counts a state change of an upstream's circuit
+// breaker.
+func (p *Pool) breakerChanged(upstream *Upstream, current BreakerState, failures int, err error) {
+	p.metrics.breakerChanged(upstream.URL.String(), current)
+
+	fields := []zap.Field{zap.String("upstream", upstream.URL.String())}
+	switch current {
+	case BreakerOpen:
+		p.logger.Warn("Opened circuit breaker of failing handler service", append(fields,
+			zap.Int("failures", failures),
+			zap.Duration("timeout", p.failTimeout),
+			zap.Error(err),
+		)...)
+	case BreakerHalfOpen:
+		p.logger.Info("Half-opened circuit breaker of handler service, trying it again", fields...)
+	case BreakerClosed:
+		p.logger.Info("Closed circuit breaker of recovered handler service", fields...)
+	}
+}
+
+// without returns the upstreams but one.
+func without(upstreams []*Upstream, excluded *Upstream) []*Upstream {
+	rest := make([]*Upstream, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		if upstream != excluded {
+			rest = append(rest, upstream)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// fakeDiscoverer returns whatever URLs a test sets.
+type fakeDiscoverer struct {
+	urls []*url.URL
+	err  error
+}
+
+func (d *fakeDiscoverer) Discover(ctx context.Context) ([]*url.URL, error) {
+	return d.urls, d.err
+}
+
+func newTestPool(t *testing.T, hosts ...string) (*Pool, *time.Time) {
+	p, err := NewPool(&config.Config{
+		HandlerURLs:         []string{"http://placeholder:8081"},
+		UpstreamMaxFails:    2,
+		UpstreamFailTimeout: 10 * time.Second,
+	}, NewMetrics(), zap.NewNop())
+	require.NoError(t, err)
+
+	discoverer := &fakeDiscoverer{}
+	for _, host := range hosts {
+		discoverer.urls = append(discoverer.urls, &url.URL{Scheme: "http", Host: host})
+	}
+	p.discoverer = discoverer
+	require.NoError(t, p.Refresh(context.Background()))
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+// pickHost picks until the pool chooses the upstream of host, reporting the
+// other picks as successful.
+func pickHost(t *testing.T, p *Pool, host string) *Upstream {
+	for i := 0; i < 10; i++ {
+		upstream, err := p.Pick("")
+		require.NoError(t, err)
+		if upstream.URL.Host == host {
+			return upstream
+		}
+		p.Done(upstream, nil)
+	}
+	require.FailNow(t, "upstream not picked", host)
+	return nil
+}
+
+func TestPool_CircuitBreaker(t *testing.T) {
+	p, now := newTestPool(t, "handler-1:8081", "handler-2:8081")
+	failing := p.Upstreams()[0]
+
+	// A single failure keeps the upstream in the rotation
+	p.Done(pickHost(t, p, "handler-1:8081"), errors.New("connection refused"))
+	assert.True(t, failing.available(*now))
+
+	p.Done(pickHost(t, p, "handler-1:8081"), errors.New("connection refused"))
+	assert.False(t, failing.available(*now))
+	assert.Equal(t, BreakerOpen, failing.breaker.state)
+	for i := 0; i < 4; i++ {
+		upstream, err := p.Pick("")
+		require.NoError(t, err)
+		assert.Equal(t, "handler-2:8081", upstream.URL.Host)
+		p.Done(upstream, nil)
+	}
+
+	// After the timeout a trial request is let through, and no other while
+	// it is in flight; its failure opens the breaker again
+	*now = now.Add(10 * time.Second)
+	assert.True(t, failing.available(*now))
+	trial := pickHost(t, p, "handler-1:8081")
+	assert.Equal(t, BreakerHalfOpen, failing.breaker.state)
+	assert.False(t, failing.available(*now))
+	p.Done(trial, errors.New("connection refused"))
+	assert.Equal(t, BreakerOpen, failing.breaker.state)
+	assert.False(t, failing.available(*now))
+
+	// A successful trial closes it for good
+	*now = now.Add(10 * time.Second)
+	p.Done(pickHost(t, p, "handler-1:8081"), nil)
+	assert.Equal(t, BreakerClosed, failing.breaker.state)
+	p.Done(pickHost(t, p, "handler-1:8081"), errors.New("connection refused"))
+	assert.True(t, failing.available(*now))
+}
+
+func TestPool_NoUpstream(t *testing.T) {
+	p, _ := newTestPool(t, "handler-1:8081")
+	upstream := p.Upstreams()[0]
+	p.Done(upstream, errors.New("timeout"))
+	p.Done(upstream, errors.New("timeout"))
+
+	_, err := p.Pick("")
+	assert.ErrorIs(t, err, ErrNoUpstream)
+}
+
+func TestPool_CountsOutstanding(t *testing.T) {
+	p, _ := newTestPool(t, "handler-1:8081")
+
+	upstream, err := p.Pick("")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), upstream.Outstanding())
+
+	p.Done(upstream, nil)
+	assert.Equal(t, int64(0), upstream.Outstanding())
+}
+
+func TestPool_Refresh(t *testing.T) {
+	p, now := newTestPool(t, "handler-1:8081", "handler-2:8081")
+	failing := p.Upstreams()[1]
+	p.Done(failing, errors.New("timeout"))
+	p.Done(failing, errors.New("timeout"))
+
+	discoverer := p.discoverer.(*fakeDiscoverer)
+	discoverer.urls = []*url.URL{{Scheme: "http", Host: "handler-2:8081"}, {Scheme: "http", Host: "handler-3:8081"}}
+	require.NoError(t, p.Refresh(context.Background()))
+
+	upstreams := p.Upstreams()
+	require.Len(t, upstreams, 2)
+	// A replica found again keeps its state
+	assert.Same(t, failing, upstreams[0])
+	assert.False(t, upstreams[0].available(*now))
+	assert.Equal(t, "handler-3:8081", upstreams[1].URL.Host)
+
+	// Failed and empty lookups keep the current replicas
+	discoverer.err = errors.New("no such host")
+	assert.Error(t, p.Refresh(context.Background()))
+	discoverer.err, discoverer.urls = nil, nil
+	assert.NoError(t, p.Refresh(context.Background()))
+	assert.Len(t, p.Upstreams(), 2)
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// annotationsMethod dispatches the custom methods of the annotation
+// collection, POST /annotations:<method>. Gin takes the colon for the start of
+// a path parameter, so the parameter holds the method including the colon.
+func (h *Handler) annotationsMethod(c *gin.Context) {
+	switch c.Param("method") {
+	case ":batch":
+		h.Batch(c)
+	default:
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "unknown annotation method",
+		})
+	}
+}
+
+// Batch handles applying many annotation writes in one transaction.
+// @Summary Batch annotation writes
+// @Description Create, update and delete annotations of any point clouds in one transaction. Atomic batches apply all operations or none; otherwise every operation succeeds or fails on its own. Every operation is answered with the status and body of the corresponding single request; operations of a failed atomic batch that were not at fault are answered with 424.
+// @Tags annotations
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchRequest true "Operations"
+// @Success 200 {object} models.BatchResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid request, or atomic batch failed with 400"
+// @Failure 404 {object} models.BatchResponse "Atomic batch failed with 404"
+// @Failure 409 {object} models.BatchResponse "Atomic batch failed with 409"
+// @Failure 412 {object} models.BatchResponse "Atomic batch failed with 412"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/annotations:batch [post]
+func (h *Handler) Batch(c *gin.Context) {
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid batch request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := writeContext(c)
+	refs := newMemoRefs(h.repo)
+	results := make([]models.BatchResult, len(req.Operations))
+
+	// Operations failing their checks are left out of the transaction
+	var ops []models.BatchOperation
+	var indices []int
+	for i := range req.Operations {
+		if failure := h.checkOperation(ctx, refs, &req.Operations[i]); failure != nil {
+			results[i] = failure.batchResult()
+			continue
+		}
+		ops = append(ops, req.Operations[i])
+		indices = append(indices, i)
+	}
+
+	failed := len(ops) < len(req.Operations)
+	if req.Atomic && failed {
+		h.writeFailedBatch(c, results)
+		return
+	}
+
+	var items []database.BatchItem
+	if len(ops) > 0 {
+		var err error
+		items, err = h.repo.Batch(ctx, ops, req.Atomic)
+		if err != nil {
+			h.logger.Error("Failed to apply batch", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "internal_error",
+				Message: "failed to apply batch",
+			})
+			return
+		}
+	}
+
+	// Annotations changed by the batch leave the cache, once per point cloud
+	changed := make(map[string]bool)
+	var changes []*models.AnnotationEvent
+	for j, item := range items {
+		op := &ops[j]
+		if item.Err != nil {
+			results[indices[j]] = h.operationError(op, item.Err).batchResult()
+			failed = true
+			continue
+		}
+
+		switch op.Op {
+		case models.BatchCreate:
+			results[indices[j]] = models.BatchResult{Status: http.StatusCreated, Annotation: item.Annotation}
+			changed[op.PointCloudID] = true
+			changes = append(changes, annotationEvent(ctx, models.EventCreated, item.Annotation))
+		case models.BatchUpdate:
+			results[indices[j]] = models.BatchResult{Status: http.StatusOK, Annotation: item.Annotation}
+			changed[op.PointCloudID] = true
+			changes = append(changes, annotationEvent(ctx, models.EventUpdated, item.Annotation))
+		default:
+			results[indices[j]] = models.BatchResult{Status: http.StatusNoContent}
+			changed[op.PointCloudID] = true
+			changes = append(changes, deletionEvent(ctx, op.PointCloudID, op.ID))
+		}
+	}
+
+	if req.Atomic && failed {
+		h.writeFailedBatch(c, results)
+		return
+	}
+
+	ctx = committed(ctx)
+	for pointCloudID := range changed {
+		_ = h.cache.InvalidateAll(ctx, pointCloudID)
+	}
+	h.publish(ctx, changes...)
+
+	c.JSON(http.StatusOK, models.BatchResponse{Data: results})
+}
+
+// checkOperation checks an operation of a batch like the corresponding
+// single request. Deletes are checked by the repository alone.
+func (h *Handler) checkOperation(ctx context.Context, refs annotationRefs, op *models.BatchOperation) *requestError {
+	if err := binding.Validator.ValidateStruct(op); err != nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+	if err := op.Validate(); err != nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	switch op.Op {
+	case models.BatchCreate:
+		return h.checkCreate(ctx, refs, op.PointCloudID, op.Create)
+	case models.BatchUpdate:
+		return h.checkUpdate(ctx, refs, op.PointCloudID, op.Update)
+	}
+	return nil
+}
+
+// operationError returns the response for an operation of a batch the
+// repository failed to apply.
+func (h *Handler) operationError(op *models.BatchOperation, err error) *requestError {
+	if errors.Is(err, models.ErrVersionMismatch) {
+		return preconditionFailed()
+	}
+	if errors.Is(err, database.ErrAnnotationNotFound) {
+		return newRequestError(http.StatusNotFound, "not_found", "annotation not found")
+	}
+	if failure := trackError(err); failure != nil {
+		return failure
+	}
+
+	h.logger.Error("Failed to apply batch operation", zap.String("op", op.Op), zap.String("id", op.ID), zap.Error(err))
+	return newRequestError(http.StatusInternalServerError, "internal_error", "failed to "+op.Op+" annotation")
+}
+
+// writeFailedBatch answers an atomic batch that was not applied with the
+// status of its first failing operation. The operations that did not fail are
+// answered with 424 Failed Dependency.
+func (h *Handler) writeFailedBatch(c *gin.Context, results []models.BatchResult) {
+	status := 0
+	for i := range results {
+		if results[i].Error == nil {
+			results[i] = models.BatchResult{
+				Status: http.StatusFailedDependency,
+				Error: &models.ErrorResponse{
+					Error:   "not_applied",
+					Message: "the batch is atomic and another operation failed",
+				},
+			}
+		} else if status == 0 {
+			status = results[i].Status
+		}
+	}
+
+	c.JSON(status, models.BatchResponse{Data: results})
+}
+
+// batchResult returns the error as the result of an operation of a batch.
+func (e *requestError) batchResult() models.BatchResult {
+	body := e.body
+	return models.BatchResult{Status: e.status, Error: &body}
+}
+
+// memoRefs reads each point cloud, label and track once, so that a batch does
+// not read them again for every operation referring to them. Errors are not
+// remembered.
+type memoRefs struct {
+	refs        annotationRefs
+	pointClouds map[string]*models.PointCloud
+	labels      map[string]*models.Label
+	tracks      map[string]*models.Track
+}
+
+func newMemoRefs(refs annotationRefs) *memoRefs {
+	return &memoRefs{
+		refs:        refs,
+		pointClouds: make(map[string]*models.PointCloud),
+		labels:      make(map[string]*models.Label),
+		tracks:      make(map[string]*models.Track),
+	}
+}
+
+// GetPointCloud reads a point cloud.
+func (m *memoRefs) GetPointCloud(ctx context.Context, id string) (*models.PointCloud, error) {
+	return memoize(ctx, m.pointClouds, id, m.refs.GetPointCloud)
+}
+
+// GetLabel reads a label.
+func (m *memoRefs) GetLabel(ctx context.Context, id string) (*models.Label, error) {
+	return memoize(ctx, m.labels, id, m.refs.GetLabel)
+}
+
+// GetTrack reads a track.
+func (m *memoRefs) GetTrack(ctx context.Context, id string) (*models.Track, error) {
+	return memoize(ctx, m.tracks, id, m.refs.GetTrack)
+}
+
+// memoize returns the value remembered for id, reading and remembering it
+// first if there is none. Missing values are remembered as nil.
+func memoize[T any](ctx context.Context, memo map[string]*T, id string, read func(context.Context, string) (*T, error)) (*T, error) {
+	if value, ok := memo[id]; ok {
+		return value, nil
+	}
+
+	value, err := read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	memo[id] = value
+	return value, nil
+}
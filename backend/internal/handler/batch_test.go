@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func postBatch(engine http.Handler, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations:batch", bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func batchStatuses(t *testing.T, w *httptest.ResponseRecorder) []int {
+	var response models.BatchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	statuses := make([]int, len(response.Data))
+	for i, result := range response.Data {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestBatch_Success(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	title := "Renamed"
+	ops := []models.BatchOperation{
+		{Op: models.BatchCreate, PointCloudID: testPointCloud.ID, Create: &models.CreateAnnotationRequest{X: 1, Y: 2, Z: 3, Title: "First"}},
+		{Op: models.BatchCreate, PointCloudID: testPointCloud.ID, Create: &models.CreateAnnotationRequest{X: 4, Y: 5, Z: 6, Title: "Second"}},
+		{Op: models.BatchUpdate, PointCloudID: testPointCloud.ID, ID: "a-1", Update: &models.UpdateAnnotationRequest{Title: &title}},
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "a-2", Version: 3},
+	}
+	items := []database.BatchItem{
+		{Annotation: &models.Annotation{ID: "new-1", PointCloudID: testPointCloud.ID, Title: "First", Version: 1}},
+		{Annotation: &models.Annotation{ID: "new-2", PointCloudID: testPointCloud.ID, Title: "Second", Version: 1}},
+		{Annotation: &models.Annotation{ID: "a-1", PointCloudID: testPointCloud.ID, Title: title, Version: 2}},
+		{},
+	}
+
+	// The point cloud is read once for the whole batch
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil).Once()
+	mockRepo.On("Batch", mock.Anything, ops, false).Return(items, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil).Once()
+
+	w := postBatch(engine, models.BatchRequest{Operations: ops})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{201, 201, 200, 204}, batchStatuses(t, w))
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatch_PartialFailure(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	ops := []models.BatchOperation{
+		{Op: models.BatchCreate, PointCloudID: "missing", Create: &models.CreateAnnotationRequest{X: 1, Y: 2, Z: 3, Title: "Lost"}},
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "gone"},
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "a-1"},
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID},
+	}
+
+	mockRepo.On("GetPointCloud", mock.Anything, "missing").Return(nil, nil)
+	mockRepo.On("Batch", mock.Anything, ops[1:3], false).Return([]database.BatchItem{
+		{Err: database.ErrAnnotationNotFound},
+		{},
+	}, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	w := postBatch(engine, models.BatchRequest{Operations: ops})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{404, 404, 204, 400}, batchStatuses(t, w))
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestBatch_AtomicRollback(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	ops := []models.BatchOperation{
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "a-1"},
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "a-2", Version: 2},
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "a-3"},
+	}
+
+	// The repository stops at the failing operation
+	mockRepo.On("Batch", mock.Anything, ops, true).Return([]database.BatchItem{
+		{},
+		{Err: models.ErrVersionMismatch},
+	}, nil)
+
+	w := postBatch(engine, models.BatchRequest{Atomic: true, Operations: ops})
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, []int{424, 412, 424}, batchStatuses(t, w))
+	mockCache.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatch_AtomicInvalidOperation(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	ops := []models.BatchOperation{
+		{Op: models.BatchDelete, PointCloudID: testPointCloud.ID, ID: "a-1"},
+		{Op: "merge", PointCloudID: testPointCloud.ID, ID: "a-2"},
+	}
+
+	w := postBatch(engine, models.BatchRequest{Atomic: true, Operations: ops})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []int{424, 400}, batchStatuses(t, w))
+	mockRepo.AssertNotCalled(t, "Batch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatch_InvalidRequest(t *testing.T) {
+	_, _, _, engine := setupTestHandler()
+
+	w := postBatch(engine, models.BatchRequest{})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnnotationsMethod_Unknown(t *testing.T) {
+	_, _, _, engine := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations:merge", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
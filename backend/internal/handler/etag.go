@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// errPreconditionFailed reports an If-Match header no version of the
+// annotation can satisfy.
+var errPreconditionFailed = errors.New("version precondition failed")
+
+// annotationETag returns the strong entity tag of an annotation version.
+func annotationETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// pageETag returns the strong entity tag of a page of annotations, a hash of
+// the page as it is served. It is computed once and kept on the page, so that
+// it is cached along with it.
+func pageETag(page *models.AnnotationPage) string {
+	if page.ETag == "" {
+		hash := sha1.New()
+		_ = json.NewEncoder(hash).Encode(models.AnnotationsResponse{Data: page.Annotations, NextCursor: page.NextCursor})
+		page.ETag = `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+	}
+	return page.ETag
+}
+
+// notModified answers a conditional GET whose If-None-Match matches etag with
+// 304 Not Modified and returns true. Otherwise it only sets the ETag of the
+// response.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	// Clients may keep the response but must revalidate it before reuse
+	c.Header("Cache-Control", "no-cache")
+
+	if !matchesIfNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// varyAccept marks a response whose representation was negotiated by its
+// Accept header, so that shared caches do not serve it to clients asking for
+// another one. It adds to the Vary values set before, such as Origin.
+func varyAccept(c *gin.Context) {
+	c.Writer.Header().Add("Vary", "Accept")
+}
+
+// matchesIfNoneMatch reports whether an If-None-Match header matches etag,
+// using the weak comparison the header calls for.
+func matchesIfNoneMatch(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch returns the annotation versions the If-Match header lists, or
+// none when it is absent or "*"; the write applies if the annotation has any
+// of them. Weak tags never match under the strong comparison If-Match calls
+// for, and neither do tags that are no version, so a header listing nothing
+// else cannot be satisfied.
+func parseIfMatch(header string) ([]int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		if version, ok := parseVersionTag(strings.TrimSpace(tag)); ok {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, errPreconditionFailed
+	}
+	return versions, nil
+}
+
+// parseVersionTag returns the annotation version a strong entity tag stands
+// for, as made by annotationETag.
+func parseVersionTag(tag string) (int64, bool) {
+	unquoted, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return 0, false
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// requireIfMatch reads the If-Match header of a conditional write, writing a
+// 412 response and returning false when it cannot be satisfied.
+func requireIfMatch(c *gin.Context) ([]int64, bool) {
+	versions, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		writePreconditionFailed(c)
+		return nil, false
+	}
+	return versions, true
+}
+
+// writePreconditionFailed answers a conditional write whose If-Match does not
+// match the annotation's current version.
+func writePreconditionFailed(c *gin.Context) {
+	preconditionFailed().write(c)
+}
+
+// preconditionFailed is the error of conditional writes to an annotation that
+// has moved on to another version.
+func preconditionFailed() *requestError {
+	return newRequestError(http.StatusPreconditionFailed, "precondition_failed", "the annotation has changed since it was read")
+}
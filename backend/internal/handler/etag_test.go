@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []int64
+		failed   bool
+	}{
+		{header: ""},
+		{header: "*"},
+		{header: `"7"`, expected: []int64{7}},
+		{header: ` "12" `, expected: []int64{12}},
+		{header: `W/"7"`, failed: true},
+		{header: `"0"`, failed: true},
+		{header: `"abc"`, failed: true},
+		{header: "7", failed: true},
+		{header: `"7", "8"`, expected: []int64{7, 8}},
+		{header: `W/"7","8"`, expected: []int64{8}},
+		{header: `W/"7", "abc"`, failed: true},
+	}
+
+	for _, tt := range tests {
+		versions, err := parseIfMatch(tt.header)
+		if tt.failed {
+			assert.ErrorIs(t, err, errPreconditionFailed, tt.header)
+			continue
+		}
+		assert.NoError(t, err, tt.header)
+		assert.Equal(t, tt.expected, versions, tt.header)
+	}
+}
+
+func TestGetByID_ETag(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	annotation := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Version: 4}
+
+	mockCache.On("Get", mock.Anything, testPointCloud.ID, "test-id").Return(nil, "3", nil)
+	mockRepo.On("GetByID", mock.Anything, testPointCloud.ID, "test-id").Return(annotation, nil)
+	mockCache.On("Set", mock.Anything, "3", annotation).Return(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/test-id", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
+
+func TestUpdate_IfMatch(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	updated := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Title: "Updated", Version: 5}
+
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64{4}).Return(updated, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(`{"title": "Updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdate_VersionMismatch(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64{4}).Return(nil, models.ErrVersionMismatch)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(`{"title": "Updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	mockCache.AssertNotCalled(t, "Set")
+}
+
+func TestUpdate_IfMatchList(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	updated := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Title: "Updated", Version: 5}
+
+	// The update applies if the annotation has any of the listed versions
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64{3, 4}).Return(updated, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(`{"title": "Updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"3", W/"2", "4"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdate_WeakIfMatch(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(`{"title": "Updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `W/"4"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	mockRepo.AssertNotCalled(t, "Update")
+}
+
+func TestUpdate_LabelCheckInTransaction(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	// The repository checks the version and the label in one transaction;
+	// the handler does not read the annotation first
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64{4}).Return(nil, models.ErrVersionMismatch)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(`{"attributes": {"occluded": true}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	mockRepo.AssertNotCalled(t, "GetByID")
+}
+
+func TestDelete_IfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"matching version", nil, http.StatusNoContent},
+		{"changed since read", models.ErrVersionMismatch, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, mockCache, engine := setupTestHandler()
+
+			mockRepo.On("Delete", mock.Anything, testPointCloud.ID, "test-id", []int64{3}).Return(tt.err)
+			mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/pointclouds/pc-1/annotations/test-id", nil)
+			req.Header.Set("If-Match", `"3"`)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	assert.False(t, matchesIfNoneMatch("", `"4"`))
+	assert.True(t, matchesIfNoneMatch("*", `"4"`))
+	assert.True(t, matchesIfNoneMatch(`"4"`, `"4"`))
+	assert.True(t, matchesIfNoneMatch(`W/"4"`, `"4"`), "If-None-Match compares weakly")
+	assert.True(t, matchesIfNoneMatch(`"3", "4"`, `"4"`))
+	assert.False(t, matchesIfNoneMatch(`"3"`, `"4"`))
+}
+
+func TestPageETag(t *testing.T) {
+	page := &models.AnnotationPage{Annotations: []models.Annotation{{ID: "1", Version: 1}}}
+	etag := pageETag(page)
+	assert.Equal(t, etag, page.ETag, "the ETag is kept on the page for caching")
+
+	changed := &models.AnnotationPage{Annotations: []models.Annotation{{ID: "1", Version: 2}}}
+	assert.NotEqual(t, etag, pageETag(changed))
+
+	// A cached ETag is served as is
+	cached := &models.AnnotationPage{ETag: `"cached"`}
+	assert.Equal(t, `"cached"`, pageETag(cached))
+}
+
+func TestGetByID_NotModified(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	annotation := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Version: 4}
+	mockCache.On("Get", mock.Anything, testPointCloud.ID, "test-id").Return(annotation, "3", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/test-id", nil)
+	req.Header.Set("If-None-Match", `"4"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	mockRepo.AssertNotCalled(t, "GetByID")
+}
+
+func TestGetAll_NotModified(t *testing.T) {
+	_, _, mockCache, engine := setupTestHandler()
+
+	cachedPage := &models.AnnotationPage{
+		Annotations: []models.Annotation{{ID: "1", X: 1, Y: 2, Z: 3, Title: "Test 1", Version: 1}},
+		ETag:        `"abc"`,
+	}
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(cachedPage, "3", true, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+}
+
+func TestGetAll_CachesETag(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	dbPage := &models.AnnotationPage{
+		Annotations: []models.Annotation{{ID: "1", X: 1, Y: 2, Z: 3, Title: "Test 1", Version: 1}},
+	}
+
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(nil, "3", false, nil)
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(dbPage, nil)
+	mockCache.On("SetAll", mock.Anything, testPointCloud.ID, "3", mock.Anything, mock.MatchedBy(func(page *models.AnnotationPage) bool {
+		return page.ETag != ""
+	})).Return(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, dbPage.ETag, w.Header().Get("ETag"))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	mockCache.AssertExpectations(t)
+}
+
+func TestUpdate_IfMatchListWithoutVersion(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	// None of the listed tags can be a version of the annotation
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(`{"title": "Updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `W/"7", "page"`)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	mockRepo.AssertNotCalled(t, "Update")
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// eventStreamContentType is the media type of Server-Sent Events.
+const eventStreamContentType = "text/event-stream"
+
+// eventKeepAlive is how often an idle event stream sends a comment, so that
+// proxies in between do not time the connection out.
+var eventKeepAlive = 15 * time.Second
+
+// StreamEvents handles streaming the annotation changes of a point cloud.
+// @Summary Stream annotation events
+// @Description Stream the creations, updates and deletions of a point cloud's annotations as Server-Sent Events, as they happen on any handler replica. Each event is named after its type and carries the event as JSON.
+// @Tags annotations
+// @Produce text/event-stream
+// @Param point_cloud_id query string true "Point cloud ID"
+// @Success 200 {object} models.AnnotationEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/annotations/events [get]
+func (h *Handler) StreamEvents(c *gin.Context) {
+	pointCloudID := c.Query("point_cloud_id")
+	if pointCloudID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "point_cloud_id is required",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	subscription := h.events.Subscribe(pointCloudID)
+	defer subscription.Close()
+
+	// Send the headers right away, so that the client knows it is
+	// subscribed before the first change
+	c.Header("Content-Type", eventStreamContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// The subscription fell behind or the handler is shutting
+				// down; the client reconnects
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.Error("Failed to marshal annotation event", zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// annotationEvent returns the event announcing a created or updated
+// annotation, attributed to the author of the write context.
+func annotationEvent(ctx context.Context, eventType string, annotation *models.Annotation) *models.AnnotationEvent {
+	return &models.AnnotationEvent{
+		Type:         eventType,
+		PointCloudID: annotation.PointCloudID,
+		AnnotationID: annotation.ID,
+		Annotation:   annotation,
+		Author:       database.AuthorFrom(ctx),
+		Time:         time.Now().UTC(),
+	}
+}
+
+// deletionEvent returns the event announcing a deleted annotation.
+func deletionEvent(ctx context.Context, pointCloudID, id string) *models.AnnotationEvent {
+	return &models.AnnotationEvent{
+		Type:         models.EventDeleted,
+		PointCloudID: pointCloudID,
+		AnnotationID: id,
+		Author:       database.AuthorFrom(ctx),
+		Time:         time.Now().UTC(),
+	}
+}
+
+// publish announces annotation changes to the clients watching their point
+// clouds. Failures are only logged: the changes are made, and watchers see
+// them on their next reload.
+func (h *Handler) publish(ctx context.Context, changes ...*models.AnnotationEvent) {
+	if err := h.events.Publish(ctx, changes...); err != nil {
+		h.logger.Warn("Failed to publish annotation events", zap.Int("count", len(changes)), zap.Error(err))
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// readEvent reads the next Server-Sent Event of a stream.
+func readEvent(t *testing.T, reader *bufio.Reader) (string, models.AnnotationEvent) {
+	var name string
+	var event models.AnnotationEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return name, event
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	created := &models.Annotation{ID: "a-1", PointCloudID: testPointCloud.ID, Title: "Car", Geometry: models.PointGeometry()}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.Anything).Return(created, nil)
+	mockRepo.On("Delete", mock.Anything, testPointCloud.ID, "a-1", []int64(nil)).Return(nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	resp, err := http.Get(server.URL + "/api/v1/annotations/events?point_cloud_id=pc-1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, eventStreamContentType, resp.Header.Get("Content-Type"))
+
+	// The stream is subscribed once its headers arrive
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(`{"x": 1, "y": 2, "z": 3, "title": "Car"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(authorHeader, "alice")
+	createResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	createResp.Body.Close()
+	assert.Equal(t, http.StatusCreated, createResp.StatusCode)
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/api/v1/pointclouds/pc-1/annotations/a-1", nil)
+	deleteResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	deleteResp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, deleteResp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+
+	name, event := readEvent(t, reader)
+	assert.Equal(t, models.EventCreated, name)
+	assert.Equal(t, models.EventCreated, event.Type)
+	assert.Equal(t, "a-1", event.AnnotationID)
+	assert.Equal(t, "alice", event.Author)
+	require.NotNil(t, event.Annotation)
+	assert.Equal(t, "Car", event.Annotation.Title)
+
+	name, event = readEvent(t, reader)
+	assert.Equal(t, models.EventDeleted, name)
+	assert.Equal(t, testPointCloud.ID, event.PointCloudID)
+	assert.Equal(t, "a-1", event.AnnotationID)
+	assert.Nil(t, event.Annotation)
+}
+
+func TestStreamEvents_MissingPointCloudID(t *testing.T) {
+	_, _, _, engine := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/events", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStreamEvents_PointCloudNotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, "missing").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/events?point_cloud_id=missing", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,8 +1,9 @@
-// Package handler provides the business logic handlers for annotation operations.
+// Package handler provides the business logic handlers for point cloud and annotation operations.
 package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/pointcloud-annotator/backend/internal/cache"
 	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/events"
 	"github.com/pointcloud-annotator/backend/internal/models"
 )
 
@@ -17,40 +19,159 @@ import (
 type Handler struct {
 	repo   database.Repository
 	cache  cache.Cache
+	events events.Bus
 	logger *zap.Logger
 }
 
 // NewHandler creates a new annotation handler.
-func NewHandler(repo database.Repository, cache cache.Cache, logger *zap.Logger) *Handler {
+func NewHandler(repo database.Repository, cache cache.Cache, bus events.Bus, logger *zap.Logger) *Handler {
 	return &Handler{
 		repo:   repo,
 		cache:  cache,
+		events: bus,
 		logger: logger,
 	}
 }
 
 // RegisterRoutes registers the handler routes on the given router group.
 func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
-	rg.POST("/annotations", h.Create)
-	rg.GET("/annotations", h.GetAll)
-	rg.GET("/annotations/:id", h.GetByID)
-	rg.PUT("/annotations/:id", h.Update)
-	rg.PATCH("/annotations/:id", h.Update)
-	rg.DELETE("/annotations/:id", h.Delete)
+	rg.POST("/pointclouds", h.CreatePointCloud)
+	rg.GET("/pointclouds", h.GetAllPointClouds)
+	rg.GET("/pointclouds/:id", h.GetPointCloud)
+	rg.PUT("/pointclouds/:id", h.UpdatePointCloud)
+	rg.PATCH("/pointclouds/:id", h.UpdatePointCloud)
+	rg.DELETE("/pointclouds/:id", h.DeletePointCloud)
+
+	rg.POST("/labels", h.CreateLabel)
+	rg.GET("/labels", h.GetAllLabels)
+	rg.GET("/labels/:id", h.GetLabel)
+	rg.PUT("/labels/:id", h.UpdateLabel)
+	rg.PATCH("/labels/:id", h.UpdateLabel)
+	rg.DELETE("/labels/:id", h.DeleteLabel)
+
+	rg.POST("/sequences", h.CreateSequence)
+	rg.GET("/sequences", h.GetAllSequences)
+	rg.GET("/sequences/:id", h.GetSequence)
+	rg.PUT("/sequences/:id", h.UpdateSequence)
+	rg.PATCH("/sequences/:id", h.UpdateSequence)
+	rg.DELETE("/sequences/:id", h.DeleteSequence)
+	rg.GET("/sequences/:id/frames", h.GetFrames)
+
+	rg.POST("/tracks", h.CreateTrack)
+	rg.GET("/tracks", h.GetAllTracks)
+	rg.GET("/tracks/:id", h.GetTrack)
+	rg.PUT("/tracks/:id", h.UpdateTrack)
+	rg.PATCH("/tracks/:id", h.UpdateTrack)
+	rg.DELETE("/tracks/:id", h.DeleteTrack)
+	rg.GET("/tracks/:id/annotations", h.GetTrackAnnotations)
+
+	rg.GET("/annotations/export", h.ExportAnnotations)
+	rg.POST("/annotations/import", h.ImportAnnotations)
+	rg.GET("/annotations/events", h.StreamEvents)
+	// Custom methods such as /annotations:batch; see annotationsMethod
+	rg.POST("/annotations:method", h.annotationsMethod)
+
+	rg.POST("/pointclouds/:id/annotations", h.Create)
+	rg.GET("/pointclouds/:id/annotations", h.GetAll)
+	rg.GET("/pointclouds/:id/annotations/:annotationId", h.GetByID)
+	rg.PUT("/pointclouds/:id/annotations/:annotationId", h.Update)
+	rg.PATCH("/pointclouds/:id/annotations/:annotationId", h.Update)
+	rg.DELETE("/pointclouds/:id/annotations/:annotationId", h.Delete)
+	rg.GET("/pointclouds/:id/annotations/:annotationId/history", h.GetHistory)
+	rg.POST("/pointclouds/:id/annotations/:annotationId/revert/:rev", h.Revert)
+	rg.POST("/pointclouds/:id/annotations/:annotationId/restore", h.Restore)
+	rg.GET("/pointclouds/:id/trash", h.GetTrash)
+
+	rg.GET("/pointclouds/:id/tags", h.GetTags)
+	rg.POST("/pointclouds/:id/annotations/:annotationId/tags", h.AddTags)
+	rg.DELETE("/pointclouds/:id/annotations/:annotationId/tags/:tag", h.RemoveTag)
+
+	rg.GET("/pointclouds/:id/segmentation", h.GetSegmentation)
+	rg.PUT("/pointclouds/:id/segmentation", h.PutSegmentation)
+	rg.PATCH("/pointclouds/:id/segmentation", h.PatchSegmentation)
+	rg.GET("/pointclouds/:id/segmentation/histogram", h.GetSegmentationHistogram)
+}
+
+// requestError is a failed check of a request together with the response
+// answering it.
+type requestError struct {
+	status int
+	body   models.ErrorResponse
+}
+
+func newRequestError(status int, code, message string) *requestError {
+	return &requestError{status: status, body: models.ErrorResponse{Error: code, Message: message}}
+}
+
+// Error returns the message, so that checks also fail calls made outside of
+// a request, such as the interchange CLI.
+func (e *requestError) Error() string {
+	return e.body.Message
+}
+
+// write answers the request with the error.
+func (e *requestError) write(c *gin.Context) {
+	c.JSON(e.status, e.body)
+}
+
+// annotationRefs reads the point clouds, labels and tracks annotation writes
+// refer to. The repository is one; batches read through a memo.
+type annotationRefs interface {
+	GetPointCloud(ctx context.Context, id string) (*models.PointCloud, error)
+	GetLabel(ctx context.Context, id string) (*models.Label, error)
+	GetTrack(ctx context.Context, id string) (*models.Track, error)
+}
+
+// cacheWritten invalidates the cached annotations and lists of a point cloud
+// after a write to one of its annotations. The written annotation is not
+// cached: entries are only filled by reads, under the generation they read
+// in, so a write racing a read or another write cannot leave it stale.
+func (h *Handler) cacheWritten(ctx context.Context, annotation *models.Annotation) {
+	_ = h.cache.InvalidateAll(ctx, annotation.PointCloudID)
+}
+
+// requirePointCloud checks that the point cloud exists, writing a 404 or 500
+// response and returning false when it does not.
+func (h *Handler) requirePointCloud(ctx context.Context, c *gin.Context, pointCloudID string) bool {
+	if failure := h.checkPointCloud(ctx, h.repo, pointCloudID); failure != nil {
+		failure.write(c)
+		return false
+	}
+	return true
+}
+
+// checkPointCloud checks that the point cloud exists.
+func (h *Handler) checkPointCloud(ctx context.Context, refs annotationRefs, pointCloudID string) *requestError {
+	pointCloud, err := refs.GetPointCloud(ctx, pointCloudID)
+	if err != nil {
+		h.logger.Error("Failed to get point cloud", zap.String("id", pointCloudID), zap.Error(err))
+		return newRequestError(http.StatusInternalServerError, "internal_error", "failed to retrieve point cloud")
+	}
+
+	if pointCloud == nil {
+		return newRequestError(http.StatusNotFound, "not_found", "point cloud not found")
+	}
+
+	return nil
 }
 
 // Create handles the creation of a new annotation.
 // @Summary Create annotation
-// @Description Create a new point cloud annotation
+// @Description Create a new annotation in a point cloud
 // @Tags annotations
 // @Accept json
 // @Produce json
+// @Param id path string true "Point cloud ID"
 // @Param annotation body models.CreateAnnotationRequest true "Annotation data"
 // @Success 201 {object} models.AnnotationResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /api/v1/annotations [post]
+// @Router /api/v1/pointclouds/{id}/annotations [post]
 func (h *Handler) Create(c *gin.Context) {
+	pointCloudID := c.Param("id")
+
 	var req models.CreateAnnotationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Invalid create request", zap.Error(err))
@@ -61,18 +182,18 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	// Validate title length (max 256 bytes)
-	if len(req.Title) > 256 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "title exceeds maximum length of 256 bytes",
-		})
+	ctx := writeContext(c)
+	if failure := h.checkCreate(ctx, h.repo, pointCloudID, &req); failure != nil {
+		failure.write(c)
 		return
 	}
 
-	ctx := context.Background()
-	annotation, err := h.repo.Create(ctx, &req)
+	annotation, err := h.repo.Create(ctx, pointCloudID, &req)
 	if err != nil {
+		if writeTrackError(c, err) {
+			return
+		}
+
 		h.logger.Error("Failed to create annotation", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "internal_error",
@@ -82,32 +203,129 @@ func (h *Handler) Create(c *gin.Context) {
 	}
 
 	// Cache the new annotation
-	_ = h.cache.Set(ctx, annotation)
+	ctx = committed(ctx)
+	h.cacheWritten(ctx, annotation)
+	h.publish(ctx, annotationEvent(ctx, models.EventCreated, annotation))
 
+	c.Header("ETag", annotationETag(annotation.Version))
 	c.JSON(http.StatusCreated, models.AnnotationResponse{Data: *annotation})
 }
 
-// GetAll handles retrieving all annotations.
+// checkCreate checks a bound create request: the title length, the geometry
+// and that the point cloud, the label and the track admit the annotation.
+func (h *Handler) checkCreate(ctx context.Context, refs annotationRefs, pointCloudID string, req *models.CreateAnnotationRequest) *requestError {
+	// Validate title length (max 256 bytes)
+	if len(req.Title) > 256 {
+		return newRequestError(http.StatusBadRequest, "invalid_request", "title exceeds maximum length of 256 bytes")
+	}
+
+	if err := req.Validate(); err != nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	if failure := h.checkPointCloud(ctx, refs, pointCloudID); failure != nil {
+		return failure
+	}
+
+	geometry := models.PointGeometry()
+	if req.Geometry != nil {
+		geometry = *req.Geometry
+	}
+	if failure := h.checkConformingLabel(ctx, refs, req.LabelID, geometry, req.Attributes); failure != nil {
+		return failure
+	}
+	return h.checkTrackInFrame(ctx, refs, req.TrackID, pointCloudID)
+}
+
+// GetAll handles retrieving a page of a point cloud's annotations.
 // @Summary Get all annotations
-// @Description Retrieve all point cloud annotations
+// @Description Retrieve a page of a point cloud's annotations, sorted and filtered, or with Accept: application/x-ndjson all matching annotations streamed one per line
 // @Tags annotations
 // @Produce json
+// @Produce x-ndjson
+// @Param id path string true "Point cloud ID"
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param sort query string false "Sort key: created_at, updated_at or title; prefix with - for descending (default -created_at)"
+// @Param title_prefix query string false "Only annotations whose title starts with this prefix"
+// @Param created_after query string false "Only annotations created at or after this RFC 3339 time"
+// @Param created_before query string false "Only annotations created before this RFC 3339 time"
+// @Param updated_after query string false "Only annotations updated at or after this RFC 3339 time"
+// @Param updated_before query string false "Only annotations updated before this RFC 3339 time"
+// @Param bbox query string false "Only annotations inside the box minx,miny,minz,maxx,maxy,maxz"
+// @Param near query string false "Center x,y,z of a radius or nearest-neighbor query"
+// @Param radius query number false "Only annotations within this distance of near"
+// @Param k query int false "Only the k annotations nearest to near"
+// @Param as_of query string false "Read the annotations as they were at this RFC 3339 time"
+// @Param If-None-Match header string false "ETag of the page the client holds"
 // @Success 200 {object} models.AnnotationsResponse
+// @Header 200 {string} ETag "Hash of the page"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /api/v1/annotations [get]
+// @Router /api/v1/pointclouds/{id}/annotations [get]
 func (h *Handler) GetAll(c *gin.Context) {
+	pointCloudID := c.Param("id")
+
+	query, err := parseAnnotationQuery(c)
+	if err != nil {
+		code := "invalid_request"
+		if errors.Is(err, models.ErrInvalidCursor) {
+			code = "invalid_cursor"
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   code,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	spatial, err := parseSpatialQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	ctx := context.Background()
 
-	// Try cache first
-	annotations, found, err := h.cache.GetAll(ctx)
-	if err == nil && found {
-		h.logger.Debug("Returning cached annotations")
-		c.JSON(http.StatusOK, models.AnnotationsResponse{Data: annotations})
+	if spatial != nil {
+		h.getWithinRegion(ctx, c, pointCloudID, spatial, query)
+		return
+	}
+
+	varyAccept(c)
+	if c.NegotiateFormat(gin.MIMEJSON, ndjsonContentType) == ndjsonContentType {
+		h.streamAll(c, pointCloudID, query)
 		return
 	}
 
+	// Try cache first; past states are read from the history instead. The
+	// generation is taken before the database read, so that a page read
+	// across a write is cached where no one looks.
+	var generation string
+	if query.AsOf == nil {
+		page, gen, found, err := h.cache.GetAll(ctx, pointCloudID, query)
+		generation = gen
+		if err == nil && found {
+			h.logger.Debug("Returning cached annotations", zap.String("point_cloud_id", pointCloudID))
+			if notModified(c, pageETag(page)) {
+				return
+			}
+			c.JSON(http.StatusOK, models.AnnotationsResponse{Data: page.Annotations, NextCursor: page.NextCursor})
+			return
+		}
+	}
+
 	// Cache miss, get from database
-	annotations, err = h.repo.GetAll(ctx)
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	page, err := h.repo.GetAll(ctx, pointCloudID, query)
 	if err != nil {
 		h.logger.Error("Failed to get annotations", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -117,36 +335,87 @@ func (h *Handler) GetAll(c *gin.Context) {
 		return
 	}
 
-	// Update cache
-	_ = h.cache.SetAll(ctx, annotations)
+	// Update cache, along with the ETag of the page
+	if query.AsOf == nil {
+		etag := pageETag(page)
+		_ = h.cache.SetAll(ctx, pointCloudID, generation, query, page)
+		if notModified(c, etag) {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.AnnotationsResponse{Data: page.Annotations, NextCursor: page.NextCursor})
+}
+
+// getWithinRegion answers a spatial annotation query. Region queries follow
+// the camera and are too varied to cache, so they always hit the database.
+func (h *Handler) getWithinRegion(ctx context.Context, c *gin.Context, pointCloudID string, spatial *models.SpatialQuery, query *models.AnnotationQuery) {
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	var annotations []models.Annotation
+	var err error
+	switch {
+	case spatial.BBox != nil:
+		annotations, err = h.repo.GetWithinBox(ctx, pointCloudID, *spatial.BBox, query)
+	case spatial.Radius != nil:
+		annotations, err = h.repo.GetWithinRadius(ctx, pointCloudID, *spatial.Near, *spatial.Radius, query)
+	default:
+		annotations, err = h.repo.GetNearest(ctx, pointCloudID, *spatial.Near, spatial.K, query)
+	}
+
+	if err != nil {
+		h.logger.Error("Failed to get annotations within region", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve annotations",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.AnnotationsResponse{Data: annotations})
 }
 
 // GetByID handles retrieving a single annotation by ID.
 // @Summary Get annotation by ID
-// @Description Retrieve a specific annotation by its ID
+// @Description Retrieve a specific annotation of a point cloud by its ID
 // @Tags annotations
 // @Produce json
-// @Param id path string true "Annotation ID"
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param as_of query string false "Read the annotation as it was at this RFC 3339 time"
+// @Param If-None-Match header string false "ETag of the copy the client holds"
 // @Success 200 {object} models.AnnotationResponse
+// @Header 200 {string} ETag "Version of the annotation, for If-Match"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /api/v1/annotations/{id} [get]
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId} [get]
 func (h *Handler) GetByID(c *gin.Context) {
-	id := c.Param("id")
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
 	ctx := context.Background()
 
+	if c.Query("as_of") != "" {
+		h.getByIDAsOf(ctx, c, pointCloudID, id)
+		return
+	}
+
 	// Try cache first
-	annotation, err := h.cache.Get(ctx, id)
+	annotation, generation, err := h.cache.Get(ctx, pointCloudID, id)
 	if err == nil && annotation != nil {
 		h.logger.Debug("Returning cached annotation", zap.String("id", id))
+		if notModified(c, annotationETag(annotation.Version)) {
+			return
+		}
 		c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
 		return
 	}
 
 	// Cache miss, get from database
-	annotation, err = h.repo.GetByID(ctx, id)
+	annotation, err = h.repo.GetByID(ctx, pointCloudID, id)
 	if err != nil {
 		h.logger.Error("Failed to get annotation", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -165,26 +434,34 @@ func (h *Handler) GetByID(c *gin.Context) {
 	}
 
 	// Update cache
-	_ = h.cache.Set(ctx, annotation)
+	_ = h.cache.Set(ctx, generation, annotation)
 
+	if notModified(c, annotationETag(annotation.Version)) {
+		return
+	}
 	c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
 }
 
 // Update handles updating an existing annotation.
 // @Summary Update annotation
-// @Description Update an existing annotation
+// @Description Update an existing annotation, optionally only if it still has the version given by If-Match
 // @Tags annotations
 // @Accept json
 // @Produce json
-// @Param id path string true "Annotation ID"
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param If-Match header string false "ETag of the version the update applies to"
 // @Param annotation body models.UpdateAnnotationRequest true "Updated annotation data"
 // @Success 200 {object} models.AnnotationResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /api/v1/annotations/{id} [put]
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId} [put]
 func (h *Handler) Update(c *gin.Context) {
-	id := c.Param("id")
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
 
 	var req models.UpdateAnnotationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -196,18 +473,27 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
-	// Validate title length if provided
-	if req.Title != nil && len(*req.Title) > 256 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "title exceeds maximum length of 256 bytes",
-		})
+	versions, ok := requireIfMatch(c)
+	if !ok {
 		return
 	}
 
-	ctx := context.Background()
-	annotation, err := h.repo.Update(ctx, id, &req)
+	ctx := writeContext(c)
+	if failure := h.checkUpdate(ctx, h.repo, pointCloudID, &req); failure != nil {
+		failure.write(c)
+		return
+	}
+
+	annotation, err := h.repo.Update(ctx, pointCloudID, id, &req, versions)
 	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writePreconditionFailed(c)
+			return
+		}
+		if writeTrackError(c, err) {
+			return
+		}
+
 		h.logger.Error("Failed to update annotation", zap.String("id", id), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "internal_error",
@@ -225,28 +511,63 @@ func (h *Handler) Update(c *gin.Context) {
 	}
 
 	// Update cache
-	_ = h.cache.Set(ctx, annotation)
+	ctx = committed(ctx)
+	h.cacheWritten(ctx, annotation)
+	h.publish(ctx, annotationEvent(ctx, models.EventUpdated, annotation))
 
+	c.Header("ETag", annotationETag(annotation.Version))
 	c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
 }
 
+// checkUpdate checks a bound update request: the title length, the geometry
+// and that the track admits the annotation. Whether the annotation keeps
+// conforming to its label is checked by the repository, in the transaction
+// of the update.
+func (h *Handler) checkUpdate(ctx context.Context, refs annotationRefs, pointCloudID string, req *models.UpdateAnnotationRequest) *requestError {
+	// Validate title length if provided
+	if req.Title != nil && len(*req.Title) > 256 {
+		return newRequestError(http.StatusBadRequest, "invalid_request", "title exceeds maximum length of 256 bytes")
+	}
+
+	if err := req.Validate(); err != nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	return h.checkTrackInFrame(ctx, refs, req.TrackID, pointCloudID)
+}
+
 // Delete handles deleting an annotation.
 // @Summary Delete annotation
-// @Description Delete an annotation by ID
+// @Description Move an annotation to the trash, optionally only if it still has the version given by If-Match
 // @Tags annotations
 // @Produce json
-// @Param id path string true "Annotation ID"
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param If-Match header string false "ETag of the version to delete"
 // @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /api/v1/annotations/{id} [delete]
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId} [delete]
 func (h *Handler) Delete(c *gin.Context) {
-	id := c.Param("id")
-	ctx := context.Background()
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
+
+	versions, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	ctx := writeContext(c)
 
-	err := h.repo.Delete(ctx, id)
+	err := h.repo.Delete(ctx, pointCloudID, id, versions)
 	if err != nil {
-		if err.Error() == "annotation not found" {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			writePreconditionFailed(c)
+			return
+		}
+		if errors.Is(err, database.ErrAnnotationNotFound) {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error:   "not_found",
 				Message: "annotation not found",
@@ -263,7 +584,9 @@ func (h *Handler) Delete(c *gin.Context) {
 	}
 
 	// Remove from cache
-	_ = h.cache.Delete(ctx, id)
+	ctx = committed(ctx)
+	_ = h.cache.InvalidateAll(ctx, pointCloudID)
+	h.publish(ctx, deletionEvent(ctx, pointCloudID, id))
 
 	c.Status(http.StatusNoContent)
 }
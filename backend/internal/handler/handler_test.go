@@ -14,6 +14,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/events"
 	"github.com/pointcloud-annotator/backend/internal/models"
 )
 
@@ -22,43 +24,351 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) Create(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+func (m *MockRepository) CreatePointCloud(ctx context.Context, req *models.CreatePointCloudRequest) (*models.PointCloud, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.Annotation), args.Error(1)
+	return args.Get(0).(*models.PointCloud), args.Error(1)
 }
 
-func (m *MockRepository) GetByID(ctx context.Context, id string) (*models.Annotation, error) {
+func (m *MockRepository) GetPointCloud(ctx context.Context, id string) (*models.PointCloud, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.Annotation), args.Error(1)
+	return args.Get(0).(*models.PointCloud), args.Error(1)
 }
 
-func (m *MockRepository) GetAll(ctx context.Context) ([]models.Annotation, error) {
+func (m *MockRepository) GetAllPointClouds(ctx context.Context) ([]models.PointCloud, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
+	return args.Get(0).([]models.PointCloud), args.Error(1)
+}
+
+func (m *MockRepository) UpdatePointCloud(ctx context.Context, id string, req *models.UpdatePointCloudRequest) (*models.PointCloud, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PointCloud), args.Error(1)
+}
+
+func (m *MockRepository) DeletePointCloud(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Create(ctx context.Context, pointCloudID string, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) GetByID(ctx context.Context, pointCloudID, id string) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) GetAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery) (*models.AnnotationPage, error) {
+	args := m.Called(ctx, pointCloudID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AnnotationPage), args.Error(1)
+}
+
+func (m *MockRepository) StreamAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery, fn func(*models.Annotation) error) error {
+	args := m.Called(ctx, pointCloudID, query)
+	annotations, _ := args.Get(0).([]models.Annotation)
+	for i := range annotations {
+		if err := fn(&annotations[i]); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockRepository) GetAttributeColumns(ctx context.Context, pointCloudID string) ([]models.AttributeColumn, error) {
+	args := m.Called(ctx, pointCloudID)
+	columns, _ := args.Get(0).([]models.AttributeColumn)
+	return columns, args.Error(1)
+}
+
+func (m *MockRepository) Update(ctx context.Context, pointCloudID, id string, req *models.UpdateAnnotationRequest, versions []int64) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id, req, versions)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, pointCloudID, id string, versions []int64) error {
+	args := m.Called(ctx, pointCloudID, id, versions)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetWithinBox(ctx context.Context, pointCloudID string, box models.BoundingBox, query *models.AnnotationQuery) ([]models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, box, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).([]models.Annotation), args.Error(1)
 }
 
-func (m *MockRepository) Update(ctx context.Context, id string, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+func (m *MockRepository) GetWithinRadius(ctx context.Context, pointCloudID string, center models.Vec3, radius float64, query *models.AnnotationQuery) ([]models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, center, radius, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) GetNearest(ctx context.Context, pointCloudID string, center models.Vec3, k int, query *models.AnnotationQuery) ([]models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, center, k, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) CreateLabel(ctx context.Context, req *models.CreateLabelRequest) (*models.Label, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Label), args.Error(1)
+}
+
+func (m *MockRepository) GetLabel(ctx context.Context, id string) (*models.Label, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Label), args.Error(1)
+}
+
+func (m *MockRepository) GetAllLabels(ctx context.Context) ([]models.Label, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Label), args.Error(1)
+}
+
+func (m *MockRepository) UpdateLabel(ctx context.Context, id string, req *models.UpdateLabelRequest) (*models.Label, error) {
 	args := m.Called(ctx, id, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
+	return args.Get(0).(*models.Label), args.Error(1)
+}
+
+func (m *MockRepository) DeleteLabel(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddTags(ctx context.Context, pointCloudID, id string, tags []string) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) RemoveTag(ctx context.Context, pointCloudID, id, tag string) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id, tag)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(*models.Annotation), args.Error(1)
 }
 
-func (m *MockRepository) Delete(ctx context.Context, id string) error {
+func (m *MockRepository) GetTagCounts(ctx context.Context, pointCloudID string) ([]models.TagCount, error) {
+	args := m.Called(ctx, pointCloudID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TagCount), args.Error(1)
+}
+
+func (m *MockRepository) GetSegmentation(ctx context.Context, pointCloudID string) (*models.Segmentation, error) {
+	args := m.Called(ctx, pointCloudID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Segmentation), args.Error(1)
+}
+
+func (m *MockRepository) PutSegmentation(ctx context.Context, segmentation *models.Segmentation) error {
+	args := m.Called(ctx, segmentation)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PatchSegmentation(ctx context.Context, pointCloudID string, patch *models.SegmentationPatch) (*models.Segmentation, error) {
+	args := m.Called(ctx, pointCloudID, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Segmentation), args.Error(1)
+}
+
+func (m *MockRepository) CreateSequence(ctx context.Context, req *models.CreateSequenceRequest) (*models.Sequence, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Sequence), args.Error(1)
+}
+
+func (m *MockRepository) GetSequence(ctx context.Context, id string) (*models.Sequence, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Sequence), args.Error(1)
+}
+
+func (m *MockRepository) GetAllSequences(ctx context.Context) ([]models.Sequence, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Sequence), args.Error(1)
+}
+
+func (m *MockRepository) UpdateSequence(ctx context.Context, id string, req *models.UpdateSequenceRequest) (*models.Sequence, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Sequence), args.Error(1)
+}
+
+func (m *MockRepository) DeleteSequence(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetFrames(ctx context.Context, sequenceID string) ([]models.PointCloud, error) {
+	args := m.Called(ctx, sequenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PointCloud), args.Error(1)
+}
+
+func (m *MockRepository) CreateTrack(ctx context.Context, req *models.CreateTrackRequest) (*models.Track, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Track), args.Error(1)
+}
+
+func (m *MockRepository) GetTrack(ctx context.Context, id string) (*models.Track, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Track), args.Error(1)
+}
+
+func (m *MockRepository) GetAllTracks(ctx context.Context, sequenceID string) ([]models.Track, error) {
+	args := m.Called(ctx, sequenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Track), args.Error(1)
+}
+
+func (m *MockRepository) UpdateTrack(ctx context.Context, id string, req *models.UpdateTrackRequest) (*models.Track, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Track), args.Error(1)
+}
+
+func (m *MockRepository) DeleteTrack(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetTrackAnnotations(ctx context.Context, id string) ([]models.TrackObservation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TrackObservation), args.Error(1)
+}
+
+func (m *MockRepository) GetHistory(ctx context.Context, pointCloudID, id string) ([]models.AnnotationRevision, error) {
+	args := m.Called(ctx, pointCloudID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AnnotationRevision), args.Error(1)
+}
+
+func (m *MockRepository) Revert(ctx context.Context, pointCloudID, id string, revision int) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) GetByIDAsOf(ctx context.Context, pointCloudID, id string, asOf time.Time) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id, asOf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) GetTrash(ctx context.Context, pointCloudID string, limit int) ([]models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) Restore(ctx context.Context, pointCloudID, id string) (*models.Annotation, error) {
+	args := m.Called(ctx, pointCloudID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *MockRepository) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]database.BatchItem, error) {
+	args := m.Called(ctx, ops, atomic)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]database.BatchItem), args.Error(1)
+}
+
+func (m *MockRepository) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Close() {
 	m.Called()
 }
@@ -68,39 +378,52 @@ type MockCache struct {
 	mock.Mock
 }
 
-func (m *MockCache) Get(ctx context.Context, id string) (*models.Annotation, error) {
-	args := m.Called(ctx, id)
+func (m *MockCache) Get(ctx context.Context, pointCloudID, id string) (*models.Annotation, string, error) {
+	args := m.Called(ctx, pointCloudID, id)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, args.String(1), args.Error(2)
 	}
-	return args.Get(0).(*models.Annotation), args.Error(1)
+	return args.Get(0).(*models.Annotation), args.String(1), args.Error(2)
 }
 
-func (m *MockCache) GetAll(ctx context.Context) ([]models.Annotation, bool, error) {
-	args := m.Called(ctx)
+func (m *MockCache) GetAll(ctx context.Context, pointCloudID string, query *models.AnnotationQuery) (*models.AnnotationPage, string, bool, error) {
+	args := m.Called(ctx, pointCloudID, query)
 	if args.Get(0) == nil {
-		return nil, args.Bool(1), args.Error(2)
+		return nil, args.String(1), args.Bool(2), args.Error(3)
 	}
-	return args.Get(0).([]models.Annotation), args.Bool(1), args.Error(2)
+	return args.Get(0).(*models.AnnotationPage), args.String(1), args.Bool(2), args.Error(3)
 }
 
-func (m *MockCache) Set(ctx context.Context, annotation *models.Annotation) error {
-	args := m.Called(ctx, annotation)
+func (m *MockCache) Set(ctx context.Context, generation string, annotation *models.Annotation) error {
+	args := m.Called(ctx, generation, annotation)
 	return args.Error(0)
 }
 
-func (m *MockCache) SetAll(ctx context.Context, annotations []models.Annotation) error {
-	args := m.Called(ctx, annotations)
+func (m *MockCache) SetAll(ctx context.Context, pointCloudID, generation string, query *models.AnnotationQuery, page *models.AnnotationPage) error {
+	args := m.Called(ctx, pointCloudID, generation, query, page)
 	return args.Error(0)
 }
 
-func (m *MockCache) Delete(ctx context.Context, id string) error {
-	args := m.Called(ctx, id)
+func (m *MockCache) GetTagCounts(ctx context.Context, pointCloudID string) ([]models.TagCount, string, bool, error) {
+	args := m.Called(ctx, pointCloudID)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Bool(2), args.Error(3)
+	}
+	return args.Get(0).([]models.TagCount), args.String(1), args.Bool(2), args.Error(3)
+}
+
+func (m *MockCache) SetTagCounts(ctx context.Context, pointCloudID, generation string, counts []models.TagCount) error {
+	args := m.Called(ctx, pointCloudID, generation, counts)
 	return args.Error(0)
 }
 
-func (m *MockCache) InvalidateAll(ctx context.Context) error {
-	args := m.Called(ctx)
+func (m *MockCache) InvalidateAll(ctx context.Context, pointCloudID string) error {
+	args := m.Called(ctx, pointCloudID)
+	return args.Error(0)
+}
+
+func (m *MockCache) InvalidatePointCloud(ctx context.Context, pointCloudID string) error {
+	args := m.Called(ctx, pointCloudID)
 	return args.Error(0)
 }
 
@@ -116,7 +439,7 @@ func setupTestHandler() (*Handler, *MockRepository, *MockCache, *gin.Engine) {
 	mockCache := new(MockCache)
 	logger, _ := zap.NewDevelopment()
 
-	handler := NewHandler(mockRepo, mockCache, logger)
+	handler := NewHandler(mockRepo, mockCache, events.NewLocalBus(logger), logger)
 
 	engine := gin.New()
 	rg := engine.Group("/api/v1")
@@ -125,27 +448,36 @@ func setupTestHandler() (*Handler, *MockRepository, *MockCache, *gin.Engine) {
 	return handler, mockRepo, mockCache, engine
 }
 
+// testPointCloud is the scene all annotation tests operate on.
+var testPointCloud = &models.PointCloud{
+	ID:        "pc-1",
+	Name:      "lion_takanawa",
+	SourceURL: "/potree/pointclouds/lion_takanawa/cloud.js",
+}
+
 func TestCreate_Success(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
 	expectedAnnotation := &models.Annotation{
-		ID:          "test-uuid",
-		X:           1.0,
-		Y:           2.0,
-		Z:           3.0,
-		Title:       "Test Annotation",
-		Description: "Test Description",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:           "test-uuid",
+		PointCloudID: testPointCloud.ID,
+		X:            1.0,
+		Y:            2.0,
+		Z:            3.0,
+		Title:        "Test Annotation",
+		Description:  "Test Description",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
-	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(req *models.CreateAnnotationRequest) bool {
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.MatchedBy(func(req *models.CreateAnnotationRequest) bool {
 		return req.X == 1.0 && req.Y == 2.0 && req.Z == 3.0 && req.Title == "Test Annotation"
 	})).Return(expectedAnnotation, nil)
-	mockCache.On("Set", mock.Anything, expectedAnnotation).Return(nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
 
 	body := `{"x": 1.0, "y": 2.0, "z": 3.0, "title": "Test Annotation", "description": "Test Description"}`
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations", bytes.NewBufferString(body))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
@@ -168,7 +500,7 @@ func TestCreate_InvalidRequest(t *testing.T) {
 
 	// Missing required fields
 	body := `{"x": 1.0}`
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations", bytes.NewBufferString(body))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
@@ -187,7 +519,7 @@ func TestCreate_TitleTooLong(t *testing.T) {
 	}
 
 	body := `{"x": 1.0, "y": 2.0, "z": 3.0, "title": "` + string(longTitle) + `"}`
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations", bytes.NewBufferString(body))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
@@ -196,17 +528,137 @@ func TestCreate_TitleTooLong(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestCreate_Cuboid(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	expectedAnnotation := &models.Annotation{
+		ID:           "test-uuid",
+		PointCloudID: testPointCloud.ID,
+		Title:        "Car",
+		Geometry: models.Geometry{
+			Type:   models.GeometryCuboid,
+			Center: &models.Vec3{X: 1, Y: 2, Z: 3},
+			Size:   &models.Dimensions{Length: 4.5, Width: 1.8, Height: 1.5},
+		},
+	}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.MatchedBy(func(req *models.CreateAnnotationRequest) bool {
+		return req.Geometry != nil && req.Geometry.Type == models.GeometryCuboid && *req.Geometry.Yaw == 0.5
+	})).Return(expectedAnnotation, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	body := `{"x": 1.0, "y": 2.0, "z": 3.0, "title": "Car", "geometry": {
+		"type": "cuboid",
+		"center": {"x": 1, "y": 2, "z": 3},
+		"size": {"length": 4.5, "width": 1.8, "height": 1.5},
+		"yaw": 0.5
+	}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.AnnotationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.GeometryCuboid, response.Data.Geometry.Type)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestCreate_Polygon(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	expectedAnnotation := &models.Annotation{ID: "test-uuid", PointCloudID: testPointCloud.ID, Title: "Building"}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.MatchedBy(func(req *models.CreateAnnotationRequest) bool {
+		return req.Geometry != nil && req.Geometry.Type == models.GeometryPolygon && len(req.Geometry.Vertices) == 4
+	})).Return(expectedAnnotation, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	body := `{"x": 5, "y": 4, "z": 0.5, "title": "Building", "geometry": {
+		"type": "polygon",
+		"vertices": [{"x": 0, "y": 0, "z": 0}, {"x": 10, "y": 0, "z": 0}, {"x": 10, "y": 8, "z": 0}, {"x": 0, "y": 0, "z": 0}],
+		"height": 12
+	}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestCreate_InvalidGeometry(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	bodies := []string{
+		// Negative size
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "geometry": {"type": "cuboid", "center": {"x": 1, "y": 2, "z": 3}, "size": {"length": -1, "width": 1, "height": 1}, "yaw": 0}}`,
+		// Not a unit quaternion
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "geometry": {"type": "cuboid", "center": {"x": 1, "y": 2, "z": 3}, "size": {"length": 1, "width": 1, "height": 1}, "rotation": {"w": 2, "x": 0, "y": 0, "z": 0}}}`,
+		// Unknown type
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "geometry": {"type": "sphere"}}`,
+		// Open polygon ring
+		`{"x": 1, "y": 2, "z": 3, "title": "Lot", "geometry": {"type": "polygon", "vertices": [{"x": 0, "y": 0, "z": 0}, {"x": 1, "y": 0, "z": 0}, {"x": 1, "y": 1, "z": 0}]}}`,
+		// Cuboid centered elsewhere than the annotation
+		`{"x": 9, "y": 2, "z": 3, "title": "Car", "geometry": {"type": "cuboid", "center": {"x": 1, "y": 2, "z": 3}, "size": {"length": 1, "width": 1, "height": 1}, "yaw": 0}}`,
+	}
+
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		engine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	mockRepo.AssertNotCalled(t, "Create")
+}
+
+func TestCreate_PointCloudNotFound(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, "missing").Return(nil, nil)
+
+	body := `{"x": 1.0, "y": 2.0, "z": 3.0, "title": "Test Annotation"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/missing/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockRepo.AssertNotCalled(t, "Create")
+	mockCache.AssertNotCalled(t, "Set")
+}
+
 func TestGetAll_FromCache(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
-	cachedAnnotations := []models.Annotation{
-		{ID: "1", X: 1.0, Y: 2.0, Z: 3.0, Title: "Test 1"},
-		{ID: "2", X: 4.0, Y: 5.0, Z: 6.0, Title: "Test 2"},
+	cachedPage := &models.AnnotationPage{
+		Annotations: []models.Annotation{
+			{ID: "1", X: 1.0, Y: 2.0, Z: 3.0, Title: "Test 1"},
+			{ID: "2", X: 4.0, Y: 5.0, Z: 6.0, Title: "Test 2"},
+		},
 	}
 
-	mockCache.On("GetAll", mock.Anything).Return(cachedAnnotations, true, nil)
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(cachedPage, "3", true, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations", nil)
 	w := httptest.NewRecorder()
 
 	engine.ServeHTTP(w, req)
@@ -217,6 +669,7 @@ func TestGetAll_FromCache(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Len(t, response.Data, 2)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"), "the page shares its URL with the NDJSON stream")
 
 	mockRepo.AssertNotCalled(t, "GetAll")
 	mockCache.AssertExpectations(t)
@@ -225,15 +678,56 @@ func TestGetAll_FromCache(t *testing.T) {
 func TestGetAll_CacheMiss(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
-	dbAnnotations := []models.Annotation{
-		{ID: "1", X: 1.0, Y: 2.0, Z: 3.0, Title: "Test 1"},
+	dbPage := &models.AnnotationPage{
+		Annotations: []models.Annotation{
+			{ID: "1", X: 1.0, Y: 2.0, Z: 3.0, Title: "Test 1"},
+		},
+	}
+
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(nil, "3", false, nil)
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(dbPage, nil)
+	mockCache.On("SetAll", mock.Anything, testPointCloud.ID, "3", mock.Anything, dbPage).Return(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AnnotationsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 1)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestGetAll_Pagination(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	cursor := &models.AnnotationCursor{Sort: "title", Value: "Test 1", ID: "8f14e45f-ceea-4e67-a3c1-4e7d5d5c6b1a"}
+	dbPage := &models.AnnotationPage{
+		Annotations: []models.Annotation{{ID: "2", Title: "Test 2"}},
+		NextCursor:  "next",
 	}
 
-	mockCache.On("GetAll", mock.Anything).Return(nil, false, nil)
-	mockRepo.On("GetAll", mock.Anything).Return(dbAnnotations, nil)
-	mockCache.On("SetAll", mock.Anything, dbAnnotations).Return(nil)
+	matchQuery := mock.MatchedBy(func(q *models.AnnotationQuery) bool {
+		return q.Limit == 1 && q.SortBy == models.SortByTitle && !q.Descending &&
+			q.After != nil && q.After.ID == "8f14e45f-ceea-4e67-a3c1-4e7d5d5c6b1a" && q.TitlePrefix == "Test" &&
+			q.CreatedAfter != nil && q.CreatedAfter.Year() == 2024
+	})
+
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, matchQuery).Return(nil, "3", false, nil)
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAll", mock.Anything, testPointCloud.ID, matchQuery).Return(dbPage, nil)
+	mockCache.On("SetAll", mock.Anything, testPointCloud.ID, "3", matchQuery, dbPage).Return(nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations", nil)
+	url := "/api/v1/pointclouds/pc-1/annotations?limit=1&sort=title&title_prefix=Test" +
+		"&created_after=2024-01-01T00:00:00Z&cursor=" + cursor.Encode()
+	req := httptest.NewRequest(http.MethodGet, url, nil)
 	w := httptest.NewRecorder()
 
 	engine.ServeHTTP(w, req)
@@ -244,11 +738,195 @@ func TestGetAll_CacheMiss(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Len(t, response.Data, 1)
+	assert.Equal(t, "next", response.NextCursor)
 
 	mockRepo.AssertExpectations(t)
 	mockCache.AssertExpectations(t)
 }
 
+func TestGetAll_AttributeFilters(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	page := &models.AnnotationPage{Annotations: []models.Annotation{}}
+	matchQuery := mock.MatchedBy(func(q *models.AnnotationQuery) bool {
+		return len(q.Attributes) == 2 &&
+			q.Attributes[0] == models.AttributeFilter{Name: "occluded", Operator: "=", Value: true} &&
+			q.Attributes[1] == models.AttributeFilter{Name: "confidence", Operator: ">=", Value: 0.8}
+	})
+
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, matchQuery).Return(page, "3", true, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?attr.occluded=true&attr.confidence%3E=0.8", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetAll")
+}
+
+func TestGetAll_InvalidQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"non-numeric limit", "limit=abc"},
+		{"limit too large", "limit=100000"},
+		{"unknown sort key", "sort=x"},
+		{"malformed timestamp", "updated_before=yesterday"},
+		{"non-numeric attribute bound", "attr.confidence%3E=high"},
+		{"malformed attribute filter", "attr.Occluded=true"},
+		{"unknown tag mode", "tags=a&tag_mode=some"},
+		{"tag mode without tags", "tag_mode=all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, mockCache, engine := setupTestHandler()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockCache.AssertNotCalled(t, "GetAll")
+			mockRepo.AssertNotCalled(t, "GetAll")
+		})
+	}
+}
+
+func TestGetAll_InvalidCursor(t *testing.T) {
+	id := "8f14e45f-ceea-4e67-a3c1-4e7d5d5c6b1a"
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"malformed cursor", "cursor=!!!"},
+		{"cursor for another sort", "sort=title&cursor=" + (&models.AnnotationCursor{Sort: "-created_at", Value: "2024-01-01T00:00:00Z", ID: id}).Encode()},
+		{"cursor with a non-UUID ID", "cursor=" + (&models.AnnotationCursor{Sort: "-created_at", Value: "2024-01-01T00:00:00Z", ID: "1' OR 1=1"}).Encode()},
+		{"cursor with a non-timestamp value", "cursor=" + (&models.AnnotationCursor{Sort: "-created_at", Value: "Car", ID: id}).Encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, mockCache, engine := setupTestHandler()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response models.ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "invalid_cursor", response.Error)
+			mockCache.AssertNotCalled(t, "GetAll")
+			mockRepo.AssertNotCalled(t, "GetAll")
+		})
+	}
+}
+
+func TestGetAll_WithinBox(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	box := models.BoundingBox{Min: models.Vec3{X: -1, Y: -1, Z: -1}, Max: models.Vec3{X: 1, Y: 1, Z: 1}}
+	found := []models.Annotation{{ID: "1", Title: "Inside"}}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetWithinBox", mock.Anything, testPointCloud.ID, box, mock.Anything).Return(found, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?bbox=-1,-1,-1,1,1,1", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AnnotationsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 1)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "GetAll")
+}
+
+func TestGetAll_WithinRadius(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	center := models.Vec3{X: 1, Y: 2, Z: 3}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetWithinRadius", mock.Anything, testPointCloud.ID, center, 2.5, mock.MatchedBy(func(q *models.AnnotationQuery) bool {
+		return q.Limit == 10
+	})).Return([]models.Annotation{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?near=1,2,3&radius=2.5&limit=10", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAll_Nearest(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	center := models.Vec3{X: 1, Y: 2, Z: 3}
+	nearest := []models.Annotation{{ID: "1"}, {ID: "2"}}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetNearest", mock.Anything, testPointCloud.ID, center, 2, mock.Anything).Return(nearest, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?near=1,2,3&k=2", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AnnotationsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAll_InvalidSpatialQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"bbox with too few numbers", "bbox=0,0,0,1,1"},
+		{"inverted bbox", "bbox=1,1,1,0,0,0"},
+		{"near without radius or k", "near=0,0,0"},
+		{"near with radius and k", "near=0,0,0&radius=1&k=3"},
+		{"radius without near", "radius=1"},
+		{"bbox with near", "bbox=0,0,0,1,1,1&near=0,0,0&k=1"},
+		{"negative radius", "near=0,0,0&radius=-1"},
+		{"k too large", "near=0,0,0&k=100000"},
+		{"spatial with cursor", "near=0,0,0&k=1&sort=title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, _, engine := setupTestHandler()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockRepo.AssertNotCalled(t, "GetPointCloud")
+		})
+	}
+}
+
 func TestGetByID_FromCache(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
@@ -260,9 +938,9 @@ func TestGetByID_FromCache(t *testing.T) {
 		Title: "Cached Annotation",
 	}
 
-	mockCache.On("Get", mock.Anything, "test-id").Return(cachedAnnotation, nil)
+	mockCache.On("Get", mock.Anything, testPointCloud.ID, "test-id").Return(cachedAnnotation, "3", nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/test-id", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/test-id", nil)
 	w := httptest.NewRecorder()
 
 	engine.ServeHTTP(w, req)
@@ -281,10 +959,10 @@ func TestGetByID_FromCache(t *testing.T) {
 func TestGetByID_NotFound(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
-	mockCache.On("Get", mock.Anything, "nonexistent").Return(nil, nil)
-	mockRepo.On("GetByID", mock.Anything, "nonexistent").Return(nil, nil)
+	mockCache.On("Get", mock.Anything, testPointCloud.ID, "nonexistent").Return(nil, "3", nil)
+	mockRepo.On("GetByID", mock.Anything, testPointCloud.ID, "nonexistent").Return(nil, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/nonexistent", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/nonexistent", nil)
 	w := httptest.NewRecorder()
 
 	engine.ServeHTTP(w, req)
@@ -299,20 +977,21 @@ func TestUpdate_Success(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
 	updatedAnnotation := &models.Annotation{
-		ID:          "test-id",
-		X:           10.0,
-		Y:           20.0,
-		Z:           30.0,
-		Title:       "Updated Title",
-		Description: "Updated Description",
-		UpdatedAt:   time.Now(),
+		ID:           "test-id",
+		PointCloudID: testPointCloud.ID,
+		X:            10.0,
+		Y:            20.0,
+		Z:            30.0,
+		Title:        "Updated Title",
+		Description:  "Updated Description",
+		UpdatedAt:    time.Now(),
 	}
 
-	mockRepo.On("Update", mock.Anything, "test-id", mock.Anything).Return(updatedAnnotation, nil)
-	mockCache.On("Set", mock.Anything, updatedAnnotation).Return(nil)
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64(nil)).Return(updatedAnnotation, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
 
 	body := `{"title": "Updated Title", "description": "Updated Description"}`
-	req := httptest.NewRequest(http.MethodPut, "/api/v1/annotations/test-id", bytes.NewBufferString(body))
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
@@ -332,10 +1011,10 @@ func TestUpdate_Success(t *testing.T) {
 func TestUpdate_NotFound(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
-	mockRepo.On("Update", mock.Anything, "nonexistent", mock.Anything).Return(nil, nil)
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "nonexistent", mock.Anything, []int64(nil)).Return(nil, nil)
 
 	body := `{"title": "Updated Title"}`
-	req := httptest.NewRequest(http.MethodPut, "/api/v1/annotations/nonexistent", bytes.NewBufferString(body))
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/pointclouds/pc-1/annotations/nonexistent", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
@@ -347,13 +1026,46 @@ func TestUpdate_NotFound(t *testing.T) {
 	mockCache.AssertNotCalled(t, "Set")
 }
 
+func TestUpdate_InvalidGeometry(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	body := `{"geometry": {"type": "cuboid", "center": {"x": 1, "y": 2, "z": 3}}}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockRepo.AssertNotCalled(t, "Update")
+}
+
+func TestUpdate_CuboidPosition(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	// Moving only the position of a cuboid is refused by the repository
+	mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64(nil)).Return(nil, models.ErrCuboidOffCenter)
+
+	body := `{"x": 5}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrCuboidOffCenter.Error())
+	mockCache.AssertNotCalled(t, "InvalidateAll", mock.Anything, mock.Anything)
+}
+
 func TestDelete_Success(t *testing.T) {
 	_, mockRepo, mockCache, engine := setupTestHandler()
 
-	mockRepo.On("Delete", mock.Anything, "test-id").Return(nil)
-	mockCache.On("Delete", mock.Anything, "test-id").Return(nil)
+	mockRepo.On("Delete", mock.Anything, testPointCloud.ID, "test-id", []int64(nil)).Return(nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/annotations/test-id", nil)
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/pointclouds/pc-1/annotations/test-id", nil)
 	w := httptest.NewRecorder()
 
 	engine.ServeHTTP(w, req)
@@ -367,24 +1079,14 @@ func TestDelete_Success(t *testing.T) {
 func TestDelete_NotFound(t *testing.T) {
 	_, mockRepo, _, engine := setupTestHandler()
 
-	// Set up mock to return "annotation not found" error
-	mockRepo.On("Delete", mock.Anything, "nonexistent").Return(
-		&notFoundError{},
+	mockRepo.On("Delete", mock.Anything, testPointCloud.ID, "nonexistent", []int64(nil)).Return(
+		database.ErrAnnotationNotFound,
 	)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/annotations/nonexistent", nil)
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/pointclouds/pc-1/annotations/nonexistent", nil)
 	w := httptest.NewRecorder()
 
 	engine.ServeHTTP(w, req)
 
-	// Will return 500 because our mock error doesn't match "annotation not found" exactly
-	// In real implementation, this would return 404
-	assert.True(t, w.Code == http.StatusNotFound || w.Code == http.StatusInternalServerError)
-}
-
-// notFoundError implements error for testing
-type notFoundError struct{}
-
-func (e *notFoundError) Error() string {
-	return "annotation not found"
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/identity"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// authorHeader names who makes an annotation write when authentication is
+// off; the name is recorded in the annotation's history.
+const authorHeader = "X-Author"
+
+// writeContext returns the context of annotation writes, attributed to the
+// authenticated user, or without authentication to the author the request
+// names. It ends with the request.
+func writeContext(c *gin.Context) context.Context {
+	author := c.GetHeader(authorHeader)
+	if id := identity.FromContext(c.Request.Context()); id != nil {
+		author = id.Author()
+	}
+	return database.WithAuthor(c.Request.Context(), author)
+}
+
+// committed returns the context of the cache updates and events that follow a
+// committed write. They must not be skipped when the client goes away, or the
+// cache would keep serving the annotations as they were before the write.
+func committed(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// writeNonconforming answers a revert or restore that would bring back an
+// annotation no longer conforming to its label. Unlike a write the client
+// sent, the request cannot be fixed, only the annotation edited first.
+func writeNonconforming(c *gin.Context, err error) {
+	c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+		Error:   "nonconforming",
+		Message: err.Error(),
+	})
+}
+
+// GetHistory handles retrieving the revisions of an annotation.
+// @Summary Get annotation history
+// @Description Retrieve every revision of an annotation, oldest first, including its deletion
+// @Tags history
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Success 200 {object} models.HistoryResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId}/history [get]
+func (h *Handler) GetHistory(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
+	ctx := c.Request.Context()
+
+	revisions, err := h.repo.GetHistory(ctx, pointCloudID, id)
+	if err != nil {
+		h.logger.Error("Failed to get annotation history", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve annotation history",
+		})
+		return
+	}
+
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "annotation not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.HistoryResponse{Data: revisions})
+}
+
+// Revert handles restoring an annotation to one of its revisions.
+// @Summary Revert annotation
+// @Description Restore an annotation to the state of a revision, recreating it if it was deleted; the revert is recorded as a new revision
+// @Tags history
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param rev path int true "Revision to restore"
+// @Success 200 {object} models.AnnotationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId}/revert/{rev} [post]
+func (h *Handler) Revert(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
+
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "revision must be a positive integer",
+		})
+		return
+	}
+
+	ctx := writeContext(c)
+	annotation, err := h.repo.Revert(ctx, pointCloudID, id, revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrRevisionIsDeletion):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "cannot revert to a deletion; delete the annotation instead",
+			})
+			return
+		case errors.Is(err, database.ErrRevisionReferenceGone):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "conflict",
+				Message: err.Error(),
+			})
+			return
+		case errors.Is(err, database.ErrNonconforming):
+			writeNonconforming(c, err)
+			return
+		}
+		if writeTrackError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to revert annotation", zap.String("id", id), zap.Int("revision", revision), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to revert annotation",
+		})
+		return
+	}
+
+	if annotation == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "revision not found",
+		})
+		return
+	}
+
+	// Update cache
+	ctx = committed(ctx)
+	h.cacheWritten(ctx, annotation)
+	h.publish(ctx, annotationEvent(ctx, models.EventUpdated, annotation))
+
+	c.Header("ETag", annotationETag(annotation.Version))
+	c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
+}
+
+// getByIDAsOf answers a point-in-time read of a single annotation from its
+// history, bypassing the cache.
+func (h *Handler) getByIDAsOf(ctx context.Context, c *gin.Context, pointCloudID, id string) {
+	asOf, err := time.Parse(time.RFC3339, c.Query("as_of"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "as_of must be an RFC 3339 timestamp",
+		})
+		return
+	}
+
+	annotation, err := h.repo.GetByIDAsOf(ctx, pointCloudID, id, asOf)
+	if err != nil {
+		h.logger.Error("Failed to get annotation revision", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve annotation",
+		})
+		return
+	}
+
+	if annotation == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "annotation not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/identity"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestGetHistory_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	revisions := []models.AnnotationRevision{
+		{AnnotationID: "test-id", Revision: 1, Operation: models.RevisionCreate, Author: "alice"},
+		{AnnotationID: "test-id", Revision: 2, Operation: models.RevisionUpdate, Author: "bob"},
+	}
+	mockRepo.On("GetHistory", mock.Anything, testPointCloud.ID, "test-id").Return(revisions, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/test-id/history", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.HistoryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.Equal(t, "bob", response.Data[1].Author)
+}
+
+func TestGetHistory_NotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetHistory", mock.Anything, testPointCloud.ID, "missing").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/missing/history", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRevert_Success(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	reverted := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Title: "car"}
+
+	mockRepo.On("Revert", mock.MatchedBy(func(ctx context.Context) bool { return ctx != nil }), testPointCloud.ID, "test-id", 2).Return(reverted, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/revert/2", nil)
+	req.Header.Set(authorHeader, "alice")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestWriteContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/revert/2", nil)
+	c.Request.Header.Set(authorHeader, "alice")
+	assert.Equal(t, "alice", database.AuthorFrom(writeContext(c)))
+
+	// The authenticated user wins over the header
+	c.Request = c.Request.WithContext(identity.WithIdentity(c.Request.Context(), &identity.Identity{Subject: "u-42", Name: "Bob"}))
+	assert.Equal(t, "Bob", database.AuthorFrom(writeContext(c)))
+
+	// Writes end with the request; what follows a committed write does not
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+	cancel()
+	assert.Error(t, writeContext(c).Err())
+	assert.NoError(t, committed(writeContext(c)).Err())
+	assert.Equal(t, "Bob", database.AuthorFrom(committed(writeContext(c))))
+}
+
+func TestRevert_InvalidRevision(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	for _, rev := range []string{"0", "-1", "latest"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/revert/"+rev, nil)
+		w := httptest.NewRecorder()
+
+		engine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, rev)
+	}
+	mockRepo.AssertNotCalled(t, "Revert")
+}
+
+func TestRevert_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *models.Annotation
+		err      error
+		expected int
+	}{
+		{"revision not found", nil, nil, http.StatusNotFound},
+		{"deletion", nil, database.ErrRevisionIsDeletion, http.StatusBadRequest},
+		{"deleted label", nil, database.ErrRevisionReferenceGone, http.StatusConflict},
+		{"track observed", nil, database.ErrTrackFrameTaken, http.StatusConflict},
+		{"nonconforming", nil, fmt.Errorf("%w: geometry type not allowed", database.ErrNonconforming), http.StatusUnprocessableEntity},
+		{"database error", nil, errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, mockCache, engine := setupTestHandler()
+
+			mockRepo.On("Revert", mock.Anything, testPointCloud.ID, "test-id", 3).Return(tt.result, tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/revert/3", nil)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			mockCache.AssertNotCalled(t, "Set")
+		})
+	}
+}
+
+func TestGetByID_AsOf(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	asOf := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Title: "old title"}
+
+	mockRepo.On("GetByIDAsOf", mock.Anything, testPointCloud.ID, "test-id", asOf).Return(past, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/test-id?as_of=2024-05-01T12:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AnnotationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "old title", response.Data.Title)
+	mockCache.AssertNotCalled(t, "Get")
+}
+
+func TestGetByID_AsOfInvalid(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations/test-id?as_of=yesterday", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "GetByIDAsOf")
+}
+
+func TestGetAll_AsOfBypassesCache(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	page := &models.AnnotationPage{Annotations: []models.Annotation{{ID: "test-id", PointCloudID: testPointCloud.ID}}}
+	matchQuery := mock.MatchedBy(func(q *models.AnnotationQuery) bool { return q.AsOf != nil })
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAll", mock.Anything, testPointCloud.ID, matchQuery).Return(page, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?as_of=2024-05-01T12:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "GetAll")
+	mockCache.AssertNotCalled(t, "SetAll")
+}
@@ -0,0 +1,539 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// exporter writes a point cloud's annotations in an interchange format.
+type exporter struct {
+	contentType string
+	extension   string
+	write       func(w io.Writer, src *exportSource) error
+
+	// columns is set for formats flattening attributes into columns, which
+	// need the attribute columns up front.
+	columns bool
+}
+
+// importer reads annotations in an interchange format into create requests.
+// Records that cannot be read are reported as import errors and records the
+// format marks as not annotated are left nil; the error is for bodies that
+// cannot be read at all.
+type importer func(r io.Reader, labels *labelNames) ([]*models.CreateAnnotationRequest, []models.ImportError, error)
+
+// maxImportBytes bounds the body of an import request.
+var maxImportBytes int64 = 64 << 20
+
+// errImportTooLarge is returned by importers for bodies holding more records
+// than an import may, as soon as they read one too many.
+var errImportTooLarge = fmt.Errorf("an import may hold at most %d annotations", models.MaxBatchOperations)
+
+// exporters and importers are keyed by the format query parameter.
+var (
+	exporters = map[string]exporter{
+		"geojson":  {contentType: "application/geo+json", extension: "geojson", write: writeGeoJSON},
+		"kitti":    {contentType: "text/plain; charset=utf-8", extension: "txt", write: writeKITTI},
+		"nuscenes": {contentType: "application/json", extension: "json", write: writeNuScenes},
+		"csv":      {contentType: "text/csv; charset=utf-8", extension: "csv", write: writeCSV, columns: true},
+		"parquet":  {contentType: "application/vnd.apache.parquet", extension: "parquet", write: writeParquet, columns: true},
+	}
+	importers = map[string]importer{
+		"geojson":  readGeoJSON,
+		"kitti":    readKITTI,
+		"nuscenes": readNuScenes,
+	}
+)
+
+// formatNames lists the formats of an exporter or importer table.
+func formatNames[T any](formats map[string]T) string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// labelNames resolves labels by name, for formats that name object classes
+// instead of referring to labels.
+type labelNames struct {
+	byID   map[string]string
+	byName map[string]string
+}
+
+func (h *Handler) labelNames(ctx context.Context) (*labelNames, error) {
+	labels, err := h.repo.GetAllLabels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+
+	names := &labelNames{byID: make(map[string]string, len(labels)), byName: make(map[string]string, len(labels))}
+	for _, label := range labels {
+		names.byID[label.ID] = label.Name
+		names.byName[strings.ToLower(label.Name)] = label.ID
+	}
+	return names, nil
+}
+
+// name returns the name of the annotation's label, or its title if it has none.
+func (l *labelNames) name(a *models.Annotation) string {
+	if a.LabelID != nil {
+		if name, ok := l.byID[*a.LabelID]; ok {
+			return name
+		}
+	}
+	return a.Title
+}
+
+// id returns the ID of the label named name regardless of case, if there is
+// one.
+func (l *labelNames) id(name string) *string {
+	if id, ok := l.byName[strings.ToLower(name)]; ok {
+		return &id
+	}
+	return nil
+}
+
+// exportSource is what exporters read: the point cloud's annotations, streamed
+// oldest first, and what they refer to.
+type exportSource struct {
+	each       func(fn func(*models.Annotation) error) error
+	labels     *labelNames
+	attributes []models.AttributeColumn
+}
+
+// export is an export whose checks have passed.
+type export struct {
+	format exporter
+	source *exportSource
+}
+
+func (e *export) write(w io.Writer) error {
+	return e.format.write(w, e.source)
+}
+
+// prepareExport checks an export of a point cloud and reads what the format
+// needs up front, so that failures are known before anything is written. The
+// annotations are streamed as the export is written.
+func (h *Handler) prepareExport(ctx context.Context, pointCloudID, format string) (*export, error) {
+	if pointCloudID == "" {
+		return nil, newRequestError(http.StatusBadRequest, "invalid_request", "point_cloud_id is required")
+	}
+
+	exp, ok := exporters[format]
+	if !ok {
+		return nil, newRequestError(http.StatusBadRequest, "invalid_request", "format must be one of "+formatNames(exporters))
+	}
+
+	if failure := h.checkPointCloud(ctx, h.repo, pointCloudID); failure != nil {
+		return nil, failure
+	}
+
+	labels, err := h.labelNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &exportSource{labels: labels}
+	if exp.columns {
+		if src.attributes, err = h.repo.GetAttributeColumns(ctx, pointCloudID); err != nil {
+			return nil, err
+		}
+	}
+
+	query := models.NewAnnotationQuery()
+	_ = query.SetSort(models.SortByCreatedAt)
+	src.each = func(fn func(*models.Annotation) error) error {
+		return h.repo.StreamAll(ctx, pointCloudID, query, fn)
+	}
+
+	return &export{format: exp, source: src}, nil
+}
+
+// Export writes all annotations of a point cloud in an interchange format.
+func (h *Handler) Export(ctx context.Context, w io.Writer, pointCloudID, format string) error {
+	exp, err := h.prepareExport(ctx, pointCloudID, format)
+	if err != nil {
+		return err
+	}
+	return exp.write(w)
+}
+
+// ExportAnnotations handles exporting a point cloud's annotations.
+// @Summary Export annotations
+// @Description Download all annotations of a point cloud in an interchange format. KITTI and nuScenes exports hold the cuboid annotations only.
+// @Tags interchange
+// @Produce json
+// @Produce plain
+// @Produce octet-stream
+// @Param point_cloud_id query string true "Point cloud ID"
+// @Param format query string true "Export format: geojson, kitti, nuscenes, csv or parquet"
+// @Success 200 {object} models.FeatureCollection
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/annotations/export [get]
+func (h *Handler) ExportAnnotations(c *gin.Context) {
+	pointCloudID := c.Query("point_cloud_id")
+	exp, err := h.prepareExport(c.Request.Context(), pointCloudID, c.Query("format"))
+	if err != nil {
+		h.writeInterchangeError(c, "export", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, pointCloudID, exp.format.extension))
+	c.Header("Content-Type", exp.format.contentType)
+	c.Status(http.StatusOK)
+	if err := exp.write(c.Writer); err != nil {
+		h.logger.Error("Failed to write export", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+	}
+}
+
+// importRejection is an import refused for the records at fault.
+type importRejection struct {
+	status int
+	code   string
+	errors []models.ImportError
+}
+
+func (e *importRejection) Error() string {
+	messages := make([]string, len(e.errors))
+	for i, importError := range e.errors {
+		messages[i] = fmt.Sprintf("record %d: %s", importError.Index, importError.Message)
+	}
+	return e.message() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *importRejection) message() string {
+	return fmt.Sprintf("%d of the imported annotations were rejected", len(e.errors))
+}
+
+// Import creates annotations in a point cloud from an interchange format. The
+// import is all or nothing: if any record is invalid, nothing is created and
+// the error lists every record at fault.
+func (h *Handler) Import(ctx context.Context, r io.Reader, pointCloudID, format string) (*models.ImportResult, error) {
+	if pointCloudID == "" {
+		return nil, newRequestError(http.StatusBadRequest, "invalid_request", "point_cloud_id is required")
+	}
+
+	read, ok := importers[format]
+	if !ok {
+		return nil, newRequestError(http.StatusBadRequest, "invalid_request", "format must be one of "+formatNames(importers))
+	}
+
+	refs := newMemoRefs(h.repo)
+	if failure := h.checkPointCloud(ctx, refs, pointCloudID); failure != nil {
+		return nil, failure
+	}
+
+	labels, err := h.labelNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, importErrors, err := read(r, labels)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, newRequestError(http.StatusRequestEntityTooLarge, "request_too_large",
+			fmt.Sprintf("an import body may be at most %d bytes", tooLarge.Limit))
+	}
+	if err != nil {
+		h.logger.Warn("Invalid import", zap.Error(err))
+		return nil, newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	// Records that could be read are checked like single creates; indexes
+	// maps the operations back to the records
+	var ops []models.BatchOperation
+	var indexes []int
+	for i, req := range requests {
+		if req == nil {
+			continue
+		}
+		if len(ops) == models.MaxBatchOperations {
+			return nil, newRequestError(http.StatusBadRequest, "invalid_request", errImportTooLarge.Error())
+		}
+		if failure := h.checkCreate(ctx, refs, pointCloudID, req); failure != nil {
+			if failure.status >= http.StatusInternalServerError {
+				return nil, failure
+			}
+			importErrors = append(importErrors, models.ImportError{Index: i, Message: failure.body.Message})
+			continue
+		}
+		ops = append(ops, models.BatchOperation{Op: models.BatchCreate, PointCloudID: pointCloudID, Create: req})
+		indexes = append(indexes, i)
+	}
+
+	if len(importErrors) > 0 {
+		sort.Slice(importErrors, func(i, j int) bool { return importErrors[i].Index < importErrors[j].Index })
+		return nil, &importRejection{status: http.StatusBadRequest, code: "invalid_request", errors: importErrors}
+	}
+	if len(ops) == 0 {
+		return nil, newRequestError(http.StatusBadRequest, "invalid_request", "the import holds no annotations")
+	}
+
+	items, err := h.repo.Batch(ctx, ops, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply import: %w", err)
+	}
+
+	// An atomic batch ends at its failing operation
+	if n := len(items); n > 0 && items[n-1].Err != nil {
+		failure := h.operationError(&ops[n-1], items[n-1].Err)
+		if failure.status >= http.StatusInternalServerError {
+			return nil, failure
+		}
+		return nil, &importRejection{
+			status: failure.status,
+			code:   failure.body.Error,
+			errors: []models.ImportError{{Index: indexes[n-1], Message: failure.body.Message}},
+		}
+	}
+
+	ctx = committed(ctx)
+	_ = h.cache.InvalidateAll(ctx, pointCloudID)
+
+	result := &models.ImportResult{Imported: len(items), IDs: make([]string, len(items))}
+	created := make([]*models.AnnotationEvent, len(items))
+	for i, item := range items {
+		result.IDs[i] = item.Annotation.ID
+		created[i] = annotationEvent(ctx, models.EventCreated, item.Annotation)
+	}
+	h.publish(ctx, created...)
+	return result, nil
+}
+
+// ImportAnnotations handles importing annotations into a point cloud.
+// @Summary Import annotations
+// @Description Create annotations in a point cloud from an interchange format. The import is all or nothing: if any feature or record is invalid, the import is rejected listing all of them.
+// @Tags interchange
+// @Accept json
+// @Accept plain
+// @Produce json
+// @Param point_cloud_id query string true "Point cloud ID"
+// @Param format query string true "Import format: geojson, kitti or nuscenes"
+// @Param annotations body models.FeatureCollection true "Annotations"
+// @Success 201 {object} models.ImportResponse
+// @Failure 400 {object} models.ImportErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ImportErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/annotations/import [post]
+func (h *Handler) ImportAnnotations(c *gin.Context) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	result, err := h.Import(writeContext(c), body, c.Query("point_cloud_id"), c.Query("format"))
+	if err != nil {
+		h.writeInterchangeError(c, "import", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.ImportResponse{Data: *result})
+}
+
+// writeInterchangeError answers a failed export or import.
+func (h *Handler) writeInterchangeError(c *gin.Context, action string, err error) {
+	var rejection *importRejection
+	if errors.As(err, &rejection) {
+		c.JSON(rejection.status, models.ImportErrorResponse{
+			Error:   rejection.code,
+			Message: rejection.message(),
+			Errors:  rejection.errors,
+		})
+		return
+	}
+
+	var failure *requestError
+	if errors.As(err, &failure) {
+		failure.write(c)
+		return
+	}
+
+	h.logger.Error("Failed to "+action+" annotations", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "internal_error",
+		Message: "failed to " + action + " annotations",
+	})
+}
+
+// writeGeoJSON writes annotations as a GeoJSON feature collection.
+func writeGeoJSON(w io.Writer, src *exportSource) error {
+	return writeJSONArray(w, `{"type":"`+models.GeoJSONFeatureCollection+`","features":`, "}", src,
+		func(a *models.Annotation) (any, bool) {
+			return models.NewFeature(a), true
+		})
+}
+
+// writeJSONArray writes the annotations as a JSON array of the records record
+// returns, skipping annotations it returns false for, between prefix and
+// suffix. Records are encoded one at a time as they are streamed.
+func writeJSONArray(w io.Writer, prefix, suffix string, src *exportSource, record func(a *models.Annotation) (any, bool)) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+
+	bw.WriteString(prefix + "[")
+	first := true
+	err := src.each(func(a *models.Annotation) error {
+		value, ok := record(a)
+		if !ok {
+			return nil
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		return encoder.Encode(value)
+	})
+	if err != nil {
+		return err
+	}
+
+	bw.WriteString("]" + suffix + "\n")
+	return bw.Flush()
+}
+
+// readGeoJSON reads the features of a GeoJSON feature collection.
+func readGeoJSON(r io.Reader, _ *labelNames) ([]*models.CreateAnnotationRequest, []models.ImportError, error) {
+	var collection models.FeatureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	if collection.Type != models.GeoJSONFeatureCollection {
+		return nil, nil, fmt.Errorf("GeoJSON must be a %s", models.GeoJSONFeatureCollection)
+	}
+	if len(collection.Features) == 0 {
+		return nil, nil, fmt.Errorf("the feature collection is empty")
+	}
+
+	requests := make([]*models.CreateAnnotationRequest, len(collection.Features))
+	var importErrors []models.ImportError
+	for i := range collection.Features {
+		req, err := collection.Features[i].CreateRequest()
+		if err != nil {
+			importErrors = append(importErrors, models.ImportError{Index: i, Message: err.Error()})
+			continue
+		}
+		requests[i] = req
+	}
+	return requests, importErrors, nil
+}
+
+// writeKITTI writes the cuboid annotations as KITTI label_2 lines, typed by
+// their label.
+func writeKITTI(w io.Writer, src *exportSource) error {
+	bw := bufio.NewWriter(w)
+	err := src.each(func(a *models.Annotation) error {
+		object, ok := models.NewKITTIObject(a, src.labels.name(a))
+		if !ok {
+			return nil
+		}
+		_, err := fmt.Fprintln(bw, object.String())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readKITTI reads KITTI label_2 lines; records are numbered by line from zero.
+// Blank lines and DontCare regions are skipped. Objects are labelled by the
+// label named like their type, reading underscores as spaces if need be.
+// Reading stops at the first object past the import limit.
+func readKITTI(r io.Reader, labels *labelNames) ([]*models.CreateAnnotationRequest, []models.ImportError, error) {
+	var requests []*models.CreateAnnotationRequest
+	var importErrors []models.ImportError
+
+	objects := 0
+	scanner := bufio.NewScanner(r)
+	for i := 0; scanner.Scan(); i++ {
+		requests = append(requests, nil)
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if objects++; objects > models.MaxBatchOperations {
+			return nil, nil, errImportTooLarge
+		}
+		object, err := models.ParseKITTIObject(line)
+		if err != nil {
+			importErrors = append(importErrors, models.ImportError{Index: i, Message: err.Error()})
+			continue
+		}
+		if object.Type == models.KITTIDontCare {
+			continue
+		}
+
+		req := object.CreateRequest()
+		req.LabelID = labels.id(object.Type)
+		if req.LabelID == nil {
+			req.LabelID = labels.id(strings.ReplaceAll(object.Type, "_", " "))
+		}
+		requests[i] = req
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("invalid KITTI labels: %w", err)
+	}
+	return requests, importErrors, nil
+}
+
+// writeNuScenes writes the cuboid annotations as a JSON array of nuScenes
+// sample_annotation records, categorized by their label.
+func writeNuScenes(w io.Writer, src *exportSource) error {
+	return writeJSONArray(w, "", "", src, func(a *models.Annotation) (any, bool) {
+		return models.NewNuScenesAnnotation(a, src.labels.name(a))
+	})
+}
+
+// readNuScenes reads a JSON array of nuScenes sample_annotation records.
+// Records are labelled by the label named like their category. The array is
+// decoded a record at a time, stopping at the first past the import limit.
+func readNuScenes(r io.Reader, labels *labelNames) ([]*models.CreateAnnotationRequest, []models.ImportError, error) {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid nuScenes sample annotations: %w", err)
+	}
+	if token == nil {
+		return nil, nil, fmt.Errorf("there are no sample annotations")
+	}
+	if token != json.Delim('[') {
+		return nil, nil, fmt.Errorf("invalid nuScenes sample annotations: expected an array")
+	}
+
+	var requests []*models.CreateAnnotationRequest
+	for decoder.More() {
+		if len(requests) == models.MaxBatchOperations {
+			return nil, nil, errImportTooLarge
+		}
+
+		var record models.NuScenesAnnotation
+		if err := decoder.Decode(&record); err != nil {
+			return nil, nil, fmt.Errorf("invalid nuScenes sample annotations: %w", err)
+		}
+		req := record.CreateRequest()
+		req.LabelID = labels.id(record.CategoryName)
+		requests = append(requests, req)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, fmt.Errorf("invalid nuScenes sample annotations: %w", err)
+	}
+	if len(requests) == 0 {
+		return nil, nil, fmt.Errorf("there are no sample annotations")
+	}
+	return requests, nil, nil
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestExportAnnotations_GeoJSON(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	yaw := 0.5
+	annotations := []models.Annotation{
+		{ID: "a-1", PointCloudID: testPointCloud.ID, X: 1, Y: 2, Z: 3, Title: "Marker", Geometry: models.PointGeometry()},
+		{ID: "a-2", PointCloudID: testPointCloud.ID, Title: "Curb", Geometry: models.Geometry{
+			Type:     models.GeometryPolyline,
+			Vertices: []models.Vec3{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}},
+		}},
+		{ID: "a-3", PointCloudID: testPointCloud.ID, Title: "Car", Geometry: models.Geometry{
+			Type:   models.GeometryCuboid,
+			Center: &models.Vec3{X: 1, Y: 2, Z: 3},
+			Size:   &models.Dimensions{Length: 4, Width: 2, Height: 1.5},
+			Yaw:    &yaw,
+		}},
+	}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{}, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.MatchedBy(func(q *models.AnnotationQuery) bool {
+		return q.Sort() == models.SortByCreatedAt
+	})).Return(annotations, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=geojson", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+
+	var collection models.FeatureCollection
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Equal(t, models.GeoJSONFeatureCollection, collection.Type)
+	assert.Len(t, collection.Features, 3)
+	assert.Equal(t, models.GeoJSONPoint, collection.Features[0].Geometry.Type)
+	assert.JSONEq(t, `[1, 2, 3]`, string(collection.Features[0].Geometry.Coordinates))
+	assert.Equal(t, models.GeoJSONLineString, collection.Features[1].Geometry.Type)
+	assert.Equal(t, models.GeometryCuboid, collection.Features[2].Properties.Shape.Type)
+}
+
+func TestExportAnnotations_UnsupportedFormat(t *testing.T) {
+	_, _, _, engine := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=shapefile", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportAnnotations_GeoJSON(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	body := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2, 3]}, "properties": {"title": "Marker"}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0, 0], [2, 0, 0], [2, 2, 0], [0, 0, 0]]]},
+			"properties": {"title": "Footprint", "height": 1.5}}
+	]}`
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil).Once()
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{}, nil)
+	mockRepo.On("Batch", mock.Anything, mock.MatchedBy(func(ops []models.BatchOperation) bool {
+		return len(ops) == 2 &&
+			ops[0].Create.Geometry.Type == models.GeometryPoint && ops[0].Create.Z == 3 &&
+			ops[1].Create.Geometry.Type == models.GeometryPolygon && *ops[1].Create.Geometry.Height == 1.5
+	}), true).Return([]database.BatchItem{
+		{Annotation: &models.Annotation{ID: "a-1"}},
+		{Annotation: &models.Annotation{ID: "a-2"}},
+	}, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations/import?point_cloud_id=pc-1&format=geojson", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.ImportResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Data.Imported)
+	assert.Equal(t, []string{"a-1", "a-2"}, response.Data.IDs)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestImportAnnotations_InvalidFeatures(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	body := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {"title": "Flat"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2, 3]}, "properties": {"title": "Marker"}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[1, 2, 3]]}, "properties": {"title": "Stub"}}
+	]}`
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations/import?point_cloud_id=pc-1&format=geojson", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response models.ImportErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Errors, 2)
+	assert.Equal(t, 0, response.Errors[0].Index)
+	assert.Equal(t, 2, response.Errors[1].Index)
+	assert.Contains(t, response.Errors[1].Message, "at least 2 vertices")
+
+	mockRepo.AssertNotCalled(t, "Batch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+var testPedestrian = models.Label{ID: "label-pedestrian", Name: "Pedestrian", Color: "#00ff00"}
+
+func TestExportAnnotations_KITTI(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	yaw := 0.0
+	annotations := []models.Annotation{
+		{ID: "a-1", PointCloudID: testPointCloud.ID, X: 1, Y: 2, Z: 3, Title: "Marker", Geometry: models.PointGeometry()},
+		{ID: "a-2", PointCloudID: testPointCloud.ID, Title: "Walker", LabelID: &testPedestrian.ID, Geometry: models.Geometry{
+			Type:   models.GeometryCuboid,
+			Center: &models.Vec3{X: 10, Y: 2, Z: 0.9},
+			Size:   &models.Dimensions{Length: 0.8, Width: 0.6, Height: 1.8},
+			Yaw:    &yaw,
+		}},
+	}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(annotations, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{testPedestrian}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=kitti", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="pc-1.txt"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "Pedestrian 0.00 0 -1.37 0.00 0.00 0.00 0.00 1.80 0.60 0.80 -2.00 -0.00 10.00 -1.57\n", w.Body.String())
+}
+
+func TestImportAnnotations_KITTI(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	body := "Pedestrian 0.00 0 -1.37 0.00 0.00 0.00 0.00 1.80 0.60 0.80 -2.00 0.00 10.00 -1.57\n" +
+		"DontCare -1 -1 -10 0.00 0.00 0.00 0.00 -1 -1 -1 -1000 -1000 -1000 -10\n" +
+		"Traffic_cone 0.00 1 0.00 0.00 0.00 0.00 0.00 0.50 0.30 0.30 1.00 1.00 5.00 0.00\n"
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil).Once()
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{testPedestrian}, nil)
+	mockRepo.On("GetLabel", mock.Anything, testPedestrian.ID).Return(&testPedestrian, nil).Once()
+	mockRepo.On("Batch", mock.Anything, mock.MatchedBy(func(ops []models.BatchOperation) bool {
+		return len(ops) == 2 &&
+			*ops[0].Create.LabelID == testPedestrian.ID && ops[0].Create.Title == "Pedestrian" &&
+			ops[1].Create.LabelID == nil && ops[1].Create.Attributes["occluded"] == 1.0
+	}), true).Return([]database.BatchItem{
+		{Annotation: &models.Annotation{ID: "a-1"}},
+		{Annotation: &models.Annotation{ID: "a-2"}},
+	}, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations/import?point_cloud_id=pc-1&format=kitti", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.ImportResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"a-1", "a-2"}, response.Data.IDs)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportAnnotations_KITTIInvalidLine(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	body := "DontCare -1 -1 -10 0.00 0.00 0.00 0.00 -1 -1 -1 -1000 -1000 -1000 -10\n" +
+		"Car 0.00 0 0.00 0.00 0.00 0.00 0.00 1.50 1.80\n"
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations/import?point_cloud_id=pc-1&format=kitti", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response models.ImportErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Errors, 1)
+	assert.Equal(t, 1, response.Errors[0].Index)
+
+	mockRepo.AssertNotCalled(t, "Batch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportAnnotations_NuScenes(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	body := `[{
+		"token": "70aecbe9b64f4722ab3c230391a3beb8", "sample_token": "cd21dbfc3bd749c7b10a5c42562e0c42",
+		"instance_token": "6dd2cbf4c24b4caeb625035869bca7b5", "visibility_token": "4", "attribute_tokens": [],
+		"translation": [373.214, 1130.48, 1.25], "size": [0.621, 0.669, 1.642], "rotation": [0.9831, 0, 0, -0.1830],
+		"prev": "", "next": "", "num_lidar_pts": 5, "num_radar_pts": 0, "category_name": "Pedestrian"
+	}]`
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil).Once()
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{testPedestrian}, nil)
+	mockRepo.On("GetLabel", mock.Anything, testPedestrian.ID).Return(&testPedestrian, nil).Once()
+	mockRepo.On("Batch", mock.Anything, mock.MatchedBy(func(ops []models.BatchOperation) bool {
+		return len(ops) == 1 &&
+			*ops[0].Create.LabelID == testPedestrian.ID &&
+			ops[0].Create.Geometry.Size.Length == 0.669 &&
+			ops[0].Create.Attributes["instance_token"] == "6dd2cbf4c24b4caeb625035869bca7b5"
+	}), true).Return([]database.BatchItem{{Annotation: &models.Annotation{ID: "a-1"}}}, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations/import?point_cloud_id=pc-1&format=nuscenes", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportAnnotations_BodyTooLarge(t *testing.T) {
+	limit := maxImportBytes
+	maxImportBytes = 64
+	defer func() { maxImportBytes = limit }()
+
+	_, mockRepo, _, engine := setupTestHandler()
+
+	body := strings.Repeat("Car 0.00 0 0.00 0.00 0.00 0.00 0.00 1.50 1.80 4.00 1.00 1.00 5.00 0.00\n", 2)
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/annotations/import?point_cloud_id=pc-1&format=kitti", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var response models.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "request_too_large", response.Error)
+
+	mockRepo.AssertNotCalled(t, "Batch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// pastImportLimit returns a reader of one record more than an import may
+// hold, failing if it is read any further.
+func pastImportLimit(record, separator string) io.Reader {
+	records := strings.Repeat(record+separator, models.MaxBatchOperations+1)
+	return io.MultiReader(strings.NewReader(records), iotest.ErrReader(errors.New("read past the import limit")))
+}
+
+func TestReadKITTI_StopsPastLimit(t *testing.T) {
+	r := pastImportLimit("Car 0.00 0 0.00 0.00 0.00 0.00 0.00 1.50 1.80 4.00 1.00 1.00 5.00 0.00", "\n")
+
+	_, _, err := readKITTI(r, &labelNames{})
+	assert.ErrorIs(t, err, errImportTooLarge)
+}
+
+func TestReadNuScenes_StopsPastLimit(t *testing.T) {
+	record := `{"token": "t", "translation": [1, 2, 3], "size": [1, 1, 1], "rotation": [1, 0, 0, 0], "category_name": "Car"}`
+	r := io.MultiReader(strings.NewReader("["), pastImportLimit(record, ","))
+
+	_, _, err := readNuScenes(r, &labelNames{})
+	assert.ErrorIs(t, err, errImportTooLarge)
+}
+
+func TestReadNuScenes_Invalid(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"null", "there are no sample annotations"},
+		{"[]", "there are no sample annotations"},
+		{`{"token": "t"}`, "expected an array"},
+		{`[{"token": "t"}`, "invalid nuScenes sample annotations"},
+		{`[{"size": "large"}]`, "invalid nuScenes sample annotations"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := readNuScenes(strings.NewReader(tt.body), &labelNames{})
+		if assert.Error(t, err, tt.body) {
+			assert.Contains(t, err.Error(), tt.expected, tt.body)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// writeLabelError writes the response for errors of label writes, returning
+// false for unexpected errors the caller has to report itself.
+func writeLabelError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, database.ErrLabelExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "conflict",
+			Message: "a label with this name already exists",
+		})
+	case errors.Is(err, database.ErrParentLabelNotFound), errors.Is(err, database.ErrLabelCycle):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// CreateLabel handles adding a class to the label taxonomy.
+// @Summary Create label
+// @Description Add a class to the label taxonomy
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param label body models.CreateLabelRequest true "Label data"
+// @Success 201 {object} models.LabelResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/labels [post]
+func (h *Handler) CreateLabel(c *gin.Context) {
+	var req models.CreateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid create label request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	label, err := h.repo.CreateLabel(ctx, &req)
+	if err != nil {
+		if writeLabelError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to create label", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to create label",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.LabelResponse{Data: *label})
+}
+
+// GetAllLabels handles retrieving the label taxonomy.
+// @Summary Get all labels
+// @Description Retrieve all classes of the label taxonomy
+// @Tags labels
+// @Produce json
+// @Success 200 {object} models.LabelsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/labels [get]
+func (h *Handler) GetAllLabels(c *gin.Context) {
+	ctx := context.Background()
+
+	labels, err := h.repo.GetAllLabels(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get labels", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve labels",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LabelsResponse{Data: labels})
+}
+
+// GetLabel handles retrieving a single label by ID.
+// @Summary Get label by ID
+// @Description Retrieve a specific class of the label taxonomy
+// @Tags labels
+// @Produce json
+// @Param id path string true "Label ID"
+// @Success 200 {object} models.LabelResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/labels/{id} [get]
+func (h *Handler) GetLabel(c *gin.Context) {
+	id := c.Param("id")
+	ctx := context.Background()
+
+	label, err := h.repo.GetLabel(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get label", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve label",
+		})
+		return
+	}
+
+	if label == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "label not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LabelResponse{Data: *label})
+}
+
+// UpdateLabel handles updating an existing label.
+// @Summary Update label
+// @Description Update a class of the label taxonomy
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param id path string true "Label ID"
+// @Param label body models.UpdateLabelRequest true "Updated label data"
+// @Success 200 {object} models.LabelResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/labels/{id} [put]
+func (h *Handler) UpdateLabel(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid update label request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	label, err := h.repo.UpdateLabel(ctx, id, &req)
+	if err != nil {
+		if writeLabelError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to update label", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to update label",
+		})
+		return
+	}
+
+	if label == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "label not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LabelResponse{Data: *label})
+}
+
+// DeleteLabel handles removing a label from the taxonomy.
+// @Summary Delete label
+// @Description Delete a class that no annotation or child class refers to
+// @Tags labels
+// @Produce json
+// @Param id path string true "Label ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/labels/{id} [delete]
+func (h *Handler) DeleteLabel(c *gin.Context) {
+	id := c.Param("id")
+	ctx := context.Background()
+
+	err := h.repo.DeleteLabel(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrLabelNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "not_found",
+				Message: "label not found",
+			})
+			return
+		case errors.Is(err, database.ErrLabelInUse):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "conflict",
+				Message: "label is still used by annotations, tracks or child labels",
+			})
+			return
+		}
+
+		h.logger.Error("Failed to delete label", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to delete label",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkConformingLabel checks that an annotation with the given geometry and
+// attributes conforms to its label. Annotations without a label always conform.
+func (h *Handler) checkConformingLabel(ctx context.Context, refs annotationRefs, labelID *string, geometry models.Geometry, attributes models.Attributes) *requestError {
+	if labelID == nil || *labelID == "" {
+		return nil
+	}
+
+	label, err := refs.GetLabel(ctx, *labelID)
+	if err != nil {
+		h.logger.Error("Failed to get label", zap.String("id", *labelID), zap.Error(err))
+		return newRequestError(http.StatusInternalServerError, "internal_error", "failed to retrieve label")
+	}
+
+	if label == nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", "label not found")
+	}
+
+	if err := label.Check(geometry, attributes); err != nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+var testLabel = &models.Label{
+	ID:            "label-car",
+	Name:          "car",
+	Color:         "#ff0000",
+	GeometryTypes: []models.GeometryType{models.GeometryCuboid},
+	Attributes: []models.AttributeDefinition{
+		{Name: "occluded", Type: models.AttributeBool, Required: true},
+		{Name: "truncation", Type: models.AttributeEnum, Options: []string{"none", "partial", "full"}},
+	},
+}
+
+const testCuboid = `{"type": "cuboid", "center": {"x": 1, "y": 2, "z": 3}, "size": {"length": 4.5, "width": 1.8, "height": 1.5}, "yaw": 0}`
+
+func TestCreateLabel_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreateLabel", mock.Anything, mock.MatchedBy(func(req *models.CreateLabelRequest) bool {
+		return req.Name == "car" && len(req.Attributes) == 2
+	})).Return(testLabel, nil)
+
+	body := `{"name": "car", "color": "#ff0000", "geometry_types": ["cuboid"], "attributes": [
+		{"name": "occluded", "type": "bool", "required": true},
+		{"name": "truncation", "type": "enum", "options": ["none", "partial", "full"]}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/labels", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.LabelResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, testLabel.ID, response.Data.ID)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateLabel_InvalidRequest(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	bodies := []string{
+		`{"color": "#ff0000"}`,
+		`{"name": "car", "color": "red"}`,
+		`{"name": "car", "color": "#ff0000", "geometry_types": ["sphere"]}`,
+		`{"name": "car", "color": "#ff0000", "attributes": [{"name": "truncation", "type": "enum"}]}`,
+	}
+
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/labels", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		engine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	mockRepo.AssertNotCalled(t, "CreateLabel")
+}
+
+func TestCreateLabel_Duplicate(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreateLabel", mock.Anything, mock.Anything).Return(nil, database.ErrLabelExists)
+
+	body := `{"name": "Car", "color": "#ff0000"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/labels", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestGetAllLabels_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{*testLabel}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LabelsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 1)
+}
+
+func TestUpdateLabel_Cycle(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("UpdateLabel", mock.Anything, "label-vehicle", mock.Anything).Return(nil, database.ErrLabelCycle)
+
+	body := `{"parent_id": "label-car"}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/labels/label-vehicle", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteLabel_InUse(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("DeleteLabel", mock.Anything, testLabel.ID).Return(database.ErrLabelInUse)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/labels/label-car", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreate_ConformingLabel(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	expectedAnnotation := &models.Annotation{ID: "test-uuid", PointCloudID: testPointCloud.ID, LabelID: &testLabel.ID}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetLabel", mock.Anything, testLabel.ID).Return(testLabel, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.Anything).Return(expectedAnnotation, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	body := `{"x": 1, "y": 2, "z": 3, "title": "Car", "label_id": "label-car", "geometry": ` + testCuboid + `,
+		"attributes": {"occluded": false, "truncation": "partial"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreate_NonconformingLabel(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetLabel", mock.Anything, testLabel.ID).Return(testLabel, nil)
+	mockRepo.On("GetLabel", mock.Anything, "missing").Return(nil, nil)
+
+	bodies := []string{
+		// Points are not allowed for cars
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "label_id": "label-car", "attributes": {"occluded": true}}`,
+		// Missing required attribute
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "label_id": "label-car", "geometry": ` + testCuboid + `}`,
+		// Wrong attribute type
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "label_id": "label-car", "geometry": ` + testCuboid + `, "attributes": {"occluded": "yes"}}`,
+		// Unknown enum value
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "label_id": "label-car", "geometry": ` + testCuboid + `, "attributes": {"occluded": true, "truncation": "most"}}`,
+		// Unknown label
+		`{"x": 1, "y": 2, "z": 3, "title": "Car", "label_id": "missing"}`,
+	}
+
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		engine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	mockRepo.AssertNotCalled(t, "Create")
+}
+
+func TestUpdate_NonconformingLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"dropped required attribute", fmt.Errorf("%w: attribute occluded is required", database.ErrNonconforming)},
+		{"unknown label", database.ErrLabelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, mockCache, engine := setupTestHandler()
+
+			mockRepo.On("Update", mock.Anything, testPointCloud.ID, "test-id", mock.Anything, []int64(nil)).Return(nil, tt.err)
+
+			body := `{"attributes": {"truncation": "none"}}`
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/annotations/test-id", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.err.Error())
+			mockRepo.AssertNotCalled(t, "GetByID")
+			mockCache.AssertNotCalled(t, "Set")
+		})
+	}
+}
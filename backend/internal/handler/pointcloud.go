@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// CreatePointCloud handles registering a new point cloud.
+// @Summary Create point cloud
+// @Description Register a new point cloud scene
+// @Tags pointclouds
+// @Accept json
+// @Produce json
+// @Param pointcloud body models.CreatePointCloudRequest true "Point cloud data, optionally a frame of a sequence"
+// @Success 201 {object} models.PointCloudResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds [post]
+func (h *Handler) CreatePointCloud(c *gin.Context) {
+	var req models.CreatePointCloudRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid create point cloud request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	pointCloud, err := h.repo.CreatePointCloud(ctx, &req)
+	if err != nil {
+		if writeFrameError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to create point cloud", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to create point cloud",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.PointCloudResponse{Data: *pointCloud})
+}
+
+// GetAllPointClouds handles retrieving all point clouds.
+// @Summary Get all point clouds
+// @Description Retrieve all registered point cloud scenes
+// @Tags pointclouds
+// @Produce json
+// @Success 200 {object} models.PointCloudsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds [get]
+func (h *Handler) GetAllPointClouds(c *gin.Context) {
+	ctx := context.Background()
+
+	pointClouds, err := h.repo.GetAllPointClouds(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get point clouds", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve point clouds",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PointCloudsResponse{Data: pointClouds})
+}
+
+// GetPointCloud handles retrieving a single point cloud by ID.
+// @Summary Get point cloud by ID
+// @Description Retrieve a specific point cloud scene by its ID
+// @Tags pointclouds
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Success 200 {object} models.PointCloudResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id} [get]
+func (h *Handler) GetPointCloud(c *gin.Context) {
+	id := c.Param("id")
+	ctx := context.Background()
+
+	pointCloud, err := h.repo.GetPointCloud(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get point cloud", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve point cloud",
+		})
+		return
+	}
+
+	if pointCloud == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "point cloud not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PointCloudResponse{Data: *pointCloud})
+}
+
+// UpdatePointCloud handles updating an existing point cloud.
+// @Summary Update point cloud
+// @Description Update an existing point cloud scene
+// @Tags pointclouds
+// @Accept json
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param pointcloud body models.UpdatePointCloudRequest true "Updated point cloud data"
+// @Success 200 {object} models.PointCloudResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id} [put]
+func (h *Handler) UpdatePointCloud(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdatePointCloudRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid update point cloud request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	pointCloud, err := h.repo.UpdatePointCloud(ctx, id, &req)
+	if err != nil {
+		if writeFrameError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to update point cloud", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to update point cloud",
+		})
+		return
+	}
+
+	if pointCloud == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "point cloud not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PointCloudResponse{Data: *pointCloud})
+}
+
+// DeletePointCloud handles deleting a point cloud together with its annotations.
+// @Summary Delete point cloud
+// @Description Delete a point cloud scene and all of its annotations
+// @Tags pointclouds
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id} [delete]
+func (h *Handler) DeletePointCloud(c *gin.Context) {
+	id := c.Param("id")
+	ctx := context.Background()
+
+	err := h.repo.DeletePointCloud(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrPointCloudNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "not_found",
+				Message: "point cloud not found",
+			})
+			return
+		}
+
+		h.logger.Error("Failed to delete point cloud", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to delete point cloud",
+		})
+		return
+	}
+
+	// Drop every cached annotation of the scene
+	_ = h.cache.InvalidatePointCloud(ctx, id)
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestCreatePointCloud_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreatePointCloud", mock.Anything, mock.MatchedBy(func(req *models.CreatePointCloudRequest) bool {
+		return req.Name == testPointCloud.Name && req.SourceURL == testPointCloud.SourceURL
+	})).Return(testPointCloud, nil)
+
+	body := `{"name": "lion_takanawa", "source_url": "/potree/pointclouds/lion_takanawa/cloud.js"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.PointCloudResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, testPointCloud.ID, response.Data.ID)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreatePointCloud_InvalidRequest(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	// Missing source URL
+	body := `{"name": "lion_takanawa"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "CreatePointCloud")
+}
+
+func TestGetAllPointClouds_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetAllPointClouds", mock.Anything).Return([]models.PointCloud{*testPointCloud}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PointCloudsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 1)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetPointCloud_NotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, "nonexistent").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/nonexistent", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdatePointCloud_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	updated := *testPointCloud
+	updated.Name = "renamed"
+
+	mockRepo.On("UpdatePointCloud", mock.Anything, testPointCloud.ID, mock.Anything).Return(&updated, nil)
+
+	body := `{"name": "renamed"}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PointCloudResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", response.Data.Name)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeletePointCloud_Success(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	mockRepo.On("DeletePointCloud", mock.Anything, testPointCloud.ID).Return(nil)
+	mockCache.On("InvalidatePointCloud", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/pointclouds/pc-1", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestDeletePointCloud_NotFound(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	mockRepo.On("DeletePointCloud", mock.Anything, "nonexistent").Return(database.ErrPointCloudNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/pointclouds/nonexistent", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockCache.AssertNotCalled(t, "InvalidatePointCloud")
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// parseAnnotationQuery builds the annotation listing query from the request's
+// query parameters: limit, cursor, sort, title_prefix, the created/updated
+// time range bounds (RFC 3339), tags with tag_mode, attribute filters and the
+// as_of time of point-in-time reads.
+func parseAnnotationQuery(c *gin.Context) (*models.AnnotationQuery, error) {
+	q := models.NewAnnotationQuery()
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("limit must be an integer")
+		}
+		q.Limit = n
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		if err := q.SetSort(sort); err != nil {
+			return nil, err
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := models.DecodeAnnotationCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.After = after
+	}
+
+	q.TitlePrefix = c.Query("title_prefix")
+
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_after", &q.CreatedAfter},
+		{"created_before", &q.CreatedBefore},
+		{"updated_after", &q.UpdatedAfter},
+		{"updated_before", &q.UpdatedBefore},
+		{"as_of", &q.AsOf},
+	}
+	for _, param := range timeParams {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", param.name)
+		}
+		*param.target = &t
+	}
+
+	if tags := c.Query("tags"); tags != "" {
+		seen := make(map[string]bool)
+		for _, tag := range strings.Split(tags, ",") {
+			tag = strings.TrimSpace(tag)
+			if !seen[tag] {
+				seen[tag] = true
+				q.Tags = append(q.Tags, tag)
+			}
+		}
+	}
+	if mode := c.Query("tag_mode"); mode != "" {
+		if len(q.Tags) == 0 {
+			return nil, fmt.Errorf("tag_mode requires tags")
+		}
+		q.TagMode = mode
+	}
+
+	filters, err := parseAttributeFilters(c)
+	if err != nil {
+		return nil, err
+	}
+	q.Attributes = filters
+
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// parseAttributeFilters collects the attr.<name><op><value> filters. They are
+// read from the raw query string because operators such as ">=" do not
+// survive the usual key=value parsing.
+func parseAttributeFilters(c *gin.Context) ([]models.AttributeFilter, error) {
+	var filters []models.AttributeFilter
+
+	for _, part := range strings.Split(c.Request.URL.RawQuery, "&") {
+		expr, err := url.QueryUnescape(part)
+		if err != nil || !models.IsAttributeFilter(expr) {
+			continue
+		}
+
+		filter, err := models.ParseAttributeFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// parseSpatialQuery builds the region restriction from the bbox, near, radius
+// and k query parameters. It returns nil when none of them is present.
+func parseSpatialQuery(c *gin.Context) (*models.SpatialQuery, error) {
+	bbox, near, radius, k := c.Query("bbox"), c.Query("near"), c.Query("radius"), c.Query("k")
+	if bbox == "" && near == "" && radius == "" && k == "" {
+		return nil, nil
+	}
+
+	q := &models.SpatialQuery{}
+
+	if bbox != "" {
+		box, err := models.ParseBoundingBox(bbox)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bbox: %w", err)
+		}
+		q.BBox = &box
+	}
+
+	if near != "" {
+		center, err := models.ParseVec3(near)
+		if err != nil {
+			return nil, fmt.Errorf("invalid near: %w", err)
+		}
+		q.Near = &center
+	}
+
+	if radius != "" {
+		r, err := strconv.ParseFloat(radius, 64)
+		if err != nil {
+			return nil, fmt.Errorf("radius must be a number")
+		}
+		q.Radius = &r
+	}
+
+	if k != "" {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("k must be an integer")
+		}
+		q.K = n
+	}
+
+	if c.Query("cursor") != "" || c.Query("sort") != "" {
+		return nil, fmt.Errorf("cursor and sort cannot be combined with spatial queries")
+	}
+
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// labelArrayContentType is the media type of segmentations encoded as one
+// little-endian uint16 class per point.
+const labelArrayContentType = "application/octet-stream"
+
+// GetSegmentation handles retrieving the per-point labels of a point cloud.
+// @Summary Get segmentation
+// @Description Retrieve the class of every point, run-length encoded as JSON or, with Accept: application/octet-stream, as one little-endian uint16 per point
+// @Tags segmentation
+// @Produce json
+// @Produce octet-stream
+// @Param id path string true "Point cloud ID"
+// @Success 200 {object} models.SegmentationResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/segmentation [get]
+func (h *Handler) GetSegmentation(c *gin.Context) {
+	segmentation, ok := h.requireSegmentation(c)
+	if !ok {
+		return
+	}
+
+	varyAccept(c)
+	if c.NegotiateFormat(gin.MIMEJSON, labelArrayContentType) == labelArrayContentType {
+		c.Header("Content-Type", labelArrayContentType)
+		c.Header("Content-Length", strconv.Itoa(2*segmentation.PointCount))
+		c.Status(http.StatusOK)
+		if err := writeLabelArray(c.Writer, segmentation.Runs); err != nil {
+			h.logger.Warn("Failed to stream segmentation", zap.String("point_cloud_id", segmentation.PointCloudID), zap.Error(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SegmentationResponse{Data: *segmentation})
+}
+
+// PutSegmentation handles replacing the per-point labels of a point cloud.
+// @Summary Replace segmentation
+// @Description Create or replace the segmentation of a point cloud; without runs all points are unlabeled
+// @Tags segmentation
+// @Accept json
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param segmentation body models.PutSegmentationRequest true "Segmentation data"
+// @Success 200 {object} models.SegmentationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/segmentation [put]
+func (h *Handler) PutSegmentation(c *gin.Context) {
+	pointCloudID := c.Param("id")
+
+	var req models.PutSegmentationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid put segmentation request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	segmentation := req.Segmentation(pointCloudID)
+	if err := h.repo.PutSegmentation(ctx, segmentation); err != nil {
+		h.logger.Error("Failed to store segmentation", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to store segmentation",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SegmentationResponse{Data: *segmentation})
+}
+
+// PatchSegmentation handles sparse changes to the per-point labels of a point cloud.
+// @Summary Patch segmentation
+// @Description Assign classes to individual points; when a point is listed more than once the last change wins
+// @Tags segmentation
+// @Accept json
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param patch body models.SegmentationPatch true "Class changes"
+// @Success 200 {object} models.SegmentationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/segmentation [patch]
+func (h *Handler) PatchSegmentation(c *gin.Context) {
+	pointCloudID := c.Param("id")
+
+	var patch models.SegmentationPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		h.logger.Warn("Invalid patch segmentation request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := patch.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	segmentation, err := h.repo.PatchSegmentation(ctx, pointCloudID, &patch)
+	if err != nil {
+		if errors.Is(err, models.ErrPointIndexOutOfRange) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		h.logger.Error("Failed to patch segmentation", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to patch segmentation",
+		})
+		return
+	}
+
+	if segmentation == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "segmentation not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SegmentationResponse{Data: *segmentation})
+}
+
+// GetSegmentationHistogram handles counting the points of every class.
+// @Summary Get segmentation histogram
+// @Description Count the points of every class present in the segmentation, ordered by class
+// @Tags segmentation
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Success 200 {object} models.HistogramResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/segmentation/histogram [get]
+func (h *Handler) GetSegmentationHistogram(c *gin.Context) {
+	segmentation, ok := h.requireSegmentation(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.HistogramResponse{Data: segmentation.Histogram()})
+}
+
+// requireSegmentation loads the segmentation of the requested point cloud,
+// writing a 404 or 500 response and returning false when it cannot.
+func (h *Handler) requireSegmentation(c *gin.Context) (*models.Segmentation, bool) {
+	pointCloudID := c.Param("id")
+	ctx := context.Background()
+
+	segmentation, err := h.repo.GetSegmentation(ctx, pointCloudID)
+	if err != nil {
+		h.logger.Error("Failed to get segmentation", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve segmentation",
+		})
+		return nil, false
+	}
+
+	if segmentation == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "segmentation not found",
+		})
+		return nil, false
+	}
+
+	return segmentation, true
+}
+
+// writeLabelArray expands runs into one little-endian uint16 per point without
+// materializing the whole array.
+func writeLabelArray(w http.ResponseWriter, runs []models.Run) error {
+	buf := bufio.NewWriterSize(w, 64*1024)
+	var class [2]byte
+	for _, run := range runs {
+		binary.LittleEndian.PutUint16(class[:], run.Class)
+		for i := uint32(0); i < run.Length; i++ {
+			if _, err := buf.Write(class[:]); err != nil {
+				return err
+			}
+		}
+	}
+	return buf.Flush()
+}
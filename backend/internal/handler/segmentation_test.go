@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+var testSegmentation = &models.Segmentation{
+	PointCloudID: testPointCloud.ID,
+	PointCount:   5,
+	Runs:         []models.Run{{Class: 0, Length: 2}, {Class: 3, Length: 1}, {Class: 0, Length: 2}},
+}
+
+func TestGetSegmentation_JSON(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetSegmentation", mock.Anything, testPointCloud.ID).Return(testSegmentation, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/segmentation", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	var response models.SegmentationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, testSegmentation.Runs, response.Data.Runs)
+}
+
+func TestGetSegmentation_LabelArray(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetSegmentation", mock.Anything, testPointCloud.ID).Return(testSegmentation, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/segmentation", nil)
+	req.Header.Set("Accept", "application/octet-stream")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+	assert.Equal(t, []byte{0, 0, 0, 0, 3, 0, 0, 0, 0, 0}, w.Body.Bytes())
+}
+
+func TestGetSegmentation_NotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetSegmentation", mock.Anything, "missing").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/missing/segmentation", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPutSegmentation_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("PutSegmentation", mock.Anything, mock.MatchedBy(func(s *models.Segmentation) bool {
+		return s.PointCloudID == testPointCloud.ID && s.PointCount == 4 && len(s.Runs) == 2
+	})).Return(nil)
+
+	body := `{"point_count": 4, "runs": [[1, 1], [1, 1], [2, 2]]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/pointclouds/pc-1/segmentation", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPutSegmentation_RunsMismatch(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	body := `{"point_count": 4, "runs": [[1, 3]]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/pointclouds/pc-1/segmentation", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "PutSegmentation")
+}
+
+func TestPatchSegmentation_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	patched := &models.Segmentation{PointCloudID: testPointCloud.ID, PointCount: 5, Runs: []models.Run{{Class: 3, Length: 5}}}
+	patch := &models.SegmentationPatch{Changes: []models.ClassChange{{Class: 3, Indices: []int{0, 1, 3, 4}}}}
+	mockRepo.On("PatchSegmentation", mock.Anything, testPointCloud.ID, patch).Return(patched, nil)
+
+	body := `{"changes": [{"class": 3, "indices": [0, 1, 3, 4]}]}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/segmentation", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatchSegmentation_OutOfRange(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("PatchSegmentation", mock.Anything, testPointCloud.ID, mock.Anything).
+		Return(nil, fmt.Errorf("%w: %d", models.ErrPointIndexOutOfRange, 5))
+
+	body := `{"changes": [{"class": 3, "indices": [5]}]}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/pointclouds/pc-1/segmentation", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetSegmentationHistogram(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetSegmentation", mock.Anything, testPointCloud.ID).Return(testSegmentation, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/segmentation/histogram", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.HistogramResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ClassCount{{Class: 0, Count: 4}, {Class: 3, Count: 1}}, response.Data)
+}
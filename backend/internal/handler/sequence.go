@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// writeFrameError writes the response for errors of point cloud writes that
+// place the point cloud in a sequence, returning false for unexpected errors
+// the caller has to report itself.
+func writeFrameError(c *gin.Context, err error) bool {
+	if errors.Is(err, database.ErrSequenceNotFound) || errors.Is(err, database.ErrFrameWithoutSequence) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return true
+	}
+	return false
+}
+
+// CreateSequence handles creating a sequence of frames.
+// @Summary Create sequence
+// @Description Create an empty sequence; frames are added by registering point clouds with its sequence_id
+// @Tags sequences
+// @Accept json
+// @Produce json
+// @Param sequence body models.CreateSequenceRequest true "Sequence data"
+// @Success 201 {object} models.SequenceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/sequences [post]
+func (h *Handler) CreateSequence(c *gin.Context) {
+	var req models.CreateSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid create sequence request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	sequence, err := h.repo.CreateSequence(ctx, &req)
+	if err != nil {
+		h.logger.Error("Failed to create sequence", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to create sequence",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SequenceResponse{Data: *sequence})
+}
+
+// GetAllSequences handles retrieving all sequences.
+// @Summary Get all sequences
+// @Description Retrieve all sequences of frames
+// @Tags sequences
+// @Produce json
+// @Success 200 {object} models.SequencesResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/sequences [get]
+func (h *Handler) GetAllSequences(c *gin.Context) {
+	ctx := context.Background()
+
+	sequences, err := h.repo.GetAllSequences(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get sequences", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve sequences",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SequencesResponse{Data: sequences})
+}
+
+// GetSequence handles retrieving a single sequence by ID.
+// @Summary Get sequence by ID
+// @Description Retrieve a specific sequence by its ID
+// @Tags sequences
+// @Produce json
+// @Param id path string true "Sequence ID"
+// @Success 200 {object} models.SequenceResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/sequences/{id} [get]
+func (h *Handler) GetSequence(c *gin.Context) {
+	ctx := context.Background()
+
+	sequence, ok := h.requireSequence(ctx, c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SequenceResponse{Data: *sequence})
+}
+
+// UpdateSequence handles updating an existing sequence.
+// @Summary Update sequence
+// @Description Update the name or description of a sequence
+// @Tags sequences
+// @Accept json
+// @Produce json
+// @Param id path string true "Sequence ID"
+// @Param sequence body models.UpdateSequenceRequest true "Updated sequence data"
+// @Success 200 {object} models.SequenceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/sequences/{id} [put]
+func (h *Handler) UpdateSequence(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid update sequence request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	sequence, err := h.repo.UpdateSequence(ctx, id, &req)
+	if err != nil {
+		h.logger.Error("Failed to update sequence", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to update sequence",
+		})
+		return
+	}
+
+	if sequence == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "sequence not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SequenceResponse{Data: *sequence})
+}
+
+// DeleteSequence handles deleting a sequence together with its tracks.
+// @Summary Delete sequence
+// @Description Delete a sequence and its tracks; its frames are kept as standalone point clouds
+// @Tags sequences
+// @Produce json
+// @Param id path string true "Sequence ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/sequences/{id} [delete]
+func (h *Handler) DeleteSequence(c *gin.Context) {
+	id := c.Param("id")
+	// Unlinking the annotations of the tracks is recorded in their history
+	ctx := writeContext(c)
+
+	// Remember the frames, whose annotations lose their tracks
+	frames, err := h.repo.GetFrames(ctx, id)
+	if err == nil {
+		err = h.repo.DeleteSequence(ctx, id)
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrSequenceNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "not_found",
+				Message: "sequence not found",
+			})
+			return
+		}
+
+		h.logger.Error("Failed to delete sequence", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to delete sequence",
+		})
+		return
+	}
+
+	ctx = committed(ctx)
+	for _, frame := range frames {
+		_ = h.cache.InvalidatePointCloud(ctx, frame.ID)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFrames handles retrieving the frames of a sequence.
+// @Summary Get frames
+// @Description Retrieve the point clouds of a sequence ordered by frame timestamp
+// @Tags sequences
+// @Produce json
+// @Param id path string true "Sequence ID"
+// @Success 200 {object} models.PointCloudsResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/sequences/{id}/frames [get]
+func (h *Handler) GetFrames(c *gin.Context) {
+	id := c.Param("id")
+	ctx := context.Background()
+
+	if _, ok := h.requireSequence(ctx, c, id); !ok {
+		return
+	}
+
+	frames, err := h.repo.GetFrames(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get frames", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve frames",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PointCloudsResponse{Data: frames})
+}
+
+// requireSequence loads a sequence, writing a 404 or 500 response and
+// returning false when it cannot.
+func (h *Handler) requireSequence(ctx context.Context, c *gin.Context, id string) (*models.Sequence, bool) {
+	sequence, err := h.repo.GetSequence(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get sequence", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve sequence",
+		})
+		return nil, false
+	}
+
+	if sequence == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "sequence not found",
+		})
+		return nil, false
+	}
+
+	return sequence, true
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+var testSequence = &models.Sequence{ID: "seq-1", Name: "drive_0001"}
+
+func TestCreatePointCloud_Frame(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreatePointCloud", mock.Anything, mock.MatchedBy(func(req *models.CreatePointCloudRequest) bool {
+		return req.SequenceID != nil && *req.SequenceID == testSequence.ID && req.FrameTimestamp != nil
+	})).Return(testPointCloud, nil)
+
+	body := `{"name": "frame_0", "source_url": "/potree/pointclouds/frame_0/cloud.js", "sequence_id": "seq-1", "frame_timestamp": "2024-05-01T12:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreatePointCloud_FrameWithoutTimestamp(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	body := `{"name": "frame_0", "source_url": "/potree/pointclouds/frame_0/cloud.js", "sequence_id": "seq-1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "CreatePointCloud")
+}
+
+func TestCreatePointCloud_UnknownSequence(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreatePointCloud", mock.Anything, mock.Anything).Return(nil, database.ErrSequenceNotFound)
+
+	body := `{"name": "frame_0", "source_url": "/potree/pointclouds/frame_0/cloud.js", "sequence_id": "missing", "frame_timestamp": "2024-05-01T12:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateSequence_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreateSequence", mock.Anything, &models.CreateSequenceRequest{Name: "drive_0001"}).Return(testSequence, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sequences", bytes.NewBufferString(`{"name": "drive_0001"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetFrames_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(100 * time.Millisecond)
+	frames := []models.PointCloud{
+		{ID: "pc-1", SequenceID: &testSequence.ID, FrameTimestamp: &first},
+		{ID: "pc-2", SequenceID: &testSequence.ID, FrameTimestamp: &second},
+	}
+
+	mockRepo.On("GetSequence", mock.Anything, testSequence.ID).Return(testSequence, nil)
+	mockRepo.On("GetFrames", mock.Anything, testSequence.ID).Return(frames, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sequences/seq-1/frames", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PointCloudsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.Equal(t, "pc-2", response.Data[1].ID)
+}
+
+func TestDeleteSequence_InvalidatesFrames(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	frames := []models.PointCloud{{ID: "pc-1"}, {ID: "pc-2"}}
+
+	mockRepo.On("GetFrames", mock.Anything, testSequence.ID).Return(frames, nil)
+	mockRepo.On("DeleteSequence", mock.Anything, testSequence.ID).Return(nil)
+	mockCache.On("InvalidatePointCloud", mock.Anything, "pc-1").Return(nil)
+	mockCache.On("InvalidatePointCloud", mock.Anything, "pc-2").Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/sequences/seq-1", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockCache.AssertExpectations(t)
+}
+
+func TestDeleteSequence_NotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetFrames", mock.Anything, "missing").Return([]models.PointCloud{}, nil)
+	mockRepo.On("DeleteSequence", mock.Anything, "missing").Return(database.ErrSequenceNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/sequences/missing", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// ndjsonContentType is the media type of annotation listings streamed as
+// newline-delimited JSON.
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonFlushRows is how many streamed annotations are written between
+// flushes to the client.
+const ndjsonFlushRows = 500
+
+// streamAll answers an annotation listing with every matching annotation as
+// newline-delimited JSON, written as the rows arrive from the database. The
+// limit is ignored and there is no next cursor; the cache is bypassed. The
+// query runs under the request context, so it stops when the client goes away.
+func (h *Handler) streamAll(c *gin.Context, pointCloudID string, query *models.AnnotationQuery) {
+	ctx := c.Request.Context()
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	// The status is committed with the first row, so that a query failing
+	// up front is still answered with an error
+	rows := 0
+	start := func() {
+		c.Header("Content-Type", ndjsonContentType)
+		c.Status(http.StatusOK)
+	}
+
+	encoder := json.NewEncoder(c.Writer)
+	err := h.repo.StreamAll(ctx, pointCloudID, query, func(annotation *models.Annotation) error {
+		if rows == 0 {
+			start()
+		}
+		rows++
+
+		if err := encoder.Encode(annotation); err != nil {
+			return err
+		}
+		if rows%ndjsonFlushRows == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil && rows == 0:
+		h.logger.Error("Failed to stream annotations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve annotations",
+		})
+	case err != nil:
+		// Too late for an error response; the client sees the stream cut short
+		h.logger.Warn("Annotation stream ended early", zap.String("point_cloud_id", pointCloudID), zap.Int("rows", rows), zap.Error(err))
+	case rows == 0:
+		start()
+	}
+}
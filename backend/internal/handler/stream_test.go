@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestGetAll_NDJSON(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	annotations := make([]models.Annotation, ndjsonFlushRows+1)
+	for i := range annotations {
+		annotations[i] = models.Annotation{ID: fmt.Sprintf("a-%d", i), PointCloudID: testPointCloud.ID, Geometry: models.PointGeometry()}
+	}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.MatchedBy(func(q *models.AnnotationQuery) bool {
+		return q.TitlePrefix == "Car"
+	})).Return(annotations, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?title_prefix=Car", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ndjsonContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	lines := 0
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		var annotation models.Annotation
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &annotation))
+		assert.Equal(t, annotations[lines].ID, annotation.ID)
+		lines++
+	}
+	assert.Equal(t, len(annotations), lines)
+
+	// Streams bypass the cache
+	mockCache.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetAll_NDJSONEmpty(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ndjsonContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestGetAll_NDJSONQueryFails(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(nil, errors.New("database error"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+	"github.com/pointcloud-annotator/backend/internal/tabular"
+)
+
+// attributeColumnPrefix names the attribute columns of tabular exports like
+// attribute filters, e.g. attr.occluded.
+const attributeColumnPrefix = "attr."
+
+// tableColumns are the columns of tabular exports; a column per attribute
+// follows them.
+var tableColumns = []tabular.Column{
+	{Name: "id", Type: tabular.String},
+	{Name: "point_cloud_id", Type: tabular.String},
+	{Name: "x", Type: tabular.Double},
+	{Name: "y", Type: tabular.Double},
+	{Name: "z", Type: tabular.Double},
+	{Name: "title", Type: tabular.String},
+	{Name: "description", Type: tabular.String},
+	{Name: "geometry_type", Type: tabular.String},
+	{Name: "geometry", Type: tabular.String},
+	{Name: "label_id", Type: tabular.String},
+	{Name: "label", Type: tabular.String},
+	{Name: "track_id", Type: tabular.String},
+	{Name: "tags", Type: tabular.String},
+	{Name: "version", Type: tabular.Int64},
+	{Name: "created_at", Type: tabular.Timestamp},
+	{Name: "updated_at", Type: tabular.Timestamp},
+}
+
+// writeCSV writes the annotations as CSV.
+func writeCSV(w io.Writer, src *exportSource) error {
+	return writeTable(w, src, func(w io.Writer, columns []tabular.Column) (tabular.Writer, error) {
+		return tabular.NewCSVWriter(w, columns)
+	})
+}
+
+// writeParquet writes the annotations as an Apache Parquet file.
+func writeParquet(w io.Writer, src *exportSource) error {
+	return writeTable(w, src, func(w io.Writer, columns []tabular.Column) (tabular.Writer, error) {
+		return tabular.NewParquetWriter(w, columns)
+	})
+}
+
+// writeTable writes the annotations as a table of one row per annotation,
+// with the attributes flattened into columns. The geometry is its JSON and
+// tags are comma-separated.
+func writeTable(w io.Writer, src *exportSource, newWriter func(io.Writer, []tabular.Column) (tabular.Writer, error)) error {
+	columns := append([]tabular.Column{}, tableColumns...)
+	for _, attribute := range src.attributes {
+		column := tabular.Column{Name: attributeColumnPrefix + attribute.Name, Type: tabular.String}
+		switch attribute.Type {
+		case models.AttributeBool:
+			column.Type = tabular.Bool
+		case models.AttributeNumber:
+			column.Type = tabular.Double
+		}
+		columns = append(columns, column)
+	}
+
+	table, err := newWriter(w, columns)
+	if err != nil {
+		return err
+	}
+
+	row := make([]any, len(columns))
+	err = src.each(func(a *models.Annotation) error {
+		geometry, err := json.Marshal(a.Geometry)
+		if err != nil {
+			return err
+		}
+
+		var label any
+		if a.LabelID != nil {
+			label = src.labels.name(a)
+		}
+
+		copy(row, []any{
+			a.ID, a.PointCloudID, a.X, a.Y, a.Z, a.Title, a.Description,
+			string(a.Geometry.Type), string(geometry),
+			optional(a.LabelID), label, optional(a.TrackID), strings.Join(a.Tags, ","),
+			a.Version, a.CreatedAt, a.UpdatedAt,
+		})
+		for i, attribute := range src.attributes {
+			row[len(tableColumns)+i] = attributeValue(a.Attributes[attribute.Name], columns[len(tableColumns)+i].Type)
+		}
+
+		return table.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+
+	return table.Close()
+}
+
+// optional returns a string pointer's value, or nil.
+func optional(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// attributeValue returns an attribute value for a column of the given type:
+// the value itself if it has the column's type, its text in string columns and
+// otherwise null.
+func attributeValue(value any, columnType tabular.ColumnType) any {
+	switch v := value.(type) {
+	case bool:
+		if columnType == tabular.Bool {
+			return v
+		}
+		if columnType == tabular.String {
+			return strconv.FormatBool(v)
+		}
+	case float64:
+		if columnType == tabular.Double {
+			return v
+		}
+		if columnType == tabular.String {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case string:
+		if columnType == tabular.String {
+			return v
+		}
+	}
+	return nil
+}
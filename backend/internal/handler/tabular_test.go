@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func tableAnnotations() []models.Annotation {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []models.Annotation{
+		{
+			ID: "a-1", PointCloudID: testPointCloud.ID, X: 1, Y: 2, Z: 3, Title: "Marker",
+			Geometry:   models.PointGeometry(),
+			LabelID:    &testPedestrian.ID,
+			Attributes: models.Attributes{"occluded": true, "confidence": 0.9, "sensor": "lidar-1"},
+			Tags:       []string{"night", "review"},
+			Version:    2, CreatedAt: created, UpdatedAt: created,
+		},
+		{
+			ID: "a-2", PointCloudID: testPointCloud.ID, Title: "Pole",
+			Geometry:   models.PointGeometry(),
+			Attributes: models.Attributes{"sensor": 7.0},
+			Version:    1, CreatedAt: created, UpdatedAt: created,
+		},
+	}
+}
+
+func TestExportAnnotations_CSV(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{testPedestrian}, nil)
+	mockRepo.On("GetAttributeColumns", mock.Anything, testPointCloud.ID).Return([]models.AttributeColumn{
+		{Name: "confidence", Type: models.AttributeNumber},
+		{Name: "occluded", Type: models.AttributeBool},
+		{Name: "sensor", Type: models.AttributeString},
+	}, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(tableAnnotations(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=csv", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	header := records[0]
+	assert.Equal(t, []string{"attr.confidence", "attr.occluded", "attr.sensor"}, header[len(header)-3:])
+
+	row := make(map[string]string)
+	for i, name := range header {
+		row[name] = records[1][i]
+	}
+	assert.Equal(t, "a-1", row["id"])
+	assert.Equal(t, "Pedestrian", row["label"])
+	assert.Equal(t, "night,review", row["tags"])
+	assert.Equal(t, `{"type":"point"}`, row["geometry"])
+	assert.Equal(t, "2024-05-01T12:00:00Z", row["created_at"])
+	assert.Equal(t, "0.9", row["attr.confidence"])
+	assert.Equal(t, "true", row["attr.occluded"])
+
+	// Mixed attribute types fall back to text; missing attributes are empty
+	assert.Equal(t, []string{"", "", "7"}, records[2][len(header)-3:])
+}
+
+func TestExportAnnotations_Parquet(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetAllLabels", mock.Anything).Return([]models.Label{testPedestrian}, nil)
+	mockRepo.On("GetAttributeColumns", mock.Anything, testPointCloud.ID).Return([]models.AttributeColumn{
+		{Name: "occluded", Type: models.AttributeBool},
+	}, nil)
+	mockRepo.On("StreamAll", mock.Anything, testPointCloud.ID, mock.Anything).Return(tableAnnotations(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=parquet", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="pc-1.parquet"`, w.Header().Get("Content-Disposition"))
+
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "PAR1"))
+	assert.True(t, strings.HasSuffix(body, "PAR1"))
+	assert.Contains(t, body, "attr.occluded")
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// AddTags handles attaching tags to an annotation.
+// @Summary Tag annotation
+// @Description Attach tags to an annotation, creating tags that do not exist yet
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param tags body models.AddTagsRequest true "Tags to add"
+// @Success 200 {object} models.AnnotationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId}/tags [post]
+func (h *Handler) AddTags(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
+
+	var req models.AddTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid add tags request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	annotation, err := h.repo.AddTags(ctx, pointCloudID, id, req.Tags)
+	if err != nil {
+		h.logger.Error("Failed to tag annotation", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to tag annotation",
+		})
+		return
+	}
+
+	h.respondTagged(ctx, c, annotation)
+}
+
+// RemoveTag handles detaching a tag from an annotation.
+// @Summary Untag annotation
+// @Description Detach a tag from an annotation
+// @Tags tags
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param tag path string true "Tag name"
+// @Success 200 {object} models.AnnotationResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId}/tags/{tag} [delete]
+func (h *Handler) RemoveTag(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
+	tag := c.Param("tag")
+
+	ctx := context.Background()
+	annotation, err := h.repo.RemoveTag(ctx, pointCloudID, id, tag)
+	if err != nil {
+		h.logger.Error("Failed to untag annotation", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to untag annotation",
+		})
+		return
+	}
+
+	h.respondTagged(ctx, c, annotation)
+}
+
+// respondTagged writes the annotation after a tag change, invalidating the
+// point cloud's cached lists and tag counts.
+func (h *Handler) respondTagged(ctx context.Context, c *gin.Context, annotation *models.Annotation) {
+	if annotation == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "annotation not found",
+		})
+		return
+	}
+
+	h.cacheWritten(ctx, annotation)
+	h.publish(ctx, annotationEvent(ctx, models.EventUpdated, annotation))
+
+	c.Header("ETag", annotationETag(annotation.Version))
+	c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
+}
+
+// GetTags handles listing the tags of a point cloud with their usage counts.
+// @Summary Get tags
+// @Description Retrieve the tags used in a point cloud with the number of annotations carrying each
+// @Tags tags
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Success 200 {object} models.TagsResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/tags [get]
+func (h *Handler) GetTags(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	ctx := context.Background()
+
+	counts, generation, found, _ := h.cache.GetTagCounts(ctx, pointCloudID)
+	if found {
+		h.logger.Debug("Returning cached tag counts", zap.String("point_cloud_id", pointCloudID))
+		c.JSON(http.StatusOK, models.TagsResponse{Data: counts})
+		return
+	}
+
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	counts, err := h.repo.GetTagCounts(ctx, pointCloudID)
+	if err != nil {
+		h.logger.Error("Failed to get tag counts", zap.String("point_cloud_id", pointCloudID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve tags",
+		})
+		return
+	}
+
+	_ = h.cache.SetTagCounts(ctx, pointCloudID, generation, counts)
+
+	c.JSON(http.StatusOK, models.TagsResponse{Data: counts})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestAddTags_Success(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	tagged := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Tags: []string{"batch-7", "needs-review"}}
+
+	mockRepo.On("AddTags", mock.Anything, testPointCloud.ID, "test-id", []string{"needs-review", "batch-7"}).Return(tagged, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	body := `{"tags": ["needs-review", "batch-7"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/tags", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AnnotationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, tagged.Tags, response.Data.Tags)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestAddTags_InvalidTag(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	for _, body := range []string{`{"tags": []}`, `{"tags": ["a,b"]}`, `{"tags": [" padded"]}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/tags", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		engine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	mockRepo.AssertNotCalled(t, "AddTags")
+}
+
+func TestRemoveTag_NotFound(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	mockRepo.On("RemoveTag", mock.Anything, testPointCloud.ID, "missing", "needs-review").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/pointclouds/pc-1/annotations/missing/tags/needs-review", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockCache.AssertNotCalled(t, "Set")
+}
+
+func TestGetTags_CacheMiss(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	counts := []models.TagCount{{Name: "needs-review", Count: 3}, {Name: "batch-7", Count: 1}}
+
+	mockCache.On("GetTagCounts", mock.Anything, testPointCloud.ID).Return(nil, "3", false, nil)
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetTagCounts", mock.Anything, testPointCloud.ID).Return(counts, nil)
+	mockCache.On("SetTagCounts", mock.Anything, testPointCloud.ID, "3", counts).Return(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/tags", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.TagsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, counts, response.Data)
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestGetAll_TagFilter(t *testing.T) {
+	_, _, mockCache, engine := setupTestHandler()
+
+	page := &models.AnnotationPage{Annotations: []models.Annotation{}}
+	matchQuery := mock.MatchedBy(func(q *models.AnnotationQuery) bool {
+		return assert.ObjectsAreEqual([]string{"needs-review", "batch-7"}, q.Tags) && q.TagMode == models.TagModeAll
+	})
+
+	mockCache.On("GetAll", mock.Anything, testPointCloud.ID, matchQuery).Return(page, "3", true, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/annotations?tags=needs-review,batch-7,needs-review&tag_mode=all", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockCache.AssertExpectations(t)
+}
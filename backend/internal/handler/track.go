@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// writeTrackError writes the response for errors of track writes and of
+// annotation writes linking tracks, returning false for unexpected errors the
+// caller has to report itself.
+func writeTrackError(c *gin.Context, err error) bool {
+	if failure := trackError(err); failure != nil {
+		failure.write(c)
+		return true
+	}
+	return false
+}
+
+// trackError returns the response for errors of track writes and of
+// annotation writes linking tracks or labels or moving cuboids, or nil for
+// unexpected errors.
+func trackError(err error) *requestError {
+	switch {
+	case errors.Is(err, database.ErrTrackFrameTaken):
+		return newRequestError(http.StatusConflict, "conflict", "the track already has an annotation in this point cloud")
+	case errors.Is(err, database.ErrTrackNotFound),
+		errors.Is(err, database.ErrSequenceNotFound),
+		errors.Is(err, database.ErrLabelNotFound),
+		errors.Is(err, database.ErrNonconforming),
+		errors.Is(err, models.ErrCuboidOffCenter):
+		return newRequestError(http.StatusBadRequest, "invalid_request", err.Error())
+	}
+	return nil
+}
+
+// CreateTrack handles creating a track in a sequence.
+// @Summary Create track
+// @Description Create an object tracked across the frames of a sequence
+// @Tags tracks
+// @Accept json
+// @Produce json
+// @Param track body models.CreateTrackRequest true "Track data"
+// @Success 201 {object} models.TrackResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/tracks [post]
+func (h *Handler) CreateTrack(c *gin.Context) {
+	var req models.CreateTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid create track request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	track, err := h.repo.CreateTrack(ctx, &req)
+	if err != nil {
+		if writeTrackError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to create track", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to create track",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.TrackResponse{Data: *track})
+}
+
+// GetAllTracks handles retrieving tracks.
+// @Summary Get all tracks
+// @Description Retrieve the tracks of all sequences or of one, oldest first
+// @Tags tracks
+// @Produce json
+// @Param sequence_id query string false "Only tracks of this sequence"
+// @Success 200 {object} models.TracksResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/tracks [get]
+func (h *Handler) GetAllTracks(c *gin.Context) {
+	ctx := context.Background()
+
+	tracks, err := h.repo.GetAllTracks(ctx, c.Query("sequence_id"))
+	if err != nil {
+		h.logger.Error("Failed to get tracks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve tracks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TracksResponse{Data: tracks})
+}
+
+// GetTrack handles retrieving a single track by ID.
+// @Summary Get track by ID
+// @Description Retrieve a specific track by its ID
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID"
+// @Success 200 {object} models.TrackResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/tracks/{id} [get]
+func (h *Handler) GetTrack(c *gin.Context) {
+	ctx := context.Background()
+
+	track, ok := h.requireTrack(ctx, c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TrackResponse{Data: *track})
+}
+
+// UpdateTrack handles updating an existing track.
+// @Summary Update track
+// @Description Update the name or label of a track
+// @Tags tracks
+// @Accept json
+// @Produce json
+// @Param id path string true "Track ID"
+// @Param track body models.UpdateTrackRequest true "Updated track data"
+// @Success 200 {object} models.TrackResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/tracks/{id} [put]
+func (h *Handler) UpdateTrack(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid update track request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	track, err := h.repo.UpdateTrack(ctx, id, &req)
+	if err != nil {
+		if writeTrackError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to update track", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to update track",
+		})
+		return
+	}
+
+	if track == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "track not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TrackResponse{Data: *track})
+}
+
+// DeleteTrack handles deleting a track.
+// @Summary Delete track
+// @Description Delete a track; the annotations observing it are kept without a track
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/tracks/{id} [delete]
+func (h *Handler) DeleteTrack(c *gin.Context) {
+	id := c.Param("id")
+	// Unlinking the observations is recorded in their history
+	ctx := writeContext(c)
+
+	// Remember the observations, which lose their track
+	observations, err := h.repo.GetTrackAnnotations(ctx, id)
+	if err == nil {
+		err = h.repo.DeleteTrack(ctx, id)
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrTrackNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "not_found",
+				Message: "track not found",
+			})
+			return
+		}
+
+		h.logger.Error("Failed to delete track", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to delete track",
+		})
+		return
+	}
+
+	ctx = committed(ctx)
+	updates := make([]*models.AnnotationEvent, len(observations))
+	for i := range observations {
+		observation := &observations[i].Annotation
+		_ = h.cache.InvalidateAll(ctx, observation.PointCloudID)
+
+		observation.TrackID = nil
+		updates[i] = annotationEvent(ctx, models.EventUpdated, observation)
+	}
+	h.publish(ctx, updates...)
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTrackAnnotations handles retrieving the trajectory of a track.
+// @Summary Get track annotations
+// @Description Retrieve the annotations of a track, one per frame, ordered by frame timestamp
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID"
+// @Success 200 {object} models.TrajectoryResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/tracks/{id}/annotations [get]
+func (h *Handler) GetTrackAnnotations(c *gin.Context) {
+	id := c.Param("id")
+	ctx := context.Background()
+
+	if _, ok := h.requireTrack(ctx, c, id); !ok {
+		return
+	}
+
+	observations, err := h.repo.GetTrackAnnotations(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get track annotations", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve track annotations",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TrajectoryResponse{Data: observations})
+}
+
+// requireTrack loads a track, writing a 404 or 500 response and returning
+// false when it cannot.
+func (h *Handler) requireTrack(ctx context.Context, c *gin.Context, id string) (*models.Track, bool) {
+	track, err := h.repo.GetTrack(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get track", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve track",
+		})
+		return nil, false
+	}
+
+	if track == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "track not found",
+		})
+		return nil, false
+	}
+
+	return track, true
+}
+
+// checkTrackInFrame checks that an annotation of the point cloud may observe
+// the track, i.e. that the point cloud is a frame of the track's sequence.
+// Annotations without a track always pass.
+func (h *Handler) checkTrackInFrame(ctx context.Context, refs annotationRefs, trackID *string, pointCloudID string) *requestError {
+	if trackID == nil || *trackID == "" {
+		return nil
+	}
+
+	track, err := refs.GetTrack(ctx, *trackID)
+	if err != nil {
+		h.logger.Error("Failed to get track", zap.String("id", *trackID), zap.Error(err))
+		return newRequestError(http.StatusInternalServerError, "internal_error", "failed to retrieve track")
+	}
+
+	if track == nil {
+		return newRequestError(http.StatusBadRequest, "invalid_request", "track not found")
+	}
+
+	pointCloud, err := refs.GetPointCloud(ctx, pointCloudID)
+	if err != nil {
+		h.logger.Error("Failed to get point cloud", zap.String("id", pointCloudID), zap.Error(err))
+		return newRequestError(http.StatusInternalServerError, "internal_error", "failed to retrieve point cloud")
+	}
+
+	if pointCloud == nil {
+		return newRequestError(http.StatusNotFound, "not_found", "point cloud not found")
+	}
+
+	if pointCloud.SequenceID == nil || *pointCloud.SequenceID != track.SequenceID {
+		return newRequestError(http.StatusBadRequest, "invalid_request", "point cloud is not a frame of the track's sequence")
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+var testTrack = &models.Track{ID: "track-1", SequenceID: "seq-1", Name: "car 1"}
+
+// testFrame is testPointCloud as a frame of the track's sequence.
+func testFrame() *models.PointCloud {
+	frame := *testPointCloud
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	frame.SequenceID, frame.FrameTimestamp = &testTrack.SequenceID, &timestamp
+	return &frame
+}
+
+func TestCreate_WithTrack(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	created := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, TrackID: &testTrack.ID}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testFrame(), nil)
+	mockRepo.On("GetTrack", mock.Anything, testTrack.ID).Return(testTrack, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.MatchedBy(func(req *models.CreateAnnotationRequest) bool {
+		return req.TrackID != nil && *req.TrackID == testTrack.ID
+	})).Return(created, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	body := `{"x": 1, "y": 2, "z": 3, "title": "car", "track_id": "track-1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreate_TrackOfAnotherSequence(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	// testPointCloud is not a frame of any sequence
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetTrack", mock.Anything, testTrack.ID).Return(testTrack, nil)
+
+	body := `{"x": 1, "y": 2, "z": 3, "title": "car", "track_id": "track-1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Create")
+}
+
+func TestCreate_TrackAlreadyObserved(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testFrame(), nil)
+	mockRepo.On("GetTrack", mock.Anything, testTrack.ID).Return(testTrack, nil)
+	mockRepo.On("Create", mock.Anything, testPointCloud.ID, mock.Anything).Return(nil, database.ErrTrackFrameTaken)
+
+	body := `{"x": 1, "y": 2, "z": 3, "title": "car", "track_id": "track-1"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateTrack_UnknownSequence(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("CreateTrack", mock.Anything, mock.Anything).Return(nil, database.ErrSequenceNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tracks", bytes.NewBufferString(`{"sequence_id": "missing"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetAllTracks_BySequence(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetAllTracks", mock.Anything, "seq-1").Return([]models.Track{*testTrack}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks?sequence_id=seq-1", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetTrackAnnotations_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	trajectory := []models.TrackObservation{
+		{Annotation: models.Annotation{ID: "a-1", PointCloudID: "pc-1", X: 1, TrackID: &testTrack.ID}, FrameTimestamp: first},
+		{Annotation: models.Annotation{ID: "a-2", PointCloudID: "pc-2", X: 2, TrackID: &testTrack.ID}, FrameTimestamp: first.Add(100 * time.Millisecond)},
+	}
+
+	mockRepo.On("GetTrack", mock.Anything, testTrack.ID).Return(testTrack, nil)
+	mockRepo.On("GetTrackAnnotations", mock.Anything, testTrack.ID).Return(trajectory, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/track-1/annotations", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.TrajectoryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.Equal(t, "a-1", response.Data[0].ID)
+	assert.Equal(t, first, response.Data[0].FrameTimestamp)
+	assert.Equal(t, 2.0, response.Data[1].X)
+}
+
+func TestGetTrackAnnotations_NotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetTrack", mock.Anything, "missing").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tracks/missing/annotations", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertNotCalled(t, "GetTrackAnnotations")
+}
+
+func TestDeleteTrack_EvictsObservations(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	trajectory := []models.TrackObservation{{Annotation: models.Annotation{ID: "a-1", PointCloudID: "pc-1"}}}
+
+	mockRepo.On("GetTrackAnnotations", mock.Anything, testTrack.ID).Return(trajectory, nil)
+	mockRepo.On("DeleteTrack", mock.Anything, testTrack.ID).Return(nil)
+	mockCache.On("InvalidateAll", mock.Anything, "pc-1").Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/tracks/track-1", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// GetTrash handles listing deleted annotations.
+// @Summary List trash
+// @Description Retrieve the deleted annotations of a point cloud that can still be restored, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param limit query int false "Maximum number of annotations (default 100, max 1000)"
+// @Success 200 {object} models.AnnotationsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/trash [get]
+func (h *Handler) GetTrash(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	ctx := c.Request.Context()
+
+	limit := models.DefaultAnnotationLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > models.MaxAnnotationLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: fmt.Sprintf("limit must be between 1 and %d", models.MaxAnnotationLimit),
+			})
+			return
+		}
+		limit = n
+	}
+
+	if !h.requirePointCloud(ctx, c, pointCloudID) {
+		return
+	}
+
+	annotations, err := h.repo.GetTrash(ctx, pointCloudID, limit)
+	if err != nil {
+		h.logger.Error("Failed to get trash", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to retrieve trash",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AnnotationsResponse{Data: annotations})
+}
+
+// Restore handles moving an annotation out of the trash.
+// @Summary Restore annotation
+// @Description Restore a deleted annotation from the trash
+// @Tags trash
+// @Produce json
+// @Param id path string true "Point cloud ID"
+// @Param annotationId path string true "Annotation ID"
+// @Success 200 {object} models.AnnotationResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pointclouds/{id}/annotations/{annotationId}/restore [post]
+func (h *Handler) Restore(c *gin.Context) {
+	pointCloudID := c.Param("id")
+	id := c.Param("annotationId")
+	ctx := writeContext(c)
+
+	annotation, err := h.repo.Restore(ctx, pointCloudID, id)
+	if err != nil {
+		if errors.Is(err, database.ErrNonconforming) {
+			writeNonconforming(c, err)
+			return
+		}
+		if writeTrackError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to restore annotation", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "failed to restore annotation",
+		})
+		return
+	}
+
+	if annotation == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "annotation not found in trash",
+		})
+		return
+	}
+
+	// Update cache
+	ctx = committed(ctx)
+	h.cacheWritten(ctx, annotation)
+	h.publish(ctx, annotationEvent(ctx, models.EventCreated, annotation))
+
+	c.Header("ETag", annotationETag(annotation.Version))
+	c.JSON(http.StatusOK, models.AnnotationResponse{Data: *annotation})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/pointcloud-annotator/backend/internal/database"
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+func TestGetTrash_Success(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	trashed := []models.Annotation{{ID: "test-id", PointCloudID: testPointCloud.ID, DeletedAt: &deletedAt}}
+
+	mockRepo.On("GetPointCloud", mock.Anything, testPointCloud.ID).Return(testPointCloud, nil)
+	mockRepo.On("GetTrash", mock.Anything, testPointCloud.ID, 20).Return(trashed, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/trash?limit=20", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AnnotationsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, deletedAt, *response.Data[0].DeletedAt)
+}
+
+func TestGetTrash_PointCloudNotFound(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	mockRepo.On("GetPointCloud", mock.Anything, "missing").Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/missing/trash", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertNotCalled(t, "GetTrash")
+}
+
+func TestGetTrash_InvalidLimit(t *testing.T) {
+	_, mockRepo, _, engine := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pointclouds/pc-1/trash?limit=5000", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "GetTrash")
+}
+
+func TestRestore_Success(t *testing.T) {
+	_, mockRepo, mockCache, engine := setupTestHandler()
+
+	restored := &models.Annotation{ID: "test-id", PointCloudID: testPointCloud.ID, Version: 3}
+
+	mockRepo.On("Restore", mock.Anything, testPointCloud.ID, "test-id").Return(restored, nil)
+	mockCache.On("InvalidateAll", mock.Anything, testPointCloud.ID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/restore", nil)
+	w := httptest.NewRecorder()
+
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	mockCache.AssertExpectations(t)
+}
+
+func TestRestore_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"not in trash", nil, http.StatusNotFound},
+		{"track observed again", database.ErrTrackFrameTaken, http.StatusConflict},
+		{"nonconforming", fmt.Errorf("%w: geometry type not allowed", database.ErrNonconforming), http.StatusUnprocessableEntity},
+		{"database error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockRepo, mockCache, engine := setupTestHandler()
+
+			mockRepo.On("Restore", mock.Anything, testPointCloud.ID, "test-id").Return(nil, tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/pointclouds/pc-1/annotations/test-id/restore", nil)
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			mockCache.AssertNotCalled(t, "Set")
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package identity carries the identity of authenticated users from the
+// gateway, which verifies their tokens, to the handlers, as internal headers
+// signed with a secret the two share.
+package identity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Internal headers of the identity. Clients cannot set them: the gateway
+// replaces them on every request, and handlers only trust them when their
+// signature is valid.
+const (
+	SubjectHeader   = "X-Identity-Subject"
+	NameHeader      = "X-Identity-Name"
+	IssuedHeader    = "X-Identity-Issued"
+	NonceHeader     = "X-Identity-Nonce"
+	SignatureHeader = "X-Identity-Signature"
+)
+
+// MaxAge is how long a signed identity stays valid, allowing for clock skew
+// between the gateway and the handlers.
+const MaxAge = time.Minute
+
+var (
+	// ErrMissing is returned when a request carries no identity.
+	ErrMissing = errors.New("request carries no identity")
+
+	// ErrInvalidSignature is returned when the identity of a request is not
+	// signed with the shared secret, or not for this request.
+	ErrInvalidSignature = errors.New("identity signature is invalid")
+
+	// ErrExpired is returned when the identity was signed too long ago.
+	ErrExpired = errors.New("identity signature has expired")
+
+	// ErrReplayed is returned when the identity was signed for a request
+	// that has been served already.
+	ErrReplayed = errors.New("identity signature was already used")
+)
+
+// Identity is an authenticated user.
+type Identity struct {
+	// Subject identifies the user at the token issuer.
+	Subject string
+
+	// Name is the user's display name, if the token has one.
+	Name string
+}
+
+// Author returns the name annotation writes of the user are attributed to:
+// the display name, otherwise the subject.
+func (id *Identity) Author() string {
+	if id.Name != "" {
+		return id.Name
+	}
+	return id.Subject
+}
+
+type contextKey struct{}
+
+// WithIdentity returns a context carrying the identity.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity attached to the context by WithIdentity,
+// or nil.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
+
+// Strip removes the identity headers, such as those a client made up.
+func Strip(header http.Header) {
+	header.Del(SubjectHeader)
+	header.Del(NameHeader)
+	header.Del(IssuedHeader)
+	header.Del(NonceHeader)
+	header.Del(SignatureHeader)
+}
+
+// Sign sets the identity headers of a request to the handler. The signature
+// covers the method, path and query, and a random nonce that handlers accept
+// only once, so that it can be used neither for another request nor again.
+// Every attempt of a request is signed anew.
+func Sign(r *http.Request, id *Identity, secret []byte, now time.Time) {
+	Strip(r.Header)
+
+	issued := strconv.FormatInt(now.Unix(), 10)
+	nonce := newNonce()
+	r.Header.Set(SubjectHeader, id.Subject)
+	if id.Name != "" {
+		r.Header.Set(NameHeader, id.Name)
+	}
+	r.Header.Set(IssuedHeader, issued)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, signature(secret, r.Method, r.URL.Path, r.URL.RawQuery, id.Subject, id.Name, issued, nonce))
+}
+
+// Verify returns the identity a request carries, if its signature is valid
+// and recent. Whether its nonce was used before is left to the caller.
+func Verify(r *http.Request, secret []byte, now time.Time) (*Identity, error) {
+	subject := r.Header.Get(SubjectHeader)
+	if subject == "" {
+		return nil, ErrMissing
+	}
+	name := r.Header.Get(NameHeader)
+	issued := r.Header.Get(IssuedHeader)
+	nonce := r.Header.Get(NonceHeader)
+	if nonce == "" {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := signature(secret, r.Method, r.URL.Path, r.URL.RawQuery, subject, name, issued, nonce)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(SignatureHeader))) {
+		return nil, ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > MaxAge || age < -MaxAge {
+		return nil, ErrExpired
+	}
+
+	return &Identity{Subject: subject, Name: name}, nil
+}
+
+// signature returns the hex HMAC-SHA256 of the signed fields, one per line.
+func signature(secret []byte, fields ...string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(append([]string{"v2"}, fields...), "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newNonce returns 128 random bits, hex encoded.
+func newNonce() string {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	return hex.EncodeToString(nonce)
+}
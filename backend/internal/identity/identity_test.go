@@ -0,0 +1,164 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testSecret = []byte("identity-secret")
+
+func signedRequest(method, target string, id *Identity, now time.Time) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	Sign(r, id, testSecret, now)
+	return r
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	alice := &Identity{Subject: "u-1", Name: "Alice"}
+
+	id, err := Verify(signedRequest(http.MethodPut, "/api/v1/labels/car", alice, now), testSecret, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, alice, id)
+
+	// Only the subject is required
+	id, err = Verify(signedRequest(http.MethodGet, "/api/v1/labels", &Identity{Subject: "u-2"}, now), testSecret, now)
+	require.NoError(t, err)
+	assert.Equal(t, "u-2", id.Author())
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	alice := &Identity{Subject: "u-1", Name: "Alice"}
+
+	_, err := Verify(httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil), testSecret, now)
+	assert.ErrorIs(t, err, ErrMissing)
+
+	// Made up by the client
+	forged := httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+	forged.Header.Set(SubjectHeader, "admin")
+	_, err = Verify(forged, testSecret, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// Signed for another user, request or secret
+	tampered := signedRequest(http.MethodGet, "/api/v1/labels", alice, now)
+	tampered.Header.Set(NameHeader, "Mallory")
+	_, err = Verify(tampered, testSecret, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	replayed := signedRequest(http.MethodGet, "/api/v1/labels", alice, now)
+	replayed.Method = http.MethodDelete
+	_, err = Verify(replayed, testSecret, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	requeried := signedRequest(http.MethodGet, "/api/v1/annotations/export?point_cloud_id=pc-1&format=kitti", alice, now)
+	requeried.URL.RawQuery = "point_cloud_id=pc-2&format=kitti"
+	_, err = Verify(requeried, testSecret, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	unsalted := signedRequest(http.MethodGet, "/api/v1/labels", alice, now)
+	unsalted.Header.Del(NonceHeader)
+	_, err = Verify(unsalted, testSecret, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = Verify(signedRequest(http.MethodGet, "/api/v1/labels", alice, now), []byte("other-secret"), now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// Signed too long ago
+	_, err = Verify(signedRequest(http.MethodGet, "/api/v1/labels", alice, now), testSecret, now.Add(2*MaxAge))
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestSign_ReplacesHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+	r.Header.Set(NameHeader, "Mallory")
+
+	Sign(r, &Identity{Subject: "u-2"}, testSecret, time.Now())
+	assert.Empty(t, r.Header.Get(NameHeader))
+	assert.Equal(t, "u-2", r.Header.Get(SubjectHeader))
+
+	// Every signature gets a nonce of its own
+	nonce := r.Header.Get(NonceHeader)
+	assert.Len(t, nonce, 32)
+	Sign(r, &Identity{Subject: "u-2"}, testSecret, time.Now())
+	assert.NotEqual(t, nonce, r.Header.Get(NonceHeader))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	alice := &Identity{Subject: "u-1", Name: "Alice"}
+	assert.Same(t, alice, FromContext(WithIdentity(context.Background(), alice)))
+	assert.Equal(t, "Alice", alice.Author())
+}
+
+// memoryNonces implements Nonces in memory, ignoring the TTL.
+type memoryNonces struct {
+	mu      sync.Mutex
+	claimed map[string]bool
+	err     error
+}
+
+func (n *memoryNonces) Claim(_ context.Context, nonce string, _ time.Duration) (bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err != nil {
+		return false, n.err
+	}
+	if n.claimed[nonce] {
+		return false, nil
+	}
+	n.claimed[nonce] = true
+	return true, nil
+}
+
+func (n *memoryNonces) Close() error {
+	return nil
+}
+
+func TestTrust(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nonces := &memoryNonces{claimed: map[string]bool{}}
+	engine := gin.New()
+	engine.Use(Trust(testSecret, nonces, zap.NewNop()))
+	engine.GET("/api/v1/labels", func(c *gin.Context) {
+		c.String(http.StatusOK, FromContext(c.Request.Context()).Author())
+	})
+
+	signed := signedRequest(http.MethodGet, "/api/v1/labels", &Identity{Subject: "u-1", Name: "Alice"}, time.Now())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, signed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Alice", w.Body.String())
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"unauthorized"`)
+
+	// A signed identity is accepted once
+	replayed := httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+	replayed.Header = signed.Header.Clone()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, replayed)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Without the nonce store, identities cannot be checked
+	nonces.err = errors.New("connection refused")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, signedRequest(http.MethodGet, "/api/v1/labels", &Identity{Subject: "u-1"}, time.Now()))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"service_unavailable"`)
+}
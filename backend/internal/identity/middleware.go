@@ -0,0 +1,55 @@
+package identity
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/models"
+)
+
+// Trust returns the handler middleware attaching the identity the gateway
+// signed to the context of every request. Requests without a valid identity
+// did not come through the gateway, or were not authenticated there, and are
+// rejected, as are requests whose identity was used before.
+func Trust(secret []byte, nonces Nonces, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := Verify(c.Request, secret, time.Now())
+		if err != nil {
+			reject(c, logger, err)
+			return
+		}
+
+		fresh, err := nonces.Claim(c.Request.Context(), c.Request.Header.Get(NonceHeader), nonceTTL)
+		if err != nil {
+			logger.Error("Failed to claim identity nonce", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.ErrorResponse{
+				Error:   "service_unavailable",
+				Message: "request identity cannot be checked",
+			})
+			return
+		}
+		if !fresh {
+			reject(c, logger, ErrReplayed)
+			return
+		}
+
+		c.Request = c.Request.WithContext(WithIdentity(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// reject answers a request without a valid identity.
+func reject(c *gin.Context, logger *zap.Logger, err error) {
+	logger.Warn("Rejected request without a valid identity",
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.Error(err),
+	)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+		Error:   "unauthorized",
+		Message: "request is not authenticated",
+	})
+}
@@ -0,0 +1,67 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/pointcloud-annotator/backend/internal/config"
+)
+
+// nonceKeyPrefix prefixes the Redis keys of claimed nonces.
+const nonceKeyPrefix = "identity:nonce:"
+
+// nonceTTL is how long a claimed nonce is remembered: as long as its
+// signature may be accepted, allowing for clock skew either way.
+const nonceTTL = 2 * MaxAge
+
+// Nonces remembers the nonces of the signed identities handlers accepted, so
+// that each is accepted once.
+type Nonces interface {
+	// Claim records the nonce for ttl, returning false if it was recorded
+	// already.
+	Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+
+	// Close closes the connection.
+	Close() error
+}
+
+// RedisNonces implements Nonces in Redis, shared by all handler replicas, so
+// that an identity accepted by one is refused by the others.
+type RedisNonces struct {
+	client *redis.Client
+}
+
+// NewRedisNonces creates a nonce store on Redis.
+func NewRedisNonces(cfg *config.Config, logger *zap.Logger) (Nonces, error) {
+	opt, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	logger.Info("Connected to Redis identity nonce store")
+	return &RedisNonces{client: client}, nil
+}
+
+// Claim records the nonce for ttl, returning false if it was recorded already.
+func (n *RedisNonces) Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return n.client.SetNX(ctx, nonceKeyPrefix+nonce, 1, ttl).Result()
+}
+
+// Close closes the Redis connection.
+func (n *RedisNonces) Close() error {
+	return n.client.Close()
+}
@@ -2,19 +2,37 @@
 package models
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrVersionMismatch is returned for conditional writes to an annotation that
+// has changed since the version the client last read.
+var ErrVersionMismatch = errors.New("annotation version does not match")
+
 // Annotation represents a point cloud annotation with its 3D position and metadata.
 type Annotation struct {
-	ID          string    `json:"id"`
-	X           float64   `json:"x"`
-	Y           float64   `json:"y"`
-	Z           float64   `json:"z"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           string     `json:"id"`
+	PointCloudID string     `json:"point_cloud_id"`
+	X            float64    `json:"x"`
+	Y            float64    `json:"y"`
+	Z            float64    `json:"z"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Geometry     Geometry   `json:"geometry"`
+	LabelID      *string    `json:"label_id,omitempty"`
+	Attributes   Attributes `json:"attributes,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	TrackID      *string    `json:"track_id,omitempty"`
+
+	// Version increases with every change of the annotation, including its
+	// tags. It is served as the annotation's ETag.
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// DeletedAt is set while the annotation is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // CreateAnnotationRequest represents the request body for creating an annotation.
@@ -24,6 +42,31 @@ type CreateAnnotationRequest struct {
 	Z           float64 `json:"z" binding:"required"`
 	Title       string  `json:"title" binding:"required,max=256"`
 	Description string  `json:"description" binding:"max=256"`
+
+	// Geometry defaults to a point at the annotation position.
+	Geometry *Geometry `json:"geometry,omitempty"`
+
+	// LabelID assigns the annotation to a class of the label taxonomy, whose
+	// attribute definitions the attributes must then conform to.
+	LabelID    *string    `json:"label_id,omitempty"`
+	Attributes Attributes `json:"attributes,omitempty"`
+
+	// TrackID links the annotation to the object it observes, which must be
+	// tracked in the sequence the point cloud is a frame of.
+	TrackID *string `json:"track_id,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express.
+func (r *CreateAnnotationRequest) Validate() error {
+	if r.Geometry != nil {
+		if err := r.Geometry.Validate(); err != nil {
+			return err
+		}
+		if err := r.Geometry.CheckPosition(Vec3{X: r.X, Y: r.Y, Z: r.Z}); err != nil {
+			return err
+		}
+	}
+	return r.Attributes.Validate()
 }
 
 // UpdateAnnotationRequest represents the request body for updating an annotation.
@@ -33,6 +76,54 @@ type UpdateAnnotationRequest struct {
 	Z           *float64 `json:"z,omitempty"`
 	Title       *string  `json:"title,omitempty" binding:"omitempty,max=256"`
 	Description *string  `json:"description,omitempty" binding:"omitempty,max=256"`
+
+	// Geometry replaces the annotation's geometry as a whole.
+	Geometry *Geometry `json:"geometry,omitempty"`
+
+	// LabelID reassigns the annotation; an empty string removes its label.
+	LabelID *string `json:"label_id,omitempty"`
+
+	// Attributes replaces the annotation's attributes as a whole.
+	Attributes Attributes `json:"attributes,omitempty"`
+
+	// TrackID links the annotation to another track; an empty string unlinks it.
+	TrackID *string `json:"track_id,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express. A cuboid
+// given together with coordinates must be centered on them.
+func (r *UpdateAnnotationRequest) Validate() error {
+	if r.Geometry != nil {
+		if err := r.Geometry.Validate(); err != nil {
+			return err
+		}
+		if r.Geometry.Type == GeometryCuboid {
+			x, y, z := r.Position()
+			if err := r.Geometry.CheckPosition(Vec3{X: *x, Y: *y, Z: *z}); err != nil {
+				return err
+			}
+		}
+	}
+	return r.Attributes.Validate()
+}
+
+// Position returns the coordinates the update sets, nil for those it keeps.
+// A cuboid moves the annotation to its center, so coordinates left out of an
+// update replacing the geometry with a cuboid are taken from the center.
+func (r *UpdateAnnotationRequest) Position() (x, y, z *float64) {
+	x, y, z = r.X, r.Y, r.Z
+	if g := r.Geometry; g != nil && g.Type == GeometryCuboid && g.Center != nil {
+		if x == nil {
+			x = &g.Center.X
+		}
+		if y == nil {
+			y = &g.Center.Y
+		}
+		if z == nil {
+			z = &g.Center.Z
+		}
+	}
+	return x, y, z
 }
 
 // AnnotationResponse wraps a single annotation in the API response.
@@ -43,6 +134,19 @@ type AnnotationResponse struct {
 // AnnotationsResponse wraps multiple annotations in the API response.
 type AnnotationsResponse struct {
 	Data []Annotation `json:"data"`
+
+	// NextCursor is set when more annotations follow; pass it as the cursor
+	// parameter to fetch the next page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AnnotationPage is one page of a point cloud's annotations.
+type AnnotationPage struct {
+	Annotations []Annotation `json:"annotations"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+
+	// ETag identifies the page as it is served; it is cached with the page.
+	ETag string `json:"etag,omitempty"`
 }
 
 // ErrorResponse represents an error response from the API.
@@ -117,6 +117,37 @@ func TestUpdateAnnotationRequest_PartialUpdate(t *testing.T) {
 	assert.Nil(t, request.Description)
 }
 
+func TestCreateAnnotationRequest_CuboidPosition(t *testing.T) {
+	cuboid := &Geometry{Type: GeometryCuboid, Center: &Vec3{X: 1, Y: 2, Z: 3}, Size: &Dimensions{Length: 1, Width: 1, Height: 1}, Yaw: floatPtr(0)}
+
+	centered := CreateAnnotationRequest{X: 1, Y: 2, Z: 3, Title: "Car", Geometry: cuboid}
+	assert.NoError(t, centered.Validate())
+
+	offCenter := CreateAnnotationRequest{X: 10, Y: 2, Z: 3, Title: "Car", Geometry: cuboid}
+	assert.ErrorIs(t, offCenter.Validate(), ErrCuboidOffCenter)
+}
+
+func TestUpdateAnnotationRequest_CuboidPosition(t *testing.T) {
+	cuboid := &Geometry{Type: GeometryCuboid, Center: &Vec3{X: 1, Y: 2, Z: 3}, Size: &Dimensions{Length: 1, Width: 1, Height: 1}, Yaw: floatPtr(0)}
+
+	// Coordinates left out are taken from the center
+	moved := UpdateAnnotationRequest{Geometry: cuboid, Z: floatPtr(3)}
+	assert.NoError(t, moved.Validate())
+	x, y, z := moved.Position()
+	assert.Equal(t, []float64{1, 2, 3}, []float64{*x, *y, *z})
+
+	offCenter := UpdateAnnotationRequest{Geometry: cuboid, X: floatPtr(10)}
+	assert.ErrorIs(t, offCenter.Validate(), ErrCuboidOffCenter)
+
+	// Without a geometry the coordinates are kept as given
+	title := "Renamed"
+	renamed := UpdateAnnotationRequest{Title: &title}
+	x, y, z = renamed.Position()
+	assert.Nil(t, x)
+	assert.Nil(t, y)
+	assert.Nil(t, z)
+}
+
 func TestAnnotationResponse_Structure(t *testing.T) {
 	annotation := Annotation{
 		ID:    "test-id",
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxAttributes caps the number of attributes of a single annotation.
+const MaxAttributes = 64
+
+// MaxAttributeStringLength caps the length of string attribute values in bytes.
+const MaxAttributeStringLength = 256
+
+// Attributes holds structured annotation data such as confidence, sensor id
+// or instance id, keyed by attribute name. Values are booleans, numbers or
+// strings.
+type Attributes map[string]any
+
+// Validate checks attribute names and that all values are scalars.
+func (a Attributes) Validate() error {
+	if len(a) > MaxAttributes {
+		return fmt.Errorf("at most %d attributes are allowed", MaxAttributes)
+	}
+
+	for name, value := range a {
+		if !attributeNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid attribute name %q", name)
+		}
+		switch v := value.(type) {
+		case bool, float64:
+		case string:
+			if len(v) > MaxAttributeStringLength {
+				return fmt.Errorf("attribute %q exceeds maximum length of %d bytes", name, MaxAttributeStringLength)
+			}
+		default:
+			return fmt.Errorf("attribute %q must be a boolean, number or string", name)
+		}
+	}
+
+	return nil
+}
+
+// AttributeColumn is an attribute flattened into a column of tabular exports.
+// Its type is bool or number if all of its values are, and string otherwise.
+type AttributeColumn struct {
+	Name string
+	Type AttributeType
+}
+
+// Attribute filter operators.
+const (
+	AttributeEqual          = "="
+	AttributeNotEqual       = "!="
+	AttributeGreater        = ">"
+	AttributeGreaterOrEqual = ">="
+	AttributeLess           = "<"
+	AttributeLessOrEqual    = "<="
+)
+
+// attributeFilterPattern matches filter expressions such as "attr.occluded=true"
+// or "attr.confidence>=0.8". Longer operators come first so that ">=" is not
+// read as ">" followed by a value starting with "=".
+var attributeFilterPattern = regexp.MustCompile(`^attr\.([a-z][a-z0-9_]{0,63})(>=|<=|!=|>|<|=)(.*)$`)
+
+// AttributeFilter restricts a listing to annotations whose attribute compares
+// to a value. Equality compares booleans, numbers and strings; the ordering
+// operators compare numbers only.
+type AttributeFilter struct {
+	Name     string
+	Operator string
+
+	// Value is a bool, float64 or string.
+	Value any
+}
+
+// IsAttributeFilter reports whether a query parameter is an attribute filter.
+func IsAttributeFilter(expr string) bool {
+	return strings.HasPrefix(expr, "attr.")
+}
+
+// ParseAttributeFilter parses an expression of the form "attr.<name><op><value>".
+// Values of equality filters are read as booleans or numbers where possible
+// and as strings otherwise; quoting a value in double quotes forces a string.
+func ParseAttributeFilter(expr string) (AttributeFilter, error) {
+	m := attributeFilterPattern.FindStringSubmatch(expr)
+	if m == nil {
+		return AttributeFilter{}, fmt.Errorf("invalid attribute filter %q", expr)
+	}
+
+	f := AttributeFilter{Name: m[1], Operator: m[2]}
+	raw := m[3]
+
+	if f.Operator != AttributeEqual && f.Operator != AttributeNotEqual {
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || !finite(n) {
+			return AttributeFilter{}, fmt.Errorf("attribute filter %q requires a number", expr)
+		}
+		f.Value = n
+		return f, nil
+	}
+
+	switch n, err := strconv.ParseFloat(raw, 64); {
+	case strings.HasPrefix(raw, `"`):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return AttributeFilter{}, fmt.Errorf("attribute filter %q has a malformed quoted value", expr)
+		}
+		f.Value = s
+	case raw == "true" || raw == "false":
+		f.Value = raw == "true"
+	case err == nil && finite(n):
+		f.Value = n
+	default:
+		f.Value = raw
+	}
+	return f, nil
+}
+
+// String returns the filter in the form accepted by ParseAttributeFilter.
+func (f AttributeFilter) String() string {
+	value := fmt.Sprint(f.Value)
+	switch v := f.Value.(type) {
+	case float64:
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		value = strconv.Quote(v)
+	}
+	return "attr." + f.Name + f.Operator + value
+}
+
+// Matches reports whether the attributes pass the filter. Annotations lacking
+// the attribute only pass "!=" filters.
+func (f AttributeFilter) Matches(attributes Attributes) bool {
+	value, ok := attributes[f.Name]
+
+	switch f.Operator {
+	case AttributeEqual:
+		return ok && value == f.Value
+	case AttributeNotEqual:
+		return !ok || value != f.Value
+	}
+
+	n, isNumber := value.(float64)
+	if !ok || !isNumber {
+		return false
+	}
+	bound := f.Value.(float64)
+
+	switch f.Operator {
+	case AttributeGreater:
+		return n > bound
+	case AttributeGreaterOrEqual:
+		return n >= bound
+	case AttributeLess:
+		return n < bound
+	default:
+		return n <= bound
+	}
+}
+
+// sortedAttributeFilters returns the canonical representations of the filters.
+func sortedAttributeFilters(filters []AttributeFilter) []string {
+	keys := make([]string, len(filters))
+	for i, f := range filters {
+		keys[i] = f.String()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
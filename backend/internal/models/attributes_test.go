@@ -0,0 +1,71 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributes_Validate(t *testing.T) {
+	assert.NoError(t, Attributes(nil).Validate())
+	assert.NoError(t, Attributes{"occluded": true, "confidence": 0.9, "sensor_id": "lidar-top"}.Validate())
+
+	assert.Error(t, Attributes{"Sensor": "x"}.Validate())
+	assert.Error(t, Attributes{"nested": map[string]any{"a": 1.0}}.Validate())
+	assert.Error(t, Attributes{"list": []any{1.0}}.Validate())
+	assert.Error(t, Attributes{"missing": nil}.Validate())
+	assert.Error(t, Attributes{"note": strings.Repeat("a", MaxAttributeStringLength+1)}.Validate())
+}
+
+func TestParseAttributeFilter(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected AttributeFilter
+	}{
+		{"attr.occluded=true", AttributeFilter{Name: "occluded", Operator: "=", Value: true}},
+		{"attr.confidence>=0.8", AttributeFilter{Name: "confidence", Operator: ">=", Value: 0.8}},
+		{"attr.confidence<1", AttributeFilter{Name: "confidence", Operator: "<", Value: 1.0}},
+		{"attr.instance_id=42", AttributeFilter{Name: "instance_id", Operator: "=", Value: 42.0}},
+		{`attr.instance_id="42"`, AttributeFilter{Name: "instance_id", Operator: "=", Value: "42"}},
+		{"attr.sensor!=lidar-top", AttributeFilter{Name: "sensor", Operator: "!=", Value: "lidar-top"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseAttributeFilter(tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, f)
+
+			// The canonical form parses to the same filter
+			again, err := ParseAttributeFilter(f.String())
+			assert.NoError(t, err)
+			assert.Equal(t, f, again)
+		})
+	}
+
+	for _, expr := range []string{"attr.", "attr.occluded", "attr.Conf=1", "attr.confidence>=high", `attr.name="open`} {
+		_, err := ParseAttributeFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestAttributeFilter_Matches(t *testing.T) {
+	attributes := Attributes{"occluded": true, "confidence": 0.8, "sensor": "lidar-top"}
+
+	matches := func(expr string) bool {
+		f, err := ParseAttributeFilter(expr)
+		assert.NoError(t, err)
+		return f.Matches(attributes)
+	}
+
+	assert.True(t, matches("attr.occluded=true"))
+	assert.False(t, matches("attr.occluded=false"))
+	assert.True(t, matches("attr.confidence>=0.8"))
+	assert.False(t, matches("attr.confidence>0.8"))
+	assert.True(t, matches("attr.sensor=lidar-top"))
+	assert.True(t, matches("attr.sensor!=radar"))
+	assert.True(t, matches("attr.missing!=1"))
+	assert.False(t, matches("attr.missing=1"))
+	assert.False(t, matches("attr.sensor>1"))
+}
@@ -0,0 +1,95 @@
+package models
+
+import "fmt"
+
+// MaxBatchOperations caps the number of operations of one batch request.
+const MaxBatchOperations = 10000
+
+// Batch operation types.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation is one annotation write of a batch. Creates carry the new
+// annotation in Create; updates and deletes address an existing annotation by
+// ID, updates carrying their changes in Update.
+type BatchOperation struct {
+	Op           string `json:"op" binding:"required,oneof=create update delete"`
+	PointCloudID string `json:"point_cloud_id" binding:"required"`
+	ID           string `json:"id,omitempty"`
+	// Version, like If-Match, makes an update or delete apply only to this
+	// version of the annotation. Zero applies it to any version.
+	Version int64                    `json:"version,omitempty" binding:"min=0"`
+	Create  *CreateAnnotationRequest `json:"create,omitempty"`
+	Update  *UpdateAnnotationRequest `json:"update,omitempty"`
+}
+
+// Versions returns the versions the operation applies to, none if it applies
+// to any version.
+func (o *BatchOperation) Versions() []int64 {
+	if o.Version == 0 {
+		return nil
+	}
+	return []int64{o.Version}
+}
+
+// Validate checks that the operation carries what its type needs. The carried
+// requests are checked like the corresponding single requests.
+func (o *BatchOperation) Validate() error {
+	switch o.Op {
+	case BatchCreate:
+		if o.Create == nil {
+			return fmt.Errorf("create operations require create")
+		}
+		if o.ID != "" || o.Version != 0 || o.Update != nil {
+			return fmt.Errorf("create operations only take point_cloud_id and create")
+		}
+	case BatchUpdate:
+		if o.ID == "" || o.Update == nil {
+			return fmt.Errorf("update operations require id and update")
+		}
+		if o.Create != nil {
+			return fmt.Errorf("update operations do not take create")
+		}
+	default:
+		if o.ID == "" {
+			return fmt.Errorf("delete operations require id")
+		}
+		if o.Create != nil || o.Update != nil {
+			return fmt.Errorf("delete operations do not take create or update")
+		}
+	}
+	return nil
+}
+
+// BatchRequest represents the request body for applying many annotation
+// writes at once. Atomic batches apply all of their operations or none;
+// otherwise every operation succeeds or fails on its own.
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1"`
+}
+
+// Validate checks the request beyond what the binding tags express. The
+// operations themselves are validated one by one.
+func (r *BatchRequest) Validate() error {
+	if len(r.Operations) > MaxBatchOperations {
+		return fmt.Errorf("a batch may hold at most %d operations", MaxBatchOperations)
+	}
+	return nil
+}
+
+// BatchResult is the outcome of one operation of a batch, in the status and
+// body the corresponding single request would have been answered with.
+type BatchResult struct {
+	Status     int            `json:"status"`
+	Annotation *Annotation    `json:"annotation,omitempty"`
+	Error      *ErrorResponse `json:"error,omitempty"`
+}
+
+// BatchResponse holds the outcome of every operation of a batch, in order.
+type BatchResponse struct {
+	Data []BatchResult `json:"data"`
+}
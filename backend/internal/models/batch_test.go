@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchOperation_Validate(t *testing.T) {
+	title := "Car"
+
+	valid := []BatchOperation{
+		{Op: BatchCreate, PointCloudID: "pc-1", Create: &CreateAnnotationRequest{Title: title}},
+		{Op: BatchUpdate, PointCloudID: "pc-1", ID: "a-1", Version: 2, Update: &UpdateAnnotationRequest{Title: &title}},
+		{Op: BatchDelete, PointCloudID: "pc-1", ID: "a-1"},
+	}
+	for _, op := range valid {
+		assert.NoError(t, op.Validate(), op.Op)
+	}
+
+	invalid := []BatchOperation{
+		{Op: BatchCreate, PointCloudID: "pc-1"},
+		{Op: BatchCreate, PointCloudID: "pc-1", ID: "a-1", Create: &CreateAnnotationRequest{Title: title}},
+		{Op: BatchUpdate, PointCloudID: "pc-1", Update: &UpdateAnnotationRequest{Title: &title}},
+		{Op: BatchUpdate, PointCloudID: "pc-1", ID: "a-1"},
+		{Op: BatchDelete, PointCloudID: "pc-1"},
+		{Op: BatchDelete, PointCloudID: "pc-1", ID: "a-1", Update: &UpdateAnnotationRequest{Title: &title}},
+	}
+	for _, op := range invalid {
+		assert.Error(t, op.Validate(), op.Op)
+	}
+}
+
+func TestBatchRequest_Validate(t *testing.T) {
+	req := BatchRequest{Operations: make([]BatchOperation, MaxBatchOperations)}
+	assert.NoError(t, req.Validate())
+
+	req.Operations = append(req.Operations, BatchOperation{})
+	assert.Error(t, req.Validate())
+}
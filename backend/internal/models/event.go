@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Types of annotation change events.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// AnnotationEvent announces a change of an annotation to the clients watching
+// its point cloud. Restored annotations are announced as created.
+type AnnotationEvent struct {
+	Type         string `json:"type"`
+	PointCloudID string `json:"point_cloud_id"`
+	AnnotationID string `json:"annotation_id"`
+
+	// Annotation is the annotation after the change; deletions leave it out.
+	Annotation *Annotation `json:"annotation,omitempty"`
+
+	// Author identifies who made the change, when the request named them.
+	Author string    `json:"author,omitempty"`
+	Time   time.Time `json:"time"`
+}
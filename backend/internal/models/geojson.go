@@ -0,0 +1,256 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GeoJSON object types.
+const (
+	GeoJSONFeatureCollection = "FeatureCollection"
+	GeoJSONFeature           = "Feature"
+	GeoJSONPoint             = "Point"
+	GeoJSONLineString        = "LineString"
+	GeoJSONPolygon           = "Polygon"
+)
+
+// FeatureCollection is a GeoJSON (RFC 7946) feature collection of annotations.
+// Coordinates are [x, y, z] positions in point cloud units rather than
+// longitude and latitude.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON feature representing one annotation.
+type Feature struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id,omitempty"`
+	Geometry   *FeatureGeometry  `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// FeatureGeometry is a GeoJSON Point, LineString or Polygon. Polygons are
+// single rings; holes are not supported.
+type FeatureGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// FeatureProperties are the annotation fields of a feature. Imports ignore
+// the fields the server assigns: point_cloud_id, tags, version and the
+// timestamps.
+type FeatureProperties struct {
+	PointCloudID string     `json:"point_cloud_id,omitempty"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description,omitempty"`
+	LabelID      *string    `json:"label_id,omitempty"`
+	Attributes   Attributes `json:"attributes,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	TrackID      *string    `json:"track_id,omitempty"`
+	Version      int64      `json:"version,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+
+	// Position is the annotation position of features that are not points.
+	// Imports default it to the centroid of the vertices.
+	Position []float64 `json:"position,omitempty"`
+
+	// Height is the extrusion height of polygons.
+	Height *float64 `json:"height,omitempty"`
+
+	// Shape carries cuboids and volumes, which GeoJSON has no geometry for.
+	// Their features are Points at the annotation position.
+	Shape *Geometry `json:"shape,omitempty"`
+}
+
+// NewFeatureCollection returns the annotations as a feature collection.
+func NewFeatureCollection(annotations []Annotation) FeatureCollection {
+	features := make([]Feature, len(annotations))
+	for i := range annotations {
+		features[i] = NewFeature(&annotations[i])
+	}
+	return FeatureCollection{Type: GeoJSONFeatureCollection, Features: features}
+}
+
+// NewFeature returns the feature representing an annotation.
+func NewFeature(a *Annotation) Feature {
+	position := []float64{a.X, a.Y, a.Z}
+	createdAt, updatedAt := a.CreatedAt, a.UpdatedAt
+
+	feature := Feature{
+		Type: GeoJSONFeature,
+		ID:   a.ID,
+		Properties: FeatureProperties{
+			PointCloudID: a.PointCloudID,
+			Title:        a.Title,
+			Description:  a.Description,
+			LabelID:      a.LabelID,
+			Attributes:   a.Attributes,
+			Tags:         a.Tags,
+			TrackID:      a.TrackID,
+			Version:      a.Version,
+			CreatedAt:    &createdAt,
+			UpdatedAt:    &updatedAt,
+		},
+	}
+
+	switch a.Geometry.Type {
+	case GeometryPolyline:
+		feature.Geometry = featureGeometry(GeoJSONLineString, positions(a.Geometry.Vertices))
+		feature.Properties.Position = position
+	case GeometryPolygon:
+		feature.Geometry = featureGeometry(GeoJSONPolygon, [][][]float64{positions(a.Geometry.Vertices)})
+		feature.Properties.Position = position
+		feature.Properties.Height = a.Geometry.Height
+	case GeometryPoint:
+		feature.Geometry = featureGeometry(GeoJSONPoint, position)
+	default:
+		shape := a.Geometry
+		feature.Geometry = featureGeometry(GeoJSONPoint, position)
+		feature.Properties.Shape = &shape
+	}
+	return feature
+}
+
+func featureGeometry(geometryType string, coordinates any) *FeatureGeometry {
+	data, _ := json.Marshal(coordinates)
+	return &FeatureGeometry{Type: geometryType, Coordinates: data}
+}
+
+func positions(vertices []Vec3) [][]float64 {
+	coordinates := make([][]float64, len(vertices))
+	for i, v := range vertices {
+		coordinates[i] = []float64{v.X, v.Y, v.Z}
+	}
+	return coordinates
+}
+
+// CreateRequest returns the request creating the annotation the feature
+// represents. Only the feature's form is checked; the request is validated
+// like any other.
+func (f *Feature) CreateRequest() (*CreateAnnotationRequest, error) {
+	if f.Type != GeoJSONFeature {
+		return nil, fmt.Errorf("type must be %q", GeoJSONFeature)
+	}
+	if f.Geometry == nil {
+		return nil, fmt.Errorf("geometry is required")
+	}
+	if f.Properties.Title == "" {
+		return nil, fmt.Errorf("properties.title is required")
+	}
+
+	var position *Vec3
+	var geometry Geometry
+	switch f.Geometry.Type {
+	case GeoJSONPoint:
+		var coordinates []float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("Point coordinates must be a position")
+		}
+		point, err := parsePosition(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		position = &point
+		geometry = PointGeometry()
+		if f.Properties.Shape != nil {
+			geometry = *f.Properties.Shape
+		}
+	case GeoJSONLineString:
+		var coordinates [][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("LineString coordinates must be an array of positions")
+		}
+		vertices, err := parseVertices(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		geometry = Geometry{Type: GeometryPolyline, Vertices: vertices}
+	case GeoJSONPolygon:
+		var rings [][][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("Polygon coordinates must be an array of rings")
+		}
+		if len(rings) != 1 {
+			return nil, fmt.Errorf("Polygon must have exactly one ring; holes are not supported")
+		}
+		vertices, err := parseVertices(rings[0])
+		if err != nil {
+			return nil, err
+		}
+		geometry = Geometry{Type: GeometryPolygon, Vertices: vertices, Height: f.Properties.Height}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q", f.Geometry.Type)
+	}
+
+	if f.Properties.Shape != nil && f.Geometry.Type != GeoJSONPoint {
+		return nil, fmt.Errorf("properties.shape requires a Point geometry")
+	}
+	if f.Properties.Height != nil && f.Geometry.Type != GeoJSONPolygon {
+		return nil, fmt.Errorf("properties.height requires a Polygon geometry")
+	}
+
+	if f.Properties.Position != nil {
+		point, err := parsePosition(f.Properties.Position)
+		if err != nil {
+			return nil, fmt.Errorf("properties.position: %w", err)
+		}
+		position = &point
+	}
+	if position == nil {
+		center := centroid(geometry.Vertices)
+		position = &center
+	}
+
+	return &CreateAnnotationRequest{
+		X:           position.X,
+		Y:           position.Y,
+		Z:           position.Z,
+		Title:       f.Properties.Title,
+		Description: f.Properties.Description,
+		Geometry:    &geometry,
+		LabelID:     f.Properties.LabelID,
+		Attributes:  f.Properties.Attributes,
+		TrackID:     f.Properties.TrackID,
+	}, nil
+}
+
+func parsePosition(coordinates []float64) (Vec3, error) {
+	if len(coordinates) != 3 {
+		return Vec3{}, fmt.Errorf("positions must have x, y and z coordinates")
+	}
+	return Vec3{X: coordinates[0], Y: coordinates[1], Z: coordinates[2]}, nil
+}
+
+func parseVertices(coordinates [][]float64) ([]Vec3, error) {
+	vertices := make([]Vec3, len(coordinates))
+	for i, c := range coordinates {
+		v, err := parsePosition(c)
+		if err != nil {
+			return nil, err
+		}
+		vertices[i] = v
+	}
+	return vertices, nil
+}
+
+// centroid returns the mean of the vertices, counting the closing vertex of a
+// ring only once.
+func centroid(vertices []Vec3) Vec3 {
+	if n := len(vertices); n > 1 && vertices[0] == vertices[n-1] {
+		vertices = vertices[:n-1]
+	}
+	if len(vertices) == 0 {
+		return Vec3{}
+	}
+
+	var sum Vec3
+	for _, v := range vertices {
+		sum.X += v.X
+		sum.Y += v.Y
+		sum.Z += v.Z
+	}
+	return sum.scale(1 / float64(len(vertices)))
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeature_RoundTrip(t *testing.T) {
+	height := 2.0
+	yaw := 0.25
+	annotations := []Annotation{
+		{X: 1, Y: 2, Z: 3, Title: "Marker", Geometry: PointGeometry()},
+		{X: 5, Y: 5, Z: 0, Title: "Curb", Geometry: Geometry{
+			Type:     GeometryPolyline,
+			Vertices: []Vec3{{X: 0, Y: 0, Z: 0}, {X: 10, Y: 0, Z: 0}},
+		}},
+		{X: 1, Y: 1, Z: 0, Title: "Footprint", Geometry: Geometry{
+			Type:     GeometryPolygon,
+			Vertices: []Vec3{{X: 0, Y: 0, Z: 0}, {X: 2, Y: 0, Z: 0}, {X: 2, Y: 2, Z: 0}, {X: 0, Y: 0, Z: 0}},
+			Height:   &height,
+		}},
+		{X: 1, Y: 2, Z: 3, Title: "Car", Geometry: Geometry{
+			Type:   GeometryCuboid,
+			Center: &Vec3{X: 1, Y: 2, Z: 3},
+			Size:   &Dimensions{Length: 4, Width: 2, Height: 1.5},
+			Yaw:    &yaw,
+		}},
+	}
+
+	data, err := json.Marshal(NewFeatureCollection(annotations))
+	assert.NoError(t, err)
+
+	var collection FeatureCollection
+	assert.NoError(t, json.Unmarshal(data, &collection))
+
+	for i, feature := range collection.Features {
+		req, err := feature.CreateRequest()
+		assert.NoError(t, err, annotations[i].Title)
+		assert.Equal(t, annotations[i].Title, req.Title)
+		assert.Equal(t, annotations[i].Position(), Vec3{X: req.X, Y: req.Y, Z: req.Z})
+		assert.Equal(t, annotations[i].Geometry, *req.Geometry)
+	}
+}
+
+func TestFeature_CreateRequestDefaultsPosition(t *testing.T) {
+	feature := Feature{
+		Type:       GeoJSONFeature,
+		Geometry:   &FeatureGeometry{Type: GeoJSONPolygon, Coordinates: json.RawMessage(`[[[0, 0, 0], [3, 0, 0], [3, 3, 0], [0, 0, 0]]]`)},
+		Properties: FeatureProperties{Title: "Footprint"},
+	}
+
+	req, err := feature.CreateRequest()
+	assert.NoError(t, err)
+
+	// The closing vertex counts once
+	assert.Equal(t, Vec3{X: 2, Y: 1, Z: 0}, Vec3{X: req.X, Y: req.Y, Z: req.Z})
+}
+
+func TestFeature_CreateRequestInvalid(t *testing.T) {
+	features := map[string]Feature{
+		"no geometry": {Type: GeoJSONFeature, Properties: FeatureProperties{Title: "a"}},
+		"no title":    {Type: GeoJSONFeature, Geometry: &FeatureGeometry{Type: GeoJSONPoint, Coordinates: json.RawMessage(`[1, 2, 3]`)}},
+		"2D point":    {Type: GeoJSONFeature, Geometry: &FeatureGeometry{Type: GeoJSONPoint, Coordinates: json.RawMessage(`[1, 2]`)}, Properties: FeatureProperties{Title: "a"}},
+		"multipoint":  {Type: GeoJSONFeature, Geometry: &FeatureGeometry{Type: "MultiPoint", Coordinates: json.RawMessage(`[[1, 2, 3]]`)}, Properties: FeatureProperties{Title: "a"}},
+		"hole": {Type: GeoJSONFeature, Geometry: &FeatureGeometry{Type: GeoJSONPolygon, Coordinates: json.RawMessage(
+			`[[[0, 0, 0], [3, 0, 0], [3, 3, 0], [0, 0, 0]], [[1, 1, 0], [2, 1, 0], [2, 2, 0], [1, 1, 0]]]`,
+		)}, Properties: FeatureProperties{Title: "a"}},
+	}
+
+	for name, feature := range features {
+		_, err := feature.CreateRequest()
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,281 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCuboidOffCenter is returned for cuboids whose center is not the position
+// of their annotation.
+var ErrCuboidOffCenter = errors.New("cuboid center must equal the annotation's x, y and z")
+
+// GeometryType identifies the shape of an annotation.
+type GeometryType string
+
+// Supported geometry types.
+const (
+	// GeometryPoint is a marker at the annotation position.
+	GeometryPoint GeometryType = "point"
+
+	// GeometryCuboid is an oriented 3D bounding box.
+	GeometryCuboid GeometryType = "cuboid"
+
+	// GeometryPolyline is an open path through its vertices, e.g. a road edge.
+	GeometryPolyline GeometryType = "polyline"
+
+	// GeometryPolygon is a closed planar ring, e.g. a footprint outline,
+	// optionally extruded along its normal.
+	GeometryPolygon GeometryType = "polygon"
+
+	// GeometryVolume is the convex hull of its vertices.
+	GeometryVolume GeometryType = "volume"
+)
+
+// MaxGeometryVertices caps the number of vertices of a single geometry.
+const MaxGeometryVertices = 10000
+
+const (
+	// quaternionTolerance is how far a rotation quaternion may deviate from unit length.
+	quaternionTolerance = 1e-3
+
+	// planarityTolerance is how far, in point cloud units, a polygon vertex may
+	// lie off the polygon's plane, and how far a volume must extend out of
+	// any single plane.
+	planarityTolerance = 1e-3
+)
+
+// Dimensions is the extent of a cuboid along its local axes.
+type Dimensions struct {
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Quaternion is a rotation given as a unit quaternion.
+type Quaternion struct {
+	W float64 `json:"w"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Norm returns the length of the quaternion.
+func (q Quaternion) Norm() float64 {
+	return math.Sqrt(q.W*q.W + q.X*q.X + q.Y*q.Y + q.Z*q.Z)
+}
+
+// Yaw returns the rotation's heading around the Z axis, dropping pitch and roll.
+func (q Quaternion) Yaw() float64 {
+	return math.Atan2(2*(q.W*q.Z+q.X*q.Y), 1-2*(q.Y*q.Y+q.Z*q.Z))
+}
+
+// YawQuaternion returns the rotation by yaw radians around the Z axis.
+func YawQuaternion(yaw float64) Quaternion {
+	return Quaternion{W: math.Cos(yaw / 2), Z: math.Sin(yaw / 2)}
+}
+
+// Geometry describes the shape of an annotation. A point needs no data beyond
+// the annotation position; a cuboid is given by its center, its size and its
+// orientation, either as a yaw angle around the Z axis (radians) or as a full
+// rotation quaternion. Polylines, polygons and volumes are given by their
+// vertices. A polygon ring is closed by repeating its first vertex at the end
+// and may carry an extrusion height.
+type Geometry struct {
+	Type GeometryType `json:"type"`
+
+	Center   *Vec3       `json:"center,omitempty"`
+	Size     *Dimensions `json:"size,omitempty"`
+	Yaw      *float64    `json:"yaw,omitempty"`
+	Rotation *Quaternion `json:"rotation,omitempty"`
+
+	Vertices []Vec3   `json:"vertices,omitempty"`
+	Height   *float64 `json:"height,omitempty"`
+}
+
+// PointGeometry returns the geometry of a plain point annotation.
+func PointGeometry() Geometry {
+	return Geometry{Type: GeometryPoint}
+}
+
+// Heading returns the yaw of a cuboid, derived from its rotation if it has one.
+func (g *Geometry) Heading() float64 {
+	switch {
+	case g.Yaw != nil:
+		return *g.Yaw
+	case g.Rotation != nil:
+		return g.Rotation.Yaw()
+	default:
+		return 0
+	}
+}
+
+// Validate checks that the geometry is complete and consistent for its type.
+func (g *Geometry) Validate() error {
+	hasBox := g.Center != nil || g.Size != nil || g.Yaw != nil || g.Rotation != nil
+	hasVertices := len(g.Vertices) > 0
+
+	switch g.Type {
+	case GeometryPoint:
+		if hasBox || hasVertices || g.Height != nil {
+			return fmt.Errorf("point geometry takes no further fields")
+		}
+		return nil
+	case GeometryCuboid:
+		if hasVertices || g.Height != nil {
+			return fmt.Errorf("cuboid geometry takes no vertices or height")
+		}
+		return g.validateCuboid()
+	case GeometryPolyline, GeometryPolygon, GeometryVolume:
+		if hasBox {
+			return fmt.Errorf("%s geometry takes no center, size or orientation", g.Type)
+		}
+		if len(g.Vertices) > MaxGeometryVertices {
+			return fmt.Errorf("%s geometry exceeds %d vertices", g.Type, MaxGeometryVertices)
+		}
+		for _, v := range g.Vertices {
+			if !v.finite() {
+				return fmt.Errorf("%s vertices must be finite", g.Type)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+
+	switch g.Type {
+	case GeometryPolyline:
+		return g.validatePolyline()
+	case GeometryPolygon:
+		return g.validatePolygon()
+	default:
+		return g.validateVolume()
+	}
+}
+
+// CheckPosition checks that a cuboid is centered on the annotation position.
+// Spatial queries find annotations by their position while exports place the
+// box at its center, so the two must not drift apart. Other geometries may lie
+// anywhere relative to the position.
+func (g *Geometry) CheckPosition(position Vec3) error {
+	if g.Type == GeometryCuboid && g.Center != nil && *g.Center != position {
+		return ErrCuboidOffCenter
+	}
+	return nil
+}
+
+func (g *Geometry) validateCuboid() error {
+	if g.Center == nil {
+		return fmt.Errorf("cuboid requires a center")
+	}
+	if g.Size == nil {
+		return fmt.Errorf("cuboid requires a size")
+	}
+	if g.Size.Length <= 0 || g.Size.Width <= 0 || g.Size.Height <= 0 {
+		return fmt.Errorf("cuboid length, width and height must be positive")
+	}
+	if (g.Yaw == nil) == (g.Rotation == nil) {
+		return fmt.Errorf("cuboid requires exactly one of yaw or rotation")
+	}
+	if g.Yaw != nil && (*g.Yaw < -2*math.Pi || *g.Yaw > 2*math.Pi) {
+		return fmt.Errorf("cuboid yaw must be within [-2π, 2π] radians")
+	}
+	if g.Rotation != nil && math.Abs(g.Rotation.Norm()-1) > quaternionTolerance {
+		return fmt.Errorf("cuboid rotation must be a unit quaternion")
+	}
+	return nil
+}
+
+func (g *Geometry) validatePolyline() error {
+	if g.Height != nil {
+		return fmt.Errorf("polyline geometry takes no height")
+	}
+	if len(g.Vertices) < 2 {
+		return fmt.Errorf("polyline requires at least 2 vertices")
+	}
+	return nil
+}
+
+func (g *Geometry) validatePolygon() error {
+	// A closed ring of a triangle has four vertices
+	if len(g.Vertices) < 4 {
+		return fmt.Errorf("polygon requires at least 3 distinct vertices")
+	}
+	if g.Vertices[0] != g.Vertices[len(g.Vertices)-1] {
+		return fmt.Errorf("polygon must be closed by repeating its first vertex")
+	}
+	ring := g.Vertices[:len(g.Vertices)-1]
+	if g.Height != nil && (*g.Height <= 0 || math.IsInf(*g.Height, 0) || math.IsNaN(*g.Height)) {
+		return fmt.Errorf("polygon height must be a positive number")
+	}
+
+	normal, centroid := newellPlane(ring)
+	if normal.Norm() == 0 {
+		return fmt.Errorf("polygon must not be degenerate")
+	}
+	normal = normal.scale(1 / normal.Norm())
+	for _, v := range ring {
+		if math.Abs(v.Sub(centroid).Dot(normal)) > planarityTolerance {
+			return fmt.Errorf("polygon vertices must be coplanar")
+		}
+	}
+	return nil
+}
+
+func (g *Geometry) validateVolume() error {
+	if g.Height != nil {
+		return fmt.Errorf("volume geometry takes no height")
+	}
+	if len(g.Vertices) < 4 {
+		return fmt.Errorf("volume requires at least 4 vertices")
+	}
+
+	// The hull encloses a volume if some vertex lies off the plane spanned by
+	// the first vertex, the vertex farthest from it and the vertex farthest
+	// from the line between those two.
+	origin := g.Vertices[0]
+	var axis Vec3
+	for _, v := range g.Vertices[1:] {
+		if d := v.Sub(origin); d.Norm() > axis.Norm() {
+			axis = d
+		}
+	}
+	var normal Vec3
+	for _, v := range g.Vertices[1:] {
+		if n := axis.Cross(v.Sub(origin)); n.Norm() > normal.Norm() {
+			normal = n
+		}
+	}
+	if normal.Norm() == 0 {
+		return fmt.Errorf("volume vertices must not be collinear")
+	}
+	normal = normal.scale(1 / normal.Norm())
+	for _, v := range g.Vertices {
+		if math.Abs(v.Sub(origin).Dot(normal)) > planarityTolerance {
+			return nil
+		}
+	}
+	return fmt.Errorf("volume vertices must not be coplanar")
+}
+
+// newellPlane returns the (unnormalized) normal of a polygon ring by Newell's
+// method together with the ring's centroid.
+func newellPlane(ring []Vec3) (normal, centroid Vec3) {
+	for i, cur := range ring {
+		next := ring[(i+1)%len(ring)]
+		normal.X += (cur.Y - next.Y) * (cur.Z + next.Z)
+		normal.Y += (cur.Z - next.Z) * (cur.X + next.X)
+		normal.Z += (cur.X - next.X) * (cur.Y + next.Y)
+		centroid.X += cur.X
+		centroid.Y += cur.Y
+		centroid.Z += cur.Z
+	}
+	return normal, centroid.scale(1 / float64(len(ring)))
+}
+
+func (v Vec3) scale(f float64) Vec3 {
+	return Vec3{X: v.X * f, Y: v.Y * f, Z: v.Z * f}
+}
+
+func (v Vec3) finite() bool {
+	return finite(v.X) && finite(v.Y) && finite(v.Z)
+}
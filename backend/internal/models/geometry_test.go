@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestGeometry_Validate(t *testing.T) {
+	center := &Vec3{X: 1, Y: 2, Z: 3}
+	size := &Dimensions{Length: 4, Width: 2, Height: 1.5}
+	s := math.Sqrt(0.5)
+
+	tests := []struct {
+		name     string
+		geometry Geometry
+		valid    bool
+	}{
+		{"point", PointGeometry(), true},
+		{"point with size", Geometry{Type: GeometryPoint, Size: size}, false},
+		{"unknown type", Geometry{Type: "sphere"}, false},
+		{"cuboid with yaw", Geometry{Type: GeometryCuboid, Center: center, Size: size, Yaw: floatPtr(math.Pi / 2)}, true},
+		{"cuboid with rotation", Geometry{Type: GeometryCuboid, Center: center, Size: size, Rotation: &Quaternion{W: s, Z: s}}, true},
+		{"cuboid without center", Geometry{Type: GeometryCuboid, Size: size, Yaw: floatPtr(0)}, false},
+		{"cuboid without size", Geometry{Type: GeometryCuboid, Center: center, Yaw: floatPtr(0)}, false},
+		{"cuboid with zero height", Geometry{Type: GeometryCuboid, Center: center, Size: &Dimensions{Length: 1, Width: 1}, Yaw: floatPtr(0)}, false},
+		{"cuboid without orientation", Geometry{Type: GeometryCuboid, Center: center, Size: size}, false},
+		{"cuboid with yaw and rotation", Geometry{Type: GeometryCuboid, Center: center, Size: size, Yaw: floatPtr(0), Rotation: &Quaternion{W: 1}}, false},
+		{"cuboid with yaw out of range", Geometry{Type: GeometryCuboid, Center: center, Size: size, Yaw: floatPtr(7)}, false},
+		{"cuboid with non-unit rotation", Geometry{Type: GeometryCuboid, Center: center, Size: size, Rotation: &Quaternion{W: 1, X: 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.geometry.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGeometry_CheckPosition(t *testing.T) {
+	cuboid := Geometry{Type: GeometryCuboid, Center: &Vec3{X: 1, Y: 2, Z: 3}, Size: &Dimensions{Length: 1, Width: 1, Height: 1}, Yaw: floatPtr(0)}
+
+	assert.NoError(t, cuboid.CheckPosition(Vec3{X: 1, Y: 2, Z: 3}))
+	assert.ErrorIs(t, cuboid.CheckPosition(Vec3{X: 1, Y: 2, Z: 4}), ErrCuboidOffCenter)
+
+	// Only cuboids are tied to the position
+	point := PointGeometry()
+	assert.NoError(t, point.CheckPosition(Vec3{X: 5}))
+}
+
+func TestGeometry_JSONRoundTrip(t *testing.T) {
+	annotation := Annotation{
+		ID:    "test-uuid",
+		Title: "Car",
+		Geometry: Geometry{
+			Type:     GeometryCuboid,
+			Center:   &Vec3{X: 1, Y: 2, Z: 3},
+			Size:     &Dimensions{Length: 4.5, Width: 1.8, Height: 1.5},
+			Rotation: &Quaternion{W: 1},
+		},
+	}
+
+	data, err := json.Marshal(annotation)
+	assert.NoError(t, err)
+
+	var unmarshaled Annotation
+	assert.NoError(t, json.Unmarshal(data, &unmarshaled))
+	assert.Equal(t, annotation.Geometry, unmarshaled.Geometry)
+
+	// Points carry nothing but their type
+	data, err = json.Marshal(PointGeometry())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "point"}`, string(data))
+}
+
+func TestGeometry_ValidateShapes(t *testing.T) {
+	square := []Vec3{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}, {X: 0, Y: 0}}
+	tetrahedron := []Vec3{{}, {X: 1}, {Y: 1}, {Z: 1}}
+
+	tests := []struct {
+		name     string
+		geometry Geometry
+		valid    bool
+	}{
+		{"polyline", Geometry{Type: GeometryPolyline, Vertices: square[:2]}, true},
+		{"polyline with one vertex", Geometry{Type: GeometryPolyline, Vertices: square[:1]}, false},
+		{"polyline with height", Geometry{Type: GeometryPolyline, Vertices: square[:2], Height: floatPtr(1)}, false},
+		{"polyline with center", Geometry{Type: GeometryPolyline, Vertices: square[:2], Center: &Vec3{}}, false},
+		{"polyline with NaN vertex", Geometry{Type: GeometryPolyline, Vertices: []Vec3{{}, {X: math.NaN()}}}, false},
+		{"polygon", Geometry{Type: GeometryPolygon, Vertices: square}, true},
+		{"extruded polygon", Geometry{Type: GeometryPolygon, Vertices: square, Height: floatPtr(2.5)}, true},
+		{"tilted polygon", Geometry{Type: GeometryPolygon, Vertices: []Vec3{{}, {X: 1, Z: 1}, {X: 1, Y: 1, Z: 1}, {}}}, true},
+		{"open polygon", Geometry{Type: GeometryPolygon, Vertices: square[:4]}, false},
+		{"polygon with two vertices", Geometry{Type: GeometryPolygon, Vertices: []Vec3{{}, {X: 1}, {}}}, false},
+		{"collinear polygon", Geometry{Type: GeometryPolygon, Vertices: []Vec3{{}, {X: 1}, {X: 2}, {}}}, false},
+		{"non-planar polygon", Geometry{Type: GeometryPolygon, Vertices: []Vec3{{}, {X: 1}, {X: 1, Y: 1, Z: 0.5}, {Y: 1}, {}}}, false},
+		{"polygon with negative height", Geometry{Type: GeometryPolygon, Vertices: square, Height: floatPtr(-1)}, false},
+		{"volume", Geometry{Type: GeometryVolume, Vertices: tetrahedron}, true},
+		{"volume with three vertices", Geometry{Type: GeometryVolume, Vertices: tetrahedron[:3]}, false},
+		{"flat volume", Geometry{Type: GeometryVolume, Vertices: square[:4]}, false},
+		{"collinear volume", Geometry{Type: GeometryVolume, Vertices: []Vec3{{}, {X: 1}, {X: 2}, {X: 3}}}, false},
+		{"point with vertices", Geometry{Type: GeometryPoint, Vertices: square}, false},
+		{"cuboid with vertices", Geometry{Type: GeometryCuboid, Center: &Vec3{}, Size: &Dimensions{1, 1, 1}, Yaw: floatPtr(0), Vertices: square}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.geometry.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Operations recorded in the history of an annotation.
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRevert  = "revert"
+	RevisionRestore = "restore"
+)
+
+// AnnotationRevision is one entry of an annotation's append-only history. It
+// holds the state of the annotation after the operation or, for deletions,
+// the state it was deleted in. Tags are not part of the history.
+type AnnotationRevision struct {
+	AnnotationID string     `json:"annotation_id"`
+	Revision     int        `json:"revision"`
+	Operation    string     `json:"operation"`
+	Annotation   Annotation `json:"annotation"`
+
+	// RevertedFrom is the revision a revert restored.
+	RevertedFrom *int `json:"reverted_from,omitempty"`
+
+	// Author identifies who made the change, when the request named them.
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HistoryResponse wraps the revisions of an annotation, oldest first, in the
+// API response.
+type HistoryResponse struct {
+	Data []AnnotationRevision `json:"data"`
+}
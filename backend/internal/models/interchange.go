@@ -0,0 +1,28 @@
+package models
+
+// ImportError reports why a feature or record of an import was rejected.
+type ImportError struct {
+	// Index is the position of the feature or record in the import.
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarizes a successful import. IDs lists the created
+// annotations in the order of the import.
+type ImportResult struct {
+	Imported int      `json:"imported"`
+	IDs      []string `json:"ids"`
+}
+
+// ImportResponse wraps an import result in the API response.
+type ImportResponse struct {
+	Data ImportResult `json:"data"`
+}
+
+// ImportErrorResponse rejects an import, listing every feature or record at
+// fault. Nothing of a rejected import is created.
+type ImportErrorResponse struct {
+	Error   string        `json:"error"`
+	Message string        `json:"message"`
+	Errors  []ImportError `json:"errors"`
+}
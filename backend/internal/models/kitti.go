@@ -0,0 +1,166 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// KITTIDontCare is the type of KITTI regions that are not annotated. Imports
+// skip them.
+const KITTIDontCare = "DontCare"
+
+// Attributes KITTI objects carry beyond their box.
+const (
+	KITTITruncatedAttribute = "truncated"
+	KITTIOccludedAttribute  = "occluded"
+	KITTIScoreAttribute     = "score"
+)
+
+// KITTIObject is one line of a KITTI object detection label_2 file.
+//
+// KITTI boxes are in camera coordinates (x right, y down, z forward) and
+// located at the bottom center of the box, whereas annotations are taken to
+// be in a LiDAR frame (x forward, y left, z up) with the cuboid center as
+// position. No calibration is applied between the two, and as annotations
+// carry no image, the 2D box is written as zeros.
+type KITTIObject struct {
+	Type      string
+	Truncated float64
+	Occluded  int
+	Alpha     float64
+
+	// BBox is the 2D box in image pixels: left, top, right and bottom.
+	BBox [4]float64
+
+	// Dimensions are height, width and length.
+	Dimensions [3]float64
+
+	// Location is the bottom center of the box.
+	Location  [3]float64
+	RotationY float64
+
+	// Score is the detection confidence, present in result files only.
+	Score *float64
+}
+
+// ParseKITTIObject parses a label_2 line.
+func ParseKITTIObject(line string) (*KITTIObject, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 15 && len(fields) != 16 {
+		return nil, fmt.Errorf("a KITTI label has 15 or 16 fields, got %d", len(fields))
+	}
+
+	values := make([]float64, len(fields))
+	for i := 1; i < len(fields); i++ {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("field %d must be a number, got %q", i+1, fields[i])
+		}
+		values[i] = v
+	}
+	if values[2] != math.Trunc(values[2]) {
+		return nil, fmt.Errorf("occluded must be an integer, got %q", fields[2])
+	}
+
+	o := &KITTIObject{
+		Type:       fields[0],
+		Truncated:  values[1],
+		Occluded:   int(values[2]),
+		Alpha:      values[3],
+		BBox:       [4]float64{values[4], values[5], values[6], values[7]},
+		Dimensions: [3]float64{values[8], values[9], values[10]},
+		Location:   [3]float64{values[11], values[12], values[13]},
+		RotationY:  values[14],
+	}
+	if len(fields) == 16 {
+		o.Score = &values[15]
+	}
+	return o, nil
+}
+
+// String formats the object as a label_2 line.
+func (o *KITTIObject) String() string {
+	line := fmt.Sprintf("%s %.2f %d %.2f %.2f %.2f %.2f %.2f %.2f %.2f %.2f %.2f %.2f %.2f %.2f",
+		o.Type, o.Truncated, o.Occluded, o.Alpha,
+		o.BBox[0], o.BBox[1], o.BBox[2], o.BBox[3],
+		o.Dimensions[0], o.Dimensions[1], o.Dimensions[2],
+		o.Location[0], o.Location[1], o.Location[2],
+		o.RotationY)
+	if o.Score != nil {
+		line += fmt.Sprintf(" %.2f", *o.Score)
+	}
+	return line
+}
+
+// NewKITTIObject returns the KITTI object of a cuboid annotation, typed
+// objectType; false if the annotation is not a cuboid. Spaces in the type are
+// replaced by underscores, as KITTI fields are separated by whitespace.
+func NewKITTIObject(a *Annotation, objectType string) (*KITTIObject, bool) {
+	g := &a.Geometry
+	if g.Type != GeometryCuboid || g.Center == nil || g.Size == nil {
+		return nil, false
+	}
+
+	location := [3]float64{-g.Center.Y, -(g.Center.Z - g.Size.Height/2), g.Center.X}
+	rotationY := normalizeAngle(-g.Heading() - math.Pi/2)
+
+	o := &KITTIObject{
+		Type:       strings.ReplaceAll(objectType, " ", "_"),
+		Alpha:      normalizeAngle(rotationY - math.Atan2(location[0], location[2])),
+		Dimensions: [3]float64{g.Size.Height, g.Size.Width, g.Size.Length},
+		Location:   location,
+		RotationY:  rotationY,
+	}
+	if v, ok := a.Attributes[KITTITruncatedAttribute].(float64); ok {
+		o.Truncated = v
+	}
+	if v, ok := a.Attributes[KITTIOccludedAttribute].(float64); ok {
+		o.Occluded = int(v)
+	}
+	if v, ok := a.Attributes[KITTIScoreAttribute].(float64); ok {
+		o.Score = &v
+	}
+	return o, true
+}
+
+// CreateRequest returns the request creating the cuboid annotation the object
+// describes, titled by its type. Truncation, occlusion and score are kept as
+// attributes.
+func (o *KITTIObject) CreateRequest() *CreateAnnotationRequest {
+	height, width, length := o.Dimensions[0], o.Dimensions[1], o.Dimensions[2]
+	center := Vec3{X: o.Location[2], Y: -o.Location[0], Z: -o.Location[1] + height/2}
+	yaw := normalizeAngle(-o.RotationY - math.Pi/2)
+
+	attributes := Attributes{
+		KITTITruncatedAttribute: o.Truncated,
+		KITTIOccludedAttribute:  float64(o.Occluded),
+	}
+	if o.Score != nil {
+		attributes[KITTIScoreAttribute] = *o.Score
+	}
+
+	return &CreateAnnotationRequest{
+		X:     center.X,
+		Y:     center.Y,
+		Z:     center.Z,
+		Title: o.Type,
+		Geometry: &Geometry{
+			Type:   GeometryCuboid,
+			Center: &center,
+			Size:   &Dimensions{Length: length, Width: width, Height: height},
+			Yaw:    &yaw,
+		},
+		Attributes: attributes,
+	}
+}
+
+// normalizeAngle wraps an angle into [-π, π).
+func normalizeAngle(angle float64) float64 {
+	angle = math.Mod(angle+math.Pi, 2*math.Pi)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return angle - math.Pi
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKITTIObject_RoundTrip(t *testing.T) {
+	yaw := 0.3
+	annotation := &Annotation{
+		Title:      "Car",
+		Attributes: Attributes{KITTITruncatedAttribute: 0.5, KITTIOccludedAttribute: 2.0},
+		Geometry: Geometry{
+			Type:   GeometryCuboid,
+			Center: &Vec3{X: 12, Y: -3, Z: 0.75},
+			Size:   &Dimensions{Length: 4, Width: 1.8, Height: 1.5},
+			Yaw:    &yaw,
+		},
+	}
+
+	object, ok := NewKITTIObject(annotation, "Police car")
+	assert.True(t, ok)
+	assert.Equal(t, "Police_car", object.Type)
+
+	// Bottom center in camera coordinates
+	assert.InDeltaSlice(t, []float64{3, 0, 12}, object.Location[:], 1e-9)
+	assert.InDelta(t, -0.3-math.Pi/2, object.RotationY, 1e-9)
+
+	parsed, err := ParseKITTIObject(object.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, parsed.Occluded)
+	assert.Nil(t, parsed.Score)
+
+	req := parsed.CreateRequest()
+	assert.Equal(t, "Police_car", req.Title)
+	assert.InDelta(t, 12, req.X, 0.01)
+	assert.InDelta(t, -3, req.Y, 0.01)
+	assert.InDelta(t, 0.75, req.Z, 0.01)
+	assert.InDelta(t, yaw, *req.Geometry.Yaw, 0.01)
+	assert.Equal(t, Dimensions{Length: 4, Width: 1.8, Height: 1.5}, *req.Geometry.Size)
+	assert.Equal(t, 0.5, req.Attributes[KITTITruncatedAttribute])
+	assert.NoError(t, req.Validate())
+}
+
+func TestNewKITTIObject_Quaternion(t *testing.T) {
+	rotation := YawQuaternion(1)
+	annotation := &Annotation{Geometry: Geometry{
+		Type:     GeometryCuboid,
+		Center:   &Vec3{X: 1, Y: 1, Z: 1},
+		Size:     &Dimensions{Length: 1, Width: 1, Height: 1},
+		Rotation: &rotation,
+	}}
+
+	object, ok := NewKITTIObject(annotation, "Box")
+	assert.True(t, ok)
+	assert.InDelta(t, -1-math.Pi/2, object.RotationY, 1e-9)
+
+	_, ok = NewKITTIObject(&Annotation{Geometry: PointGeometry()}, "Marker")
+	assert.False(t, ok)
+}
+
+func TestParseKITTIObject(t *testing.T) {
+	object, err := ParseKITTIObject("Pedestrian 0.00 0 -0.20 712.40 143.00 810.73 307.92 1.89 0.48 1.20 1.84 1.47 8.41 0.01 0.95")
+	assert.NoError(t, err)
+	assert.Equal(t, "Pedestrian", object.Type)
+	assert.Equal(t, [4]float64{712.40, 143.00, 810.73, 307.92}, object.BBox)
+	assert.Equal(t, 0.95, *object.Score)
+
+	invalid := []string{
+		"Pedestrian 0.00 0 -0.20",
+		"Pedestrian 0.00 0 -0.20 712.40 143.00 810.73 307.92 1.89 0.48 1.20 1.84 1.47 8.41 far",
+		"Pedestrian 0.00 0.5 -0.20 712.40 143.00 810.73 307.92 1.89 0.48 1.20 1.84 1.47 8.41 0.01",
+	}
+	for _, line := range invalid {
+		_, err := ParseKITTIObject(line)
+		assert.Error(t, err, line)
+	}
+}
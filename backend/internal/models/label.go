@@ -0,0 +1,209 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// AttributeType is the value type of a label attribute.
+type AttributeType string
+
+// Supported attribute types.
+const (
+	AttributeBool   AttributeType = "bool"
+	AttributeNumber AttributeType = "number"
+	AttributeString AttributeType = "string"
+	AttributeEnum   AttributeType = "enum"
+)
+
+// colorPattern matches colors in #rrggbb notation.
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// attributeNamePattern matches attribute names usable as query parameters.
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// AttributeDefinition declares an attribute that annotations of a label carry.
+type AttributeDefinition struct {
+	Name string        `json:"name"`
+	Type AttributeType `json:"type"`
+
+	// Options lists the allowed values of an enum attribute.
+	Options []string `json:"options,omitempty"`
+
+	// Required attributes must be set on every annotation of the label.
+	Required bool `json:"required,omitempty"`
+}
+
+// Label is a class of the label taxonomy that annotations are assigned to.
+type Label struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Color    string  `json:"color"`
+	ParentID *string `json:"parent_id,omitempty"`
+
+	// GeometryTypes restricts the shapes annotations of the label may have;
+	// empty allows all of them.
+	GeometryTypes []GeometryType `json:"geometry_types"`
+
+	Attributes []AttributeDefinition `json:"attributes"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// CreateLabelRequest represents the request body for creating a label.
+type CreateLabelRequest struct {
+	Name          string                `json:"name" binding:"required,max=64"`
+	Color         string                `json:"color" binding:"required"`
+	ParentID      *string               `json:"parent_id,omitempty"`
+	GeometryTypes []GeometryType        `json:"geometry_types,omitempty"`
+	Attributes    []AttributeDefinition `json:"attributes,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express.
+func (r *CreateLabelRequest) Validate() error {
+	return validateLabel(&r.Color, r.GeometryTypes, r.Attributes)
+}
+
+// UpdateLabelRequest represents the request body for updating a label. An
+// empty parent_id detaches the label from its parent.
+type UpdateLabelRequest struct {
+	Name          *string                `json:"name,omitempty" binding:"omitempty,min=1,max=64"`
+	Color         *string                `json:"color,omitempty"`
+	ParentID      *string                `json:"parent_id,omitempty"`
+	GeometryTypes *[]GeometryType        `json:"geometry_types,omitempty"`
+	Attributes    *[]AttributeDefinition `json:"attributes,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express.
+func (r *UpdateLabelRequest) Validate() error {
+	var geometryTypes []GeometryType
+	if r.GeometryTypes != nil {
+		geometryTypes = *r.GeometryTypes
+	}
+	var attributes []AttributeDefinition
+	if r.Attributes != nil {
+		attributes = *r.Attributes
+	}
+	return validateLabel(r.Color, geometryTypes, attributes)
+}
+
+// LabelResponse wraps a single label in the API response.
+type LabelResponse struct {
+	Data Label `json:"data"`
+}
+
+// LabelsResponse wraps multiple labels in the API response.
+type LabelsResponse struct {
+	Data []Label `json:"data"`
+}
+
+func validateLabel(color *string, geometryTypes []GeometryType, attributes []AttributeDefinition) error {
+	if color != nil && !colorPattern.MatchString(*color) {
+		return fmt.Errorf("color must be given as #rrggbb")
+	}
+
+	for _, t := range geometryTypes {
+		if !t.valid() {
+			return fmt.Errorf("unsupported geometry type %q", t)
+		}
+	}
+
+	names := make(map[string]bool, len(attributes))
+	for _, def := range attributes {
+		if !attributeNamePattern.MatchString(def.Name) {
+			return fmt.Errorf("invalid attribute name %q", def.Name)
+		}
+		if names[def.Name] {
+			return fmt.Errorf("duplicate attribute %q", def.Name)
+		}
+		names[def.Name] = true
+
+		switch def.Type {
+		case AttributeBool, AttributeNumber, AttributeString:
+			if len(def.Options) > 0 {
+				return fmt.Errorf("attribute %q: only enum attributes take options", def.Name)
+			}
+		case AttributeEnum:
+			if len(def.Options) == 0 {
+				return fmt.Errorf("attribute %q: enum requires options", def.Name)
+			}
+			seen := make(map[string]bool, len(def.Options))
+			for _, option := range def.Options {
+				if option == "" || seen[option] {
+					return fmt.Errorf("attribute %q: options must be unique and non-empty", def.Name)
+				}
+				seen[option] = true
+			}
+		default:
+			return fmt.Errorf("attribute %q: unsupported type %q", def.Name, def.Type)
+		}
+	}
+
+	return nil
+}
+
+// Check reports whether an annotation with the given geometry and attributes
+// conforms to the label: its geometry type must be allowed, required
+// attributes must be set and defined attributes must have the declared type.
+// Attributes the label does not define are left alone.
+func (l *Label) Check(geometry Geometry, attributes Attributes) error {
+	if len(l.GeometryTypes) > 0 {
+		allowed := false
+		for _, t := range l.GeometryTypes {
+			allowed = allowed || t == geometry.Type
+		}
+		if !allowed {
+			return fmt.Errorf("label %q does not allow %s geometry", l.Name, geometry.Type)
+		}
+	}
+
+	for _, def := range l.Attributes {
+		value, ok := attributes[def.Name]
+		if !ok {
+			if def.Required {
+				return fmt.Errorf("label %q requires attribute %q", l.Name, def.Name)
+			}
+			continue
+		}
+		if !def.accepts(value) {
+			return fmt.Errorf("attribute %q must be a valid %s", def.Name, def.Type)
+		}
+	}
+
+	return nil
+}
+
+// accepts reports whether a decoded JSON value is valid for the attribute.
+func (d *AttributeDefinition) accepts(value any) bool {
+	switch d.Type {
+	case AttributeBool:
+		_, ok := value.(bool)
+		return ok
+	case AttributeNumber:
+		_, ok := value.(float64)
+		return ok
+	case AttributeString:
+		_, ok := value.(string)
+		return ok
+	case AttributeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		for _, option := range d.Options {
+			if option == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t GeometryType) valid() bool {
+	switch t {
+	case GeometryPoint, GeometryCuboid, GeometryPolyline, GeometryPolygon, GeometryVolume:
+		return true
+	}
+	return false
+}
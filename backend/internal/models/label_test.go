@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateLabelRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CreateLabelRequest
+		valid   bool
+	}{
+		{"minimal", CreateLabelRequest{Name: "car", Color: "#00ff7F"}, true},
+		{"invalid color", CreateLabelRequest{Name: "car", Color: "#00ff7"}, false},
+		{"unknown geometry type", CreateLabelRequest{Name: "car", Color: "#000000", GeometryTypes: []GeometryType{"sphere"}}, false},
+		{"attributes", CreateLabelRequest{Name: "car", Color: "#000000", Attributes: []AttributeDefinition{
+			{Name: "occluded", Type: AttributeBool},
+			{Name: "truncation", Type: AttributeEnum, Options: []string{"none", "full"}},
+		}}, true},
+		{"duplicate attribute", CreateLabelRequest{Name: "car", Color: "#000000", Attributes: []AttributeDefinition{
+			{Name: "occluded", Type: AttributeBool},
+			{Name: "occluded", Type: AttributeString},
+		}}, false},
+		{"invalid attribute name", CreateLabelRequest{Name: "car", Color: "#000000", Attributes: []AttributeDefinition{
+			{Name: "Occluded!", Type: AttributeBool},
+		}}, false},
+		{"unknown attribute type", CreateLabelRequest{Name: "car", Color: "#000000", Attributes: []AttributeDefinition{
+			{Name: "occluded", Type: "date"},
+		}}, false},
+		{"enum without options", CreateLabelRequest{Name: "car", Color: "#000000", Attributes: []AttributeDefinition{
+			{Name: "truncation", Type: AttributeEnum},
+		}}, false},
+		{"bool with options", CreateLabelRequest{Name: "car", Color: "#000000", Attributes: []AttributeDefinition{
+			{Name: "occluded", Type: AttributeBool, Options: []string{"yes"}},
+		}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLabel_Check(t *testing.T) {
+	label := Label{
+		Name:          "car",
+		GeometryTypes: []GeometryType{GeometryCuboid},
+		Attributes: []AttributeDefinition{
+			{Name: "occluded", Type: AttributeBool, Required: true},
+			{Name: "confidence", Type: AttributeNumber},
+			{Name: "truncation", Type: AttributeEnum, Options: []string{"none", "full"}},
+		},
+	}
+	cuboid := Geometry{Type: GeometryCuboid}
+
+	assert.NoError(t, label.Check(cuboid, Attributes{"occluded": true}))
+	assert.NoError(t, label.Check(cuboid, Attributes{"occluded": false, "confidence": 0.9, "truncation": "full", "sensor": "lidar-top"}))
+
+	assert.Error(t, label.Check(PointGeometry(), Attributes{"occluded": true}))
+	assert.Error(t, label.Check(cuboid, nil))
+	assert.Error(t, label.Check(cuboid, Attributes{"occluded": 1.0}))
+	assert.Error(t, label.Check(cuboid, Attributes{"occluded": true, "confidence": "high"}))
+	assert.Error(t, label.Check(cuboid, Attributes{"occluded": true, "truncation": "half"}))
+
+	// Without restrictions any geometry is allowed
+	assert.NoError(t, (&Label{Name: "misc"}).Check(PointGeometry(), nil))
+}
@@ -0,0 +1,120 @@
+package models
+
+// Attributes nuScenes annotations carry beyond their box.
+const (
+	NuScenesInstanceAttribute    = "instance_token"
+	NuScenesVisibilityAttribute  = "visibility_token"
+	NuScenesLidarPointsAttribute = "num_lidar_pts"
+	NuScenesRadarPointsAttribute = "num_radar_pts"
+)
+
+// NuScenesDefaultTitle titles imported records that have no category.
+const NuScenesDefaultTitle = "object"
+
+// NuScenesAnnotation is a nuScenes sample_annotation record. The token is the
+// annotation ID and the sample token the point cloud ID; the instance token is
+// the annotation's track, or its instance_token attribute if it has none.
+// Translation, size and rotation are taken to be in point cloud coordinates.
+type NuScenesAnnotation struct {
+	Token           string   `json:"token"`
+	SampleToken     string   `json:"sample_token"`
+	InstanceToken   string   `json:"instance_token"`
+	VisibilityToken string   `json:"visibility_token"`
+	AttributeTokens []string `json:"attribute_tokens"`
+
+	Translation [3]float64 `json:"translation"`
+
+	// Size is width, length and height.
+	Size [3]float64 `json:"size"`
+
+	// Rotation is a quaternion as w, x, y and z.
+	Rotation [4]float64 `json:"rotation"`
+
+	Prev        string `json:"prev"`
+	Next        string `json:"next"`
+	NumLidarPts int    `json:"num_lidar_pts"`
+	NumRadarPts int    `json:"num_radar_pts"`
+
+	// CategoryName is the annotation's label. nuScenes keeps the category
+	// with the instance rather than the sample annotation; the field carries
+	// it for exchanges without the instance table.
+	CategoryName string `json:"category_name,omitempty"`
+}
+
+// NewNuScenesAnnotation returns the sample annotation of a cuboid annotation
+// in category; false if the annotation is not a cuboid.
+func NewNuScenesAnnotation(a *Annotation, category string) (*NuScenesAnnotation, bool) {
+	g := &a.Geometry
+	if g.Type != GeometryCuboid || g.Center == nil || g.Size == nil {
+		return nil, false
+	}
+
+	rotation := YawQuaternion(g.Heading())
+	if g.Rotation != nil {
+		rotation = *g.Rotation
+	}
+
+	n := &NuScenesAnnotation{
+		Token:           a.ID,
+		SampleToken:     a.PointCloudID,
+		AttributeTokens: []string{},
+		Translation:     [3]float64{g.Center.X, g.Center.Y, g.Center.Z},
+		Size:            [3]float64{g.Size.Width, g.Size.Length, g.Size.Height},
+		Rotation:        [4]float64{rotation.W, rotation.X, rotation.Y, rotation.Z},
+		CategoryName:    category,
+	}
+	if token, ok := a.Attributes[NuScenesInstanceAttribute].(string); ok {
+		n.InstanceToken = token
+	}
+	if a.TrackID != nil {
+		n.InstanceToken = *a.TrackID
+	}
+	if token, ok := a.Attributes[NuScenesVisibilityAttribute].(string); ok {
+		n.VisibilityToken = token
+	}
+	if v, ok := a.Attributes[NuScenesLidarPointsAttribute].(float64); ok {
+		n.NumLidarPts = int(v)
+	}
+	if v, ok := a.Attributes[NuScenesRadarPointsAttribute].(float64); ok {
+		n.NumRadarPts = int(v)
+	}
+	return n, true
+}
+
+// CreateRequest returns the request creating the cuboid annotation the record
+// describes, titled by its category. Instance and visibility tokens and the
+// point counts are kept as attributes; the record's own tokens, attribute
+// tokens and links to neighbouring samples are dropped.
+func (n *NuScenesAnnotation) CreateRequest() *CreateAnnotationRequest {
+	center := Vec3{X: n.Translation[0], Y: n.Translation[1], Z: n.Translation[2]}
+
+	title := n.CategoryName
+	if title == "" {
+		title = NuScenesDefaultTitle
+	}
+
+	attributes := Attributes{
+		NuScenesLidarPointsAttribute: float64(n.NumLidarPts),
+		NuScenesRadarPointsAttribute: float64(n.NumRadarPts),
+	}
+	if n.InstanceToken != "" {
+		attributes[NuScenesInstanceAttribute] = n.InstanceToken
+	}
+	if n.VisibilityToken != "" {
+		attributes[NuScenesVisibilityAttribute] = n.VisibilityToken
+	}
+
+	return &CreateAnnotationRequest{
+		X:     center.X,
+		Y:     center.Y,
+		Z:     center.Z,
+		Title: title,
+		Geometry: &Geometry{
+			Type:     GeometryCuboid,
+			Center:   &center,
+			Size:     &Dimensions{Width: n.Size[0], Length: n.Size[1], Height: n.Size[2]},
+			Rotation: &Quaternion{W: n.Rotation[0], X: n.Rotation[1], Y: n.Rotation[2], Z: n.Rotation[3]},
+		},
+		Attributes: attributes,
+	}
+}
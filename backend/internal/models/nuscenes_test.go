@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNuScenesAnnotation_RoundTrip(t *testing.T) {
+	yaw := -0.5
+	trackID := "track-1"
+	annotation := &Annotation{
+		ID:           "a-1",
+		PointCloudID: "pc-1",
+		Title:        "Car",
+		TrackID:      &trackID,
+		Attributes:   Attributes{NuScenesVisibilityAttribute: "4", NuScenesLidarPointsAttribute: 42.0},
+		Geometry: Geometry{
+			Type:   GeometryCuboid,
+			Center: &Vec3{X: 373.2, Y: 1130.4, Z: 1.2},
+			Size:   &Dimensions{Length: 4.5, Width: 1.9, Height: 1.6},
+			Yaw:    &yaw,
+		},
+	}
+
+	record, ok := NewNuScenesAnnotation(annotation, "vehicle.car")
+	assert.True(t, ok)
+	assert.Equal(t, "a-1", record.Token)
+	assert.Equal(t, "pc-1", record.SampleToken)
+	assert.Equal(t, trackID, record.InstanceToken)
+	assert.Equal(t, [3]float64{1.9, 4.5, 1.6}, record.Size)
+	assert.Equal(t, 42, record.NumLidarPts)
+
+	req := record.CreateRequest()
+	assert.Equal(t, "vehicle.car", req.Title)
+	assert.Equal(t, *annotation.Geometry.Center, *req.Geometry.Center)
+	assert.Equal(t, *annotation.Geometry.Size, *req.Geometry.Size)
+	assert.InDelta(t, yaw, req.Geometry.Rotation.Yaw(), 1e-9)
+	assert.Equal(t, trackID, req.Attributes[NuScenesInstanceAttribute])
+	assert.Equal(t, "4", req.Attributes[NuScenesVisibilityAttribute])
+	assert.NoError(t, req.Validate())
+}
+
+func TestNuScenesAnnotation_CreateRequestDefaultsTitle(t *testing.T) {
+	record := NuScenesAnnotation{Size: [3]float64{1, 1, 1}, Rotation: [4]float64{1, 0, 0, 0}}
+	assert.Equal(t, NuScenesDefaultTitle, record.CreateRequest().Title)
+}
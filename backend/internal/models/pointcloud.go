@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// PointCloud represents a scanned scene that annotations are attached to.
+type PointCloud struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SourceURL   string `json:"source_url"`
+
+	// SequenceID and FrameTimestamp are set when the point cloud is a frame
+	// of a sequence.
+	SequenceID     *string    `json:"sequence_id,omitempty"`
+	FrameTimestamp *time.Time `json:"frame_timestamp,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreatePointCloudRequest represents the request body for registering a point cloud.
+type CreatePointCloudRequest struct {
+	Name        string `json:"name" binding:"required,max=256"`
+	Description string `json:"description" binding:"max=256"`
+	SourceURL   string `json:"source_url" binding:"required,max=1024"`
+
+	// SequenceID makes the point cloud a frame of a sequence, taken at FrameTimestamp.
+	SequenceID     *string    `json:"sequence_id,omitempty"`
+	FrameTimestamp *time.Time `json:"frame_timestamp,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express.
+func (r *CreatePointCloudRequest) Validate() error {
+	return validateFrame(r.SequenceID, r.FrameTimestamp)
+}
+
+// UpdatePointCloudRequest represents the request body for updating a point cloud.
+type UpdatePointCloudRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,max=256"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=256"`
+	SourceURL   *string `json:"source_url,omitempty" binding:"omitempty,max=1024"`
+
+	// SequenceID moves the point cloud into a sequence, together with
+	// FrameTimestamp; an empty string takes it out of its sequence.
+	SequenceID     *string    `json:"sequence_id,omitempty"`
+	FrameTimestamp *time.Time `json:"frame_timestamp,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express. The frame
+// timestamp of a point cloud already in a sequence may change on its own.
+func (r *UpdatePointCloudRequest) Validate() error {
+	if r.SequenceID == nil {
+		return nil
+	}
+	return validateFrame(r.SequenceID, r.FrameTimestamp)
+}
+
+// PointCloudResponse wraps a single point cloud in the API response.
+type PointCloudResponse struct {
+	Data PointCloud `json:"data"`
+}
+
+// PointCloudsResponse wraps multiple point clouds in the API response.
+type PointCloudsResponse struct {
+	Data []PointCloud `json:"data"`
+}
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sort keys accepted when listing annotations.
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByTitle     = "title"
+)
+
+// Page size limits for annotation listings.
+const (
+	DefaultAnnotationLimit = 100
+	MaxAnnotationLimit     = 1000
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by a listing
+// in the query's sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// AnnotationQuery describes which page of a point cloud's annotations to list.
+type AnnotationQuery struct {
+	// Limit is the maximum number of annotations to return.
+	Limit int
+
+	// SortBy is one of the SortBy* keys; Descending reverses the order.
+	SortBy     string
+	Descending bool
+
+	// After continues the listing behind the last annotation of a previous page.
+	After *AnnotationCursor
+
+	// Filters
+	TitlePrefix   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+
+	// Attributes are combined with AND.
+	Attributes []AttributeFilter
+
+	// Tags selects annotations carrying any or, with TagMode TagModeAll, all
+	// of the given tags.
+	Tags    []string
+	TagMode string
+
+	// AsOf reads the annotations as they were at the given time, reconstructed
+	// from their history.
+	AsOf *time.Time
+}
+
+// NewAnnotationQuery returns a query for the first page in the default order,
+// newest annotations first.
+func NewAnnotationQuery() *AnnotationQuery {
+	return &AnnotationQuery{
+		Limit:      DefaultAnnotationLimit,
+		SortBy:     SortByCreatedAt,
+		Descending: true,
+		TagMode:    TagModeAny,
+	}
+}
+
+// SetSort parses a sort expression such as "title" or "-created_at", where a
+// leading minus requests descending order.
+func (q *AnnotationQuery) SetSort(sort string) error {
+	descending := strings.HasPrefix(sort, "-")
+	key := strings.TrimPrefix(sort, "-")
+
+	switch key {
+	case SortByCreatedAt, SortByUpdatedAt, SortByTitle:
+	default:
+		return fmt.Errorf("unsupported sort key %q", key)
+	}
+
+	q.SortBy = key
+	q.Descending = descending
+	return nil
+}
+
+// Sort returns the sort expression of the query in the form accepted by SetSort.
+func (q *AnnotationQuery) Sort() string {
+	if q.Descending {
+		return "-" + q.SortBy
+	}
+	return q.SortBy
+}
+
+// Validate checks the query for consistency.
+func (q *AnnotationQuery) Validate() error {
+	if q.Limit < 1 || q.Limit > MaxAnnotationLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxAnnotationLimit)
+	}
+	if q.After != nil && q.After.Sort != q.Sort() {
+		return fmt.Errorf("%w: issued for sort %q", ErrInvalidCursor, q.After.Sort)
+	}
+	if q.CreatedAfter != nil && q.CreatedBefore != nil && !q.CreatedAfter.Before(*q.CreatedBefore) {
+		return fmt.Errorf("created_after must be before created_before")
+	}
+	if q.UpdatedAfter != nil && q.UpdatedBefore != nil && !q.UpdatedAfter.Before(*q.UpdatedBefore) {
+		return fmt.Errorf("updated_after must be before updated_before")
+	}
+	if len(q.Attributes) > MaxAttributes {
+		return fmt.Errorf("at most %d attribute filters are allowed", MaxAttributes)
+	}
+	if q.TagMode != TagModeAny && q.TagMode != TagModeAll {
+		return fmt.Errorf("tag_mode must be %q or %q", TagModeAny, TagModeAll)
+	}
+	if len(q.Tags) > MaxTagsPerRequest {
+		return fmt.Errorf("at most %d tags can be filtered on", MaxTagsPerRequest)
+	}
+	for _, tag := range q.Tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CacheKey returns a canonical representation of the query, identical for all
+// queries that select the same page. Values are URL-encoded so that no filter
+// value can pass for another parameter.
+func (q *AnnotationQuery) CacheKey() string {
+	values := url.Values{}
+
+	values.Set("limit", strconv.Itoa(q.Limit))
+	values.Set("sort", q.Sort())
+	if q.After != nil {
+		values.Set("cursor", q.After.Encode())
+	}
+	if q.TitlePrefix != "" {
+		values.Set("title_prefix", q.TitlePrefix)
+	}
+	setTime := func(name string, t *time.Time) {
+		if t != nil {
+			values.Set(name, t.UTC().Format(time.RFC3339Nano))
+		}
+	}
+	setTime("created_after", q.CreatedAfter)
+	setTime("created_before", q.CreatedBefore)
+	setTime("updated_after", q.UpdatedAfter)
+	setTime("updated_before", q.UpdatedBefore)
+	for _, filter := range sortedAttributeFilters(q.Attributes) {
+		values.Add("attr", filter)
+	}
+	if len(q.Tags) > 0 {
+		tags := append([]string(nil), q.Tags...)
+		sort.Strings(tags)
+		values["tags"] = tags
+		values.Set("tag_mode", q.TagMode)
+	}
+	setTime("as_of", q.AsOf)
+
+	return values.Encode()
+}
+
+// Matches reports whether the annotation passes the query's filters. Sorting,
+// limit and cursor are not considered.
+func (q *AnnotationQuery) Matches(a *Annotation) bool {
+	if q.TitlePrefix != "" && !strings.HasPrefix(a.Title, q.TitlePrefix) {
+		return false
+	}
+	if q.CreatedAfter != nil && a.CreatedAt.Before(*q.CreatedAfter) {
+		return false
+	}
+	if q.CreatedBefore != nil && !a.CreatedAt.Before(*q.CreatedBefore) {
+		return false
+	}
+	if q.UpdatedAfter != nil && a.UpdatedAt.Before(*q.UpdatedAfter) {
+		return false
+	}
+	if q.UpdatedBefore != nil && !a.UpdatedAt.Before(*q.UpdatedBefore) {
+		return false
+	}
+	for _, filter := range q.Attributes {
+		if !filter.Matches(a.Attributes) {
+			return false
+		}
+	}
+	if len(q.Tags) > 0 {
+		carried := 0
+		for _, tag := range q.Tags {
+			for _, t := range a.Tags {
+				if t == tag {
+					carried++
+					break
+				}
+			}
+		}
+		if carried == 0 || (q.TagMode == TagModeAll && carried < len(q.Tags)) {
+			return false
+		}
+	}
+	return true
+}
+
+// AnnotationCursor marks the position of the last annotation of a page.
+type AnnotationCursor struct {
+	// Sort is the sort expression the cursor was issued for.
+	Sort string `json:"s"`
+
+	// Value is the sort key of the last annotation: the title, or a
+	// timestamp in RFC 3339 format.
+	Value string `json:"v"`
+
+	// ID breaks ties between annotations with equal sort keys.
+	ID string `json:"id"`
+}
+
+// NewAnnotationCursor returns the cursor positioned behind the given annotation.
+func NewAnnotationCursor(q *AnnotationQuery, annotation *Annotation) *AnnotationCursor {
+	cursor := &AnnotationCursor{Sort: q.Sort(), ID: annotation.ID}
+
+	switch q.SortBy {
+	case SortByTitle:
+		cursor.Value = annotation.Title
+	case SortByUpdatedAt:
+		cursor.Value = annotation.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		cursor.Value = annotation.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return cursor
+}
+
+// Encode returns the opaque string representation handed out to clients.
+func (c *AnnotationCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Time returns the cursor value of a timestamp-sorted listing.
+func (c *AnnotationCursor) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, c.Value)
+}
+
+// DecodeAnnotationCursor parses a cursor produced by Encode. Its ID must be a
+// UUID and, unless it sorts by title, its value a timestamp.
+func DecodeAnnotationCursor(s string) (*AnnotationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+
+	var cursor AnnotationCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+
+	if _, err := uuid.Parse(cursor.ID); err != nil {
+		return nil, fmt.Errorf("%w: malformed annotation ID", ErrInvalidCursor)
+	}
+
+	if strings.TrimPrefix(cursor.Sort, "-") != SortByTitle {
+		if _, err := cursor.Time(); err != nil {
+			return nil, fmt.Errorf("%w: malformed timestamp", ErrInvalidCursor)
+		}
+	}
+
+	return &cursor, nil
+}
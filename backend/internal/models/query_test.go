@@ -0,0 +1,158 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotationQuery_Defaults(t *testing.T) {
+	q := NewAnnotationQuery()
+
+	assert.Equal(t, DefaultAnnotationLimit, q.Limit)
+	assert.Equal(t, "-created_at", q.Sort())
+	assert.NoError(t, q.Validate())
+}
+
+func TestAnnotationQuery_SetSort(t *testing.T) {
+	tests := []struct {
+		sort       string
+		key        string
+		descending bool
+		valid      bool
+	}{
+		{"created_at", SortByCreatedAt, false, true},
+		{"-updated_at", SortByUpdatedAt, true, true},
+		{"title", SortByTitle, false, true},
+		{"-title", SortByTitle, true, true},
+		{"description", "", false, false},
+		{"", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			q := NewAnnotationQuery()
+			err := q.SetSort(tt.sort)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.key, q.SortBy)
+			assert.Equal(t, tt.descending, q.Descending)
+			assert.Equal(t, tt.sort, q.Sort())
+		})
+	}
+}
+
+func TestAnnotationQuery_Validate(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+
+	q := NewAnnotationQuery()
+	q.Limit = 0
+	assert.Error(t, q.Validate())
+
+	q = NewAnnotationQuery()
+	q.CreatedAfter, q.CreatedBefore = &late, &early
+	assert.Error(t, q.Validate())
+
+	q = NewAnnotationQuery()
+	q.UpdatedAfter, q.UpdatedBefore = &early, &late
+	assert.NoError(t, q.Validate())
+
+	q = NewAnnotationQuery()
+	q.After = &AnnotationCursor{Sort: "title", Value: "a", ID: "1"}
+	assert.Error(t, q.Validate(), "cursor sort must match the query sort")
+}
+
+func TestAnnotationQuery_CacheKey(t *testing.T) {
+	prefix := NewAnnotationQuery()
+	prefix.TitlePrefix = "car"
+
+	otherLimit := NewAnnotationQuery()
+	otherLimit.Limit = 10
+
+	assert.Equal(t, NewAnnotationQuery().CacheKey(), NewAnnotationQuery().CacheKey())
+	assert.NotEqual(t, NewAnnotationQuery().CacheKey(), prefix.CacheKey())
+	assert.NotEqual(t, NewAnnotationQuery().CacheKey(), otherLimit.CacheKey())
+
+	// Attribute filters are order-independent
+	occluded := AttributeFilter{Name: "occluded", Operator: "=", Value: true}
+	confident := AttributeFilter{Name: "confidence", Operator: ">=", Value: 0.8}
+	first, second := NewAnnotationQuery(), NewAnnotationQuery()
+	first.Attributes = []AttributeFilter{occluded, confident}
+	second.Attributes = []AttributeFilter{confident, occluded}
+	assert.Equal(t, first.CacheKey(), second.CacheKey())
+	assert.NotEqual(t, NewAnnotationQuery().CacheKey(), first.CacheKey())
+
+	asOf := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := NewAnnotationQuery()
+	past.AsOf = &asOf
+	assert.NotEqual(t, NewAnnotationQuery().CacheKey(), past.CacheKey())
+
+	// Values cannot pass for other parameters
+	smuggled := NewAnnotationQuery()
+	smuggled.TitlePrefix = "a&tags=b&tag_mode=any"
+	tagged := NewAnnotationQuery()
+	tagged.TitlePrefix = "a"
+	tagged.Tags = []string{"b"}
+	assert.NotEqual(t, tagged.CacheKey(), smuggled.CacheKey())
+
+	quoted := NewAnnotationQuery()
+	quoted.Attributes = []AttributeFilter{{Name: "sensor", Operator: "=", Value: `lidar"&attr=x`}}
+	assert.NotContains(t, quoted.CacheKey(), "&attr=x")
+}
+
+func TestAnnotationQuery_MatchesTags(t *testing.T) {
+	annotation := &Annotation{Tags: []string{"batch-7", "needs-review"}}
+
+	q := NewAnnotationQuery()
+	q.Tags = []string{"needs-review", "approved"}
+	assert.True(t, q.Matches(annotation))
+
+	q.TagMode = TagModeAll
+	assert.False(t, q.Matches(annotation))
+
+	q.Tags = []string{"needs-review", "batch-7"}
+	assert.True(t, q.Matches(annotation))
+
+	q.Tags = []string{"approved"}
+	q.TagMode = TagModeAny
+	assert.False(t, q.Matches(annotation))
+}
+
+func TestAnnotationCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+	annotation := &Annotation{ID: "8f14e45f-ceea-4e67-a3c1-4e7d5d5c6b1a", Title: "Car", CreatedAt: created, UpdatedAt: created}
+
+	q := NewAnnotationQuery()
+	cursor := NewAnnotationCursor(q, annotation)
+
+	decoded, err := DecodeAnnotationCursor(cursor.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	value, err := decoded.Time()
+	assert.NoError(t, err)
+	assert.True(t, created.Equal(value))
+
+	assert.NoError(t, q.SetSort("title"))
+	cursor = NewAnnotationCursor(q, annotation)
+	assert.Equal(t, "Car", cursor.Value)
+}
+
+func TestDecodeAnnotationCursor_Malformed(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"!!!",
+		"bm90LWpzb24",
+		(&AnnotationCursor{Sort: "-created_at", Value: "yesterday", ID: "8f14e45f-ceea-4e67-a3c1-4e7d5d5c6b1a"}).Encode(),
+		(&AnnotationCursor{Sort: "-created_at", Value: "2024-01-01T00:00:00Z", ID: "1"}).Encode(),
+		(&AnnotationCursor{Sort: "title", Value: "Car"}).Encode(),
+	} {
+		_, err := DecodeAnnotationCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
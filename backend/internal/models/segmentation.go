@@ -0,0 +1,286 @@
+package models
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Limits of the segmentation subsystem.
+const (
+	// MaxSegmentationPoints caps the number of points of a segmented point cloud.
+	MaxSegmentationPoints = 1<<31 - 1
+
+	// MaxSegmentationPatchPoints caps the number of points changed by one patch.
+	MaxSegmentationPatchPoints = 1 << 20
+)
+
+// UnlabeledClass is the class of points no class has been assigned to.
+const UnlabeledClass uint16 = 0
+
+// ErrPointIndexOutOfRange is returned for patches addressing points beyond the
+// end of the point cloud.
+var ErrPointIndexOutOfRange = errors.New("point index out of range")
+
+// Run is a sequence of consecutive points, in point order, sharing a class.
+// It is represented in JSON as a [class, length] pair.
+type Run struct {
+	Class  uint16
+	Length uint32
+}
+
+// MarshalJSON encodes the run as a [class, length] pair.
+func (r Run) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]uint32{uint32(r.Class), r.Length})
+}
+
+// UnmarshalJSON decodes a [class, length] pair.
+func (r *Run) UnmarshalJSON(data []byte) error {
+	var pair [2]int64
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("run must be a [class, length] pair")
+	}
+	if pair[0] < 0 || pair[0] > 0xffff {
+		return fmt.Errorf("run class must be between 0 and 65535")
+	}
+	if pair[1] < 1 || pair[1] > MaxSegmentationPoints {
+		return fmt.Errorf("run length must be between 1 and %d", MaxSegmentationPoints)
+	}
+	r.Class, r.Length = uint16(pair[0]), uint32(pair[1])
+	return nil
+}
+
+// Segmentation assigns a class to every point of a point cloud, indexed by the
+// point order of the cloud. Labels are kept run-length encoded.
+type Segmentation struct {
+	PointCloudID string    `json:"point_cloud_id"`
+	PointCount   int       `json:"point_count"`
+	Runs         []Run     `json:"runs"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// NewSegmentation returns a segmentation of pointCount unlabeled points.
+func NewSegmentation(pointCloudID string, pointCount int) *Segmentation {
+	return &Segmentation{
+		PointCloudID: pointCloudID,
+		PointCount:   pointCount,
+		Runs:         []Run{{Class: UnlabeledClass, Length: uint32(pointCount)}},
+	}
+}
+
+// Labels expands the runs into one class per point.
+func (s *Segmentation) Labels() []uint16 {
+	labels := make([]uint16, 0, s.PointCount)
+	for _, run := range s.Runs {
+		for i := uint32(0); i < run.Length; i++ {
+			labels = append(labels, run.Class)
+		}
+	}
+	return labels
+}
+
+// Apply assigns the classes of the patch to its points. Runs are merged with
+// the changes in a single pass, so the cost depends on the number of runs and
+// changes rather than on the number of points.
+func (s *Segmentation) Apply(patch *SegmentationPatch) error {
+	changes := patch.sorted()
+	if n := len(changes); n > 0 && changes[n-1].index >= s.PointCount {
+		return fmt.Errorf("%w: %d", ErrPointIndexOutOfRange, changes[n-1].index)
+	}
+
+	var b runBuilder
+	next, start := 0, 0
+	for _, run := range s.Runs {
+		end := start + int(run.Length)
+		for ; next < len(changes) && changes[next].index < end; next++ {
+			change := changes[next]
+			b.add(run.Class, change.index-start)
+			b.add(change.class, 1)
+			start = change.index + 1
+		}
+		b.add(run.Class, end-start)
+		start = end
+	}
+
+	s.Runs = b.runs
+	return nil
+}
+
+// Histogram counts the points of every class present, ordered by class.
+func (s *Segmentation) Histogram() []ClassCount {
+	counts := make(map[uint16]int)
+	for _, run := range s.Runs {
+		counts[run.Class] += int(run.Length)
+	}
+
+	histogram := make([]ClassCount, 0, len(counts))
+	for class, count := range counts {
+		histogram = append(histogram, ClassCount{Class: class, Count: count})
+	}
+	sort.Slice(histogram, func(i, j int) bool { return histogram[i].Class < histogram[j].Class })
+	return histogram
+}
+
+// EncodeRuns serializes runs compactly as consecutive uvarint class/length pairs.
+func EncodeRuns(runs []Run) []byte {
+	data := make([]byte, 0, len(runs)*4)
+	for _, run := range runs {
+		data = binary.AppendUvarint(data, uint64(run.Class))
+		data = binary.AppendUvarint(data, uint64(run.Length))
+	}
+	return data
+}
+
+// DecodeRuns parses runs serialized by EncodeRuns.
+func DecodeRuns(data []byte) ([]Run, error) {
+	var runs []Run
+	for len(data) > 0 {
+		class, n := binary.Uvarint(data)
+		if n <= 0 || class > 0xffff {
+			return nil, fmt.Errorf("malformed run class")
+		}
+		data = data[n:]
+
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length == 0 || length > MaxSegmentationPoints {
+			return nil, fmt.Errorf("malformed run length")
+		}
+		data = data[n:]
+
+		runs = append(runs, Run{Class: uint16(class), Length: uint32(length)})
+	}
+	return runs, nil
+}
+
+// runBuilder accumulates runs, merging neighbors of the same class.
+type runBuilder struct {
+	runs []Run
+}
+
+func (b *runBuilder) add(class uint16, length int) {
+	if length <= 0 {
+		return
+	}
+	if n := len(b.runs); n > 0 && b.runs[n-1].Class == class {
+		b.runs[n-1].Length += uint32(length)
+		return
+	}
+	b.runs = append(b.runs, Run{Class: class, Length: uint32(length)})
+}
+
+// PutSegmentationRequest represents the request body for replacing a point
+// cloud's segmentation. Without runs all points start out unlabeled.
+type PutSegmentationRequest struct {
+	PointCount int   `json:"point_count" binding:"required,min=1"`
+	Runs       []Run `json:"runs,omitempty"`
+}
+
+// Validate checks the request beyond what the binding tags express.
+func (r *PutSegmentationRequest) Validate() error {
+	if r.PointCount > MaxSegmentationPoints {
+		return fmt.Errorf("point_count must not exceed %d", MaxSegmentationPoints)
+	}
+	if len(r.Runs) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, run := range r.Runs {
+		total += int(run.Length)
+	}
+	if total != r.PointCount {
+		return fmt.Errorf("runs cover %d points instead of point_count %d", total, r.PointCount)
+	}
+	return nil
+}
+
+// Segmentation returns the segmentation described by the request.
+func (r *PutSegmentationRequest) Segmentation(pointCloudID string) *Segmentation {
+	segmentation := NewSegmentation(pointCloudID, r.PointCount)
+	if len(r.Runs) > 0 {
+		// Normalize the runs by merging neighbors of the same class
+		var b runBuilder
+		for _, run := range r.Runs {
+			b.add(run.Class, int(run.Length))
+		}
+		segmentation.Runs = b.runs
+	}
+	return segmentation
+}
+
+// ClassChange assigns a class to a set of points, given by their indices in
+// point order.
+type ClassChange struct {
+	Class   uint16 `json:"class"`
+	Indices []int  `json:"indices" binding:"required,min=1"`
+}
+
+// SegmentationPatch is a sparse update of a segmentation. When a point is
+// listed more than once, the last change wins.
+type SegmentationPatch struct {
+	Changes []ClassChange `json:"changes" binding:"required,min=1,dive"`
+}
+
+// Validate checks the patch independently of the segmentation it applies to.
+func (p *SegmentationPatch) Validate() error {
+	total := 0
+	for _, change := range p.Changes {
+		total += len(change.Indices)
+		for _, index := range change.Indices {
+			if index < 0 {
+				return fmt.Errorf("%w: %d", ErrPointIndexOutOfRange, index)
+			}
+		}
+	}
+	if total > MaxSegmentationPatchPoints {
+		return fmt.Errorf("a patch may change at most %d points", MaxSegmentationPatchPoints)
+	}
+	return nil
+}
+
+type pointChange struct {
+	index int
+	class uint16
+}
+
+// sorted returns the changes ordered by point index, one per point.
+func (p *SegmentationPatch) sorted() []pointChange {
+	var changes []pointChange
+	for _, change := range p.Changes {
+		for _, index := range change.Indices {
+			changes = append(changes, pointChange{index: index, class: change.Class})
+		}
+	}
+
+	// A stable sort keeps later changes of a point behind earlier ones
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].index < changes[j].index })
+
+	unique := changes[:0]
+	for _, change := range changes {
+		if n := len(unique); n > 0 && unique[n-1].index == change.index {
+			unique[n-1] = change
+			continue
+		}
+		unique = append(unique, change)
+	}
+	return unique
+}
+
+// ClassCount is the number of points of a class.
+type ClassCount struct {
+	Class uint16 `json:"class"`
+	Count int    `json:"count"`
+}
+
+// SegmentationResponse wraps a segmentation in the API response.
+type SegmentationResponse struct {
+	Data Segmentation `json:"data"`
+}
+
+// HistogramResponse wraps a class histogram in the API response.
+type HistogramResponse struct {
+	Data []ClassCount `json:"data"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentation_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		runs    []Run
+		changes []ClassChange
+		want    []Run
+	}{
+		{
+			"split a run",
+			[]Run{{0, 10}},
+			[]ClassChange{{Class: 3, Indices: []int{4}}},
+			[]Run{{0, 4}, {3, 1}, {0, 5}},
+		},
+		{
+			"first and last point",
+			[]Run{{0, 5}},
+			[]ClassChange{{Class: 1, Indices: []int{0, 4}}},
+			[]Run{{1, 1}, {0, 3}, {1, 1}},
+		},
+		{
+			"merge with neighbors",
+			[]Run{{1, 3}, {2, 1}, {1, 3}},
+			[]ClassChange{{Class: 1, Indices: []int{3}}},
+			[]Run{{1, 7}},
+		},
+		{
+			"extend a neighbor across runs",
+			[]Run{{1, 2}, {2, 2}},
+			[]ClassChange{{Class: 1, Indices: []int{2}}},
+			[]Run{{1, 3}, {2, 1}},
+		},
+		{
+			"unchanged class",
+			[]Run{{0, 4}},
+			[]ClassChange{{Class: 0, Indices: []int{2}}},
+			[]Run{{0, 4}},
+		},
+		{
+			"last change wins",
+			[]Run{{0, 4}},
+			[]ClassChange{{Class: 5, Indices: []int{1, 2}}, {Class: 6, Indices: []int{1}}},
+			[]Run{{0, 1}, {6, 1}, {5, 1}, {0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := 0
+			for _, run := range tt.runs {
+				total += int(run.Length)
+			}
+			segmentation := &Segmentation{PointCount: total, Runs: tt.runs}
+
+			err := segmentation.Apply(&SegmentationPatch{Changes: tt.changes})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, segmentation.Runs)
+		})
+	}
+}
+
+func TestSegmentation_ApplyOutOfRange(t *testing.T) {
+	segmentation := NewSegmentation("pc-1", 10)
+
+	err := segmentation.Apply(&SegmentationPatch{Changes: []ClassChange{{Class: 1, Indices: []int{3, 10}}}})
+	assert.True(t, errors.Is(err, ErrPointIndexOutOfRange))
+	assert.Equal(t, []Run{{0, 10}}, segmentation.Runs)
+}
+
+func TestSegmentation_LabelsAndHistogram(t *testing.T) {
+	segmentation := &Segmentation{PointCount: 6, Runs: []Run{{2, 2}, {0, 1}, {2, 1}, {7, 2}}}
+
+	assert.Equal(t, []uint16{2, 2, 0, 2, 7, 7}, segmentation.Labels())
+	assert.Equal(t, []ClassCount{{0, 1}, {2, 3}, {7, 2}}, segmentation.Histogram())
+}
+
+func TestEncodeRuns_RoundTrip(t *testing.T) {
+	runs := []Run{{0, 1}, {65535, 300}, {12, MaxSegmentationPoints - 301}}
+
+	decoded, err := DecodeRuns(EncodeRuns(runs))
+	require.NoError(t, err)
+	assert.Equal(t, runs, decoded)
+
+	_, err = DecodeRuns([]byte{0x80})
+	assert.Error(t, err)
+	_, err = DecodeRuns([]byte{0x01, 0x00})
+	assert.Error(t, err)
+}
+
+func TestRun_JSON(t *testing.T) {
+	data, err := json.Marshal([]Run{{3, 7}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[[3, 7]]`, string(data))
+
+	var runs []Run
+	require.NoError(t, json.Unmarshal([]byte(`[[0, 2], [65535, 1]]`), &runs))
+	assert.Equal(t, []Run{{0, 2}, {65535, 1}}, runs)
+
+	for _, invalid := range []string{`[[70000, 1]]`, `[[1, 0]]`, `[[-1, 1]]`, `[{"class": 1}]`} {
+		assert.Error(t, json.Unmarshal([]byte(invalid), &runs), invalid)
+	}
+}
+
+func TestPutSegmentationRequest(t *testing.T) {
+	req := PutSegmentationRequest{PointCount: 5, Runs: []Run{{1, 2}, {1, 1}, {0, 2}}}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []Run{{1, 3}, {0, 2}}, req.Segmentation("pc-1").Runs)
+
+	req = PutSegmentationRequest{PointCount: 5}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []Run{{0, 5}}, req.Segmentation("pc-1").Runs)
+
+	req = PutSegmentationRequest{PointCount: 5, Runs: []Run{{1, 4}}}
+	assert.Error(t, req.Validate())
+}
+
+func TestSegmentationPatch_Validate(t *testing.T) {
+	patch := SegmentationPatch{Changes: []ClassChange{{Class: 1, Indices: []int{0, -1}}}}
+	assert.True(t, errors.Is(patch.Validate(), ErrPointIndexOutOfRange))
+
+	patch = SegmentationPatch{Changes: []ClassChange{{Class: 1, Indices: make([]int, MaxSegmentationPatchPoints+1)}}}
+	assert.Error(t, patch.Validate())
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sequence is an ordered series of LiDAR frames. Its frames are point clouds
+// carrying the sequence's ID and a frame timestamp.
+type Sequence struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateSequenceRequest represents the request body for creating a sequence.
+type CreateSequenceRequest struct {
+	Name        string `json:"name" binding:"required,max=256"`
+	Description string `json:"description" binding:"max=256"`
+}
+
+// UpdateSequenceRequest represents the request body for updating a sequence.
+type UpdateSequenceRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,max=256"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=256"`
+}
+
+// SequenceResponse wraps a single sequence in the API response.
+type SequenceResponse struct {
+	Data Sequence `json:"data"`
+}
+
+// SequencesResponse wraps multiple sequences in the API response.
+type SequencesResponse struct {
+	Data []Sequence `json:"data"`
+}
+
+// validateFrame checks that a point cloud is either a frame of a sequence,
+// with both a sequence ID and a frame timestamp, or neither.
+func validateFrame(sequenceID *string, frameTimestamp *time.Time) error {
+	inSequence := sequenceID != nil && *sequenceID != ""
+	if inSequence && frameTimestamp == nil {
+		return fmt.Errorf("frame_timestamp is required with sequence_id")
+	}
+	if !inSequence && frameTimestamp != nil {
+		return fmt.Errorf("frame_timestamp requires sequence_id")
+	}
+	return nil
+}
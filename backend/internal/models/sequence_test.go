@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointCloudRequest_ValidateFrame(t *testing.T) {
+	sequenceID := "seq-1"
+	empty := ""
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	create := CreatePointCloudRequest{SequenceID: &sequenceID, FrameTimestamp: &timestamp}
+	assert.NoError(t, create.Validate())
+
+	create = CreatePointCloudRequest{SequenceID: &sequenceID}
+	assert.Error(t, create.Validate())
+
+	create = CreatePointCloudRequest{FrameTimestamp: &timestamp}
+	assert.Error(t, create.Validate())
+
+	// Only the timestamp of a frame may change on its own
+	update := UpdatePointCloudRequest{FrameTimestamp: &timestamp}
+	assert.NoError(t, update.Validate())
+
+	update = UpdatePointCloudRequest{SequenceID: &sequenceID}
+	assert.Error(t, update.Validate())
+
+	update = UpdatePointCloudRequest{SequenceID: &empty}
+	assert.NoError(t, update.Validate())
+
+	update = UpdatePointCloudRequest{SequenceID: &empty, FrameTimestamp: &timestamp}
+	assert.Error(t, update.Validate())
+}
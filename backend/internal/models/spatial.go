@@ -0,0 +1,139 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxNearestNeighbors caps the k of nearest-neighbor queries.
+const MaxNearestNeighbors = MaxAnnotationLimit
+
+// Vec3 is a point or direction in point cloud coordinates.
+type Vec3 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Distance returns the Euclidean distance between two points.
+func (v Vec3) Distance(o Vec3) float64 {
+	return v.Sub(o).Norm()
+}
+
+// Sub returns the vector from o to v.
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{X: v.X - o.X, Y: v.Y - o.Y, Z: v.Z - o.Z}
+}
+
+// Dot returns the dot product of two vectors.
+func (v Vec3) Dot(o Vec3) float64 {
+	return v.X*o.X + v.Y*o.Y + v.Z*o.Z
+}
+
+// Cross returns the cross product of two vectors.
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{
+		X: v.Y*o.Z - v.Z*o.Y,
+		Y: v.Z*o.X - v.X*o.Z,
+		Z: v.X*o.Y - v.Y*o.X,
+	}
+}
+
+// Norm returns the length of the vector.
+func (v Vec3) Norm() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// ParseVec3 parses a point given as "x,y,z".
+func ParseVec3(s string) (Vec3, error) {
+	values, err := parseFloats(s, 3)
+	if err != nil {
+		return Vec3{}, err
+	}
+	return Vec3{X: values[0], Y: values[1], Z: values[2]}, nil
+}
+
+// BoundingBox is an axis-aligned box spanned by its minimum and maximum corners.
+type BoundingBox struct {
+	Min Vec3 `json:"min"`
+	Max Vec3 `json:"max"`
+}
+
+// ParseBoundingBox parses a box given as "minx,miny,minz,maxx,maxy,maxz".
+func ParseBoundingBox(s string) (BoundingBox, error) {
+	values, err := parseFloats(s, 6)
+	if err != nil {
+		return BoundingBox{}, err
+	}
+
+	box := BoundingBox{
+		Min: Vec3{X: values[0], Y: values[1], Z: values[2]},
+		Max: Vec3{X: values[3], Y: values[4], Z: values[5]},
+	}
+	if box.Min.X > box.Max.X || box.Min.Y > box.Max.Y || box.Min.Z > box.Max.Z {
+		return BoundingBox{}, fmt.Errorf("minimum corner must not exceed maximum corner")
+	}
+
+	return box, nil
+}
+
+// Contains reports whether the point lies inside the box or on its boundary.
+func (b BoundingBox) Contains(p Vec3) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X &&
+		p.Y >= b.Min.Y && p.Y <= b.Max.Y &&
+		p.Z >= b.Min.Z && p.Z <= b.Max.Z
+}
+
+// Position returns the marker position of the annotation.
+func (a *Annotation) Position() Vec3 {
+	return Vec3{X: a.X, Y: a.Y, Z: a.Z}
+}
+
+// SpatialQuery restricts an annotation listing to a region of the point cloud.
+// Exactly one of BBox, or Near combined with either Radius or K, is set.
+type SpatialQuery struct {
+	BBox   *BoundingBox
+	Near   *Vec3
+	Radius *float64
+	K      int
+}
+
+// Validate checks that the query selects exactly one kind of region.
+func (q *SpatialQuery) Validate() error {
+	switch {
+	case q.BBox != nil && q.Near != nil:
+		return fmt.Errorf("bbox cannot be combined with near")
+	case q.BBox != nil && (q.Radius != nil || q.K != 0):
+		return fmt.Errorf("radius and k require near instead of bbox")
+	case q.Near != nil && (q.Radius == nil) == (q.K == 0):
+		return fmt.Errorf("near requires exactly one of radius or k")
+	case q.Near == nil && (q.Radius != nil || q.K != 0):
+		return fmt.Errorf("radius and k require near")
+	case q.Radius != nil && (*q.Radius < 0 || math.IsNaN(*q.Radius) || math.IsInf(*q.Radius, 0)):
+		return fmt.Errorf("radius must be a non-negative number")
+	case q.Near != nil && q.Radius == nil && (q.K < 1 || q.K > MaxNearestNeighbors):
+		return fmt.Errorf("k must be between 1 and %d", MaxNearestNeighbors)
+	}
+	return nil
+}
+
+// parseFloats parses exactly n comma-separated finite numbers.
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d comma-separated numbers", n)
+	}
+
+	values := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number %q", part)
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVec3(t *testing.T) {
+	v, err := ParseVec3("1.5, -2,3e1")
+	assert.NoError(t, err)
+	assert.Equal(t, Vec3{X: 1.5, Y: -2, Z: 30}, v)
+
+	for _, s := range []string{"", "1,2", "1,2,3,4", "1,a,3", "1,NaN,3", "1,2,Inf"} {
+		_, err := ParseVec3(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseBoundingBox(t *testing.T) {
+	box, err := ParseBoundingBox("-1,-2,-3,1,2,3")
+	assert.NoError(t, err)
+	assert.True(t, box.Contains(Vec3{}))
+	assert.True(t, box.Contains(Vec3{X: 1, Y: 2, Z: 3}))
+	assert.False(t, box.Contains(Vec3{X: 1.01}))
+
+	_, err = ParseBoundingBox("1,0,0,0,1,1")
+	assert.Error(t, err)
+}
+
+func TestVec3_Distance(t *testing.T) {
+	assert.Equal(t, 5.0, Vec3{X: 3, Y: 4}.Distance(Vec3{}))
+}
+
+func TestSpatialQuery_Validate(t *testing.T) {
+	radius := 1.0
+	negative := -1.0
+	box := BoundingBox{}
+	center := Vec3{}
+
+	tests := []struct {
+		name  string
+		query SpatialQuery
+		valid bool
+	}{
+		{"bbox", SpatialQuery{BBox: &box}, true},
+		{"radius", SpatialQuery{Near: &center, Radius: &radius}, true},
+		{"nearest", SpatialQuery{Near: &center, K: 5}, true},
+		{"bbox and near", SpatialQuery{BBox: &box, Near: &center, K: 5}, false},
+		{"bbox and k", SpatialQuery{BBox: &box, K: 5}, false},
+		{"near alone", SpatialQuery{Near: &center}, false},
+		{"radius and k", SpatialQuery{Near: &center, Radius: &radius, K: 5}, false},
+		{"negative radius", SpatialQuery{Near: &center, Radius: &negative}, false},
+		{"k too large", SpatialQuery{Near: &center, K: MaxNearestNeighbors + 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Tag matching modes of annotation listings.
+const (
+	TagModeAny = "any"
+	TagModeAll = "all"
+)
+
+// MaxTagsPerRequest caps the number of tags added or filtered on at once.
+const MaxTagsPerRequest = 32
+
+// tagPattern matches tag names such as "needs-review" or "batch-7". Commas are
+// excluded because tag filters are comma-separated.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,63}$`)
+
+// ValidateTag checks that a tag name is well-formed.
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid tag %q", tag)
+	}
+	return nil
+}
+
+// AddTagsRequest represents the request body for tagging an annotation.
+type AddTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// Validate checks the request beyond what the binding tags express.
+func (r *AddTagsRequest) Validate() error {
+	if len(r.Tags) > MaxTagsPerRequest {
+		return fmt.Errorf("at most %d tags can be added at once", MaxTagsPerRequest)
+	}
+	for _, tag := range r.Tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagCount is a tag together with the number of annotations carrying it.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TagsResponse wraps the tags of a point cloud in the API response.
+type TagsResponse struct {
+	Data []TagCount `json:"data"`
+}
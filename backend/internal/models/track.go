@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// Track is a physical object followed across the frames of a sequence. The
+// annotations referring to it through their track_id are its observations,
+// at most one per frame.
+type Track struct {
+	ID         string    `json:"id"`
+	SequenceID string    `json:"sequence_id"`
+	Name       string    `json:"name"`
+	LabelID    *string   `json:"label_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateTrackRequest represents the request body for creating a track.
+type CreateTrackRequest struct {
+	SequenceID string  `json:"sequence_id" binding:"required"`
+	Name       string  `json:"name" binding:"max=256"`
+	LabelID    *string `json:"label_id,omitempty"`
+}
+
+// UpdateTrackRequest represents the request body for updating a track. A
+// track cannot move to another sequence.
+type UpdateTrackRequest struct {
+	Name *string `json:"name,omitempty" binding:"omitempty,max=256"`
+
+	// LabelID reassigns the track; an empty string removes its label.
+	LabelID *string `json:"label_id,omitempty"`
+}
+
+// TrackObservation is an annotation of a track together with the timestamp
+// of the frame it was made in.
+type TrackObservation struct {
+	Annotation
+	FrameTimestamp time.Time `json:"frame_timestamp"`
+}
+
+// TrackResponse wraps a single track in the API response.
+type TrackResponse struct {
+	Data Track `json:"data"`
+}
+
+// TracksResponse wraps multiple tracks in the API response.
+type TracksResponse struct {
+	Data []Track `json:"data"`
+}
+
+// TrajectoryResponse wraps the observations of a track, ordered by frame
+// timestamp, in the API response.
+type TrajectoryResponse struct {
+	Data []TrackObservation `json:"data"`
+}
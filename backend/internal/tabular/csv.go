@@ -0,0 +1,60 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVWriter writes a table as CSV with a header row. Nulls are empty fields,
+// numbers are written in full precision and timestamps in RFC 3339.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+// NewCSVWriter starts a CSV table of the columns, writing its header row.
+func NewCSVWriter(w io.Writer, columns []Column) (*CSVWriter, error) {
+	cw := &CSVWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+
+	for i, column := range columns {
+		cw.record[i] = column.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write writes a row.
+func (cw *CSVWriter) Write(row []any) error {
+	if err := checkRow(cw.columns, row); err != nil {
+		return err
+	}
+
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+			cw.record[i] = ""
+		case string:
+			cw.record[i] = v
+		case float64:
+			cw.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			cw.record[i] = strconv.FormatInt(v, 10)
+		case bool:
+			cw.record[i] = strconv.FormatBool(v)
+		case time.Time:
+			cw.record[i] = v.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return cw.w.Write(cw.record)
+}
+
+// Close flushes the rows written.
+func (cw *CSVWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
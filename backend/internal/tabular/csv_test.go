@@ -0,0 +1,31 @@
+package tabular
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVWriter(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: String},
+		{Name: "x", Type: Double},
+		{Name: "version", Type: Int64},
+		{Name: "attr.occluded", Type: Bool},
+		{Name: "created_at", Type: Timestamp},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf, columns)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]any{"a-1", 0.1, int64(3), true, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}))
+	assert.NoError(t, w.Write([]any{"a, 2", nil, int64(1), nil, nil}))
+	assert.Error(t, w.Write([]any{"a-3", "far", int64(1), nil, nil}))
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "id,x,version,attr.occluded,created_at\n"+
+		"a-1,0.1,3,true,2024-05-01T12:00:00Z\n"+
+		"\"a, 2\",,1,,\n", buf.String())
+}
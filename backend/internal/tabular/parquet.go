@@ -0,0 +1,310 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// ParquetRowGroupSize is the number of rows ParquetWriter buffers before
+// writing them out as a row group.
+const ParquetRowGroupSize = 10000
+
+// parquetMagic starts and ends Parquet files.
+const parquetMagic = "PAR1"
+
+// Values of the parquet.thrift enums the writer uses.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+// ParquetWriter writes a table as an Apache Parquet file. All columns are
+// optional and flat; each row group holds one uncompressed, plain encoded data
+// page per column, so memory is bounded by the row group size rather than the
+// table.
+type ParquetWriter struct {
+	w       *countingWriter
+	columns []Column
+	buffers []columnBuffer
+	rows    int
+
+	rowGroups []rowGroup
+	numRows   int64
+}
+
+// columnBuffer holds a column's values of the row group being written.
+type columnBuffer struct {
+	// defined holds the definition level of each row: false for nulls.
+	defined []bool
+
+	// values holds the plain encoded values, except for booleans, which are
+	// bit-packed when the page is written.
+	values bytes.Buffer
+	bools  []bool
+}
+
+type rowGroup struct {
+	numRows int64
+	size    int64
+	chunks  []columnChunk
+}
+
+type columnChunk struct {
+	offset int64
+	size   int64
+}
+
+// NewParquetWriter starts a Parquet file of the columns.
+func NewParquetWriter(w io.Writer, columns []Column) (*ParquetWriter, error) {
+	pw := &ParquetWriter{
+		w:       &countingWriter{w: w},
+		columns: columns,
+		buffers: make([]columnBuffer, len(columns)),
+	}
+	if _, err := io.WriteString(pw.w, parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write buffers a row, writing a row group once ParquetRowGroupSize rows are
+// buffered.
+func (pw *ParquetWriter) Write(row []any) error {
+	if err := checkRow(pw.columns, row); err != nil {
+		return err
+	}
+
+	for i, value := range row {
+		buffer := &pw.buffers[i]
+		buffer.defined = append(buffer.defined, value != nil)
+
+		var scratch [8]byte
+		switch v := value.(type) {
+		case string:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(len(v)))
+			buffer.values.Write(scratch[:4])
+			buffer.values.WriteString(v)
+		case float64:
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(v))
+			buffer.values.Write(scratch[:])
+		case int64:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v))
+			buffer.values.Write(scratch[:])
+		case time.Time:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v.UnixMicro()))
+			buffer.values.Write(scratch[:])
+		case bool:
+			buffer.bools = append(buffer.bools, v)
+		}
+	}
+
+	pw.rows++
+	if pw.rows == ParquetRowGroupSize {
+		return pw.writeRowGroup()
+	}
+	return nil
+}
+
+// writeRowGroup writes the buffered rows as a row group.
+func (pw *ParquetWriter) writeRowGroup() error {
+	group := rowGroup{numRows: int64(pw.rows), chunks: make([]columnChunk, len(pw.columns))}
+
+	for i := range pw.buffers {
+		buffer := &pw.buffers[i]
+
+		page := encodeLevels(buffer.defined)
+		if pw.columns[i].Type == Bool {
+			page = append(page, packBools(buffer.bools)...)
+		} else {
+			page = append(page, buffer.values.Bytes()...)
+		}
+
+		header := &thriftWriter{}
+		header.beginStruct()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5)
+		header.i32(1, int32(pw.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		offset := pw.w.n
+		if _, err := pw.w.Write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := pw.w.Write(page); err != nil {
+			return err
+		}
+
+		group.chunks[i] = columnChunk{offset: offset, size: pw.w.n - offset}
+		group.size += pw.w.n - offset
+
+		buffer.defined = buffer.defined[:0]
+		buffer.values.Reset()
+		buffer.bools = buffer.bools[:0]
+	}
+
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.numRows += group.numRows
+	pw.rows = 0
+	return nil
+}
+
+// Close writes the buffered rows and the file footer.
+func (pw *ParquetWriter) Close() error {
+	if pw.rows > 0 {
+		if err := pw.writeRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := pw.fileMetaData()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+
+	for _, b := range [][]byte{footer, length[:], []byte(parquetMagic)} {
+		if _, err := pw.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileMetaData encodes the FileMetaData struct of the footer.
+func (pw *ParquetWriter) fileMetaData() []byte {
+	t := &thriftWriter{}
+	t.beginStruct()
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(pw.columns)+1)
+	t.beginStruct()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.endStruct()
+	for _, column := range pw.columns {
+		physical, converted := parquetTypes(column.Type)
+		t.beginStruct()
+		t.i32(1, physical)
+		t.i32(3, parquetOptional)
+		t.binary(4, column.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, pw.numRows)
+
+	t.list(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		t.beginStruct()
+		t.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			physical, _ := parquetTypes(pw.columns[i].Type)
+			t.beginStruct()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, physical)
+			t.list(2, thriftI32, 2)
+			t.varint(parquetPlain)
+			t.varint(parquetRLE)
+			t.list(3, thriftBinary, 1)
+			t.bytes(pw.columns[i].Name)
+			t.i32(4, parquetUncompressed)
+			t.i64(5, group.numRows)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.numRows)
+		t.endStruct()
+	}
+
+	t.binary(6, "pointcloud-annotator")
+	t.endStruct()
+	return t.buf.Bytes()
+}
+
+// parquetTypes returns the physical and converted type of a column type; the
+// converted type is -1 if there is none.
+func parquetTypes(columnType ColumnType) (physical, converted int32) {
+	switch columnType {
+	case Double:
+		return parquetDouble, -1
+	case Int64:
+		return parquetInt64, -1
+	case Bool:
+		return parquetBoolean, -1
+	case Timestamp:
+		return parquetInt64, parquetTimestampMicros
+	default:
+		return parquetByteArray, parquetUTF8
+	}
+}
+
+// encodeLevels encodes definition levels of bit width 1 as runs of the
+// RLE/bit-packing hybrid, prefixed by their length as data pages require.
+func encodeLevels(defined []bool) []byte {
+	levels := make([]byte, 4, 16)
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		levels = binary.AppendUvarint(levels, uint64(j-i)<<1)
+		if defined[i] {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		i = j
+	}
+	binary.LittleEndian.PutUint32(levels, uint32(len(levels)-4))
+	return levels
+}
+
+// packBools bit-packs booleans, least significant bit first.
+func packBools(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// countingWriter counts the bytes written, which locate the pages.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,513 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// The reader below decodes Parquet files written for analytics tools from
+// the Parquet and Thrift compact protocol specifications alone, without the
+// writer's constants or encoders, so that reading a file back checks the
+// writer against the format rather than against itself.
+
+// Thrift compact protocol types, as the specification numbers them.
+const (
+	compactStop   = 0
+	compactTrue   = 1
+	compactFalse  = 2
+	compactByte   = 3
+	compactI16    = 4
+	compactI32    = 5
+	compactI64    = 6
+	compactDouble = 7
+	compactBinary = 8
+	compactList   = 9
+	compactSet    = 10
+	compactMap    = 11
+	compactStruct = 12
+)
+
+// compactMaxDepth bounds the nesting of decoded structs.
+const compactMaxDepth = 64
+
+// compactStructValue is a decoded struct: field values by field ID. Integers
+// decode to int64, binaries to []byte, collections to []any and maps to
+// map[any]any.
+type compactStructValue map[int16]any
+
+// compactDecoder decodes the Thrift compact protocol.
+type compactDecoder struct {
+	r     *bytes.Reader
+	depth int
+}
+
+func (d *compactDecoder) uvarint() (uint64, error) {
+	return binary.ReadUvarint(d.r)
+}
+
+func (d *compactDecoder) zigzag() (int64, error) {
+	u, err := d.uvarint()
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+func (d *compactDecoder) value(typ byte) (any, error) {
+	switch typ {
+	case compactTrue:
+		return true, nil
+	case compactFalse:
+		return false, nil
+	case compactByte:
+		b, err := d.r.ReadByte()
+		return int64(int8(b)), err
+	case compactI16, compactI32, compactI64:
+		return d.zigzag()
+	case compactDouble:
+		var b [8]byte
+		if _, err := io.ReadFull(d.r, b[:]); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case compactBinary:
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(d.r.Len()) {
+			return nil, fmt.Errorf("binary of %d bytes overruns the input", n)
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(d.r, b)
+		return b, err
+	case compactList, compactSet:
+		return d.list()
+	case compactMap:
+		return d.mapValue()
+	case compactStruct:
+		return d.structValue()
+	}
+	return nil, fmt.Errorf("unknown compact type %d", typ)
+}
+
+func (d *compactDecoder) list() (any, error) {
+	header, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n := uint64(header >> 4)
+	if n == 15 {
+		if n, err = d.uvarint(); err != nil {
+			return nil, err
+		}
+	}
+	if n > uint64(d.r.Len()) {
+		return nil, fmt.Errorf("list of %d elements overruns the input", n)
+	}
+
+	elemType := header & 0x0f
+	values := make([]any, n)
+	for i := range values {
+		if elemType == compactTrue || elemType == compactFalse {
+			// Booleans in collections take a byte each
+			b, err := d.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			values[i] = b == compactTrue
+			continue
+		}
+		if values[i], err = d.value(elemType); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (d *compactDecoder) mapValue() (any, error) {
+	n, err := d.uvarint()
+	if err != nil || n == 0 {
+		return map[any]any{}, err
+	}
+	types, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[any]any, n)
+	for i := uint64(0); i < n; i++ {
+		key, err := d.value(types >> 4)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		if values[key], err = d.value(types & 0x0f); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (d *compactDecoder) structValue() (compactStructValue, error) {
+	if d.depth++; d.depth > compactMaxDepth {
+		return nil, errors.New("structs nested too deeply")
+	}
+	defer func() { d.depth-- }()
+
+	fields := compactStructValue{}
+	var id int16
+	for {
+		header, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		typ := header & 0x0f
+		if typ == compactStop {
+			return fields, nil
+		}
+
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			long, err := d.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(long)
+		}
+		if _, ok := fields[id]; ok {
+			return nil, fmt.Errorf("field %d repeated", id)
+		}
+		if fields[id], err = d.value(typ); err != nil {
+			return nil, fmt.Errorf("field %d: %w", id, err)
+		}
+	}
+}
+
+// decodeCompactStruct decodes a struct at the start of data, returning it with
+// its encoded length.
+func decodeCompactStruct(data []byte) (compactStructValue, int, error) {
+	d := &compactDecoder{r: bytes.NewReader(data)}
+	value, err := d.structValue()
+	return value, len(data) - d.r.Len(), err
+}
+
+// Values of the parquet.thrift enums the reader supports.
+const (
+	specBoolean   = 0
+	specInt64     = 2
+	specDouble    = 5
+	specByteArray = 6
+
+	specRequired = 0
+	specOptional = 1
+
+	specUTF8            = 0
+	specTimestampMicros = 10
+
+	specPlain         = 0
+	specRLE           = 3
+	specDataPage      = 0
+	specUncompressed  = 0
+	specDefinitionMax = 1
+)
+
+// parquetTable is a Parquet file read back: its column names and types, and
+// its rows, with nil for nulls.
+type parquetTable struct {
+	names []string
+	types []int64
+	rows  [][]any
+}
+
+// readParquet reads a Parquet file of flat, optional or required columns,
+// with uncompressed, plain encoded v1 data pages.
+func readParquet(data []byte) (*parquetTable, error) {
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		return nil, errors.New("missing PAR1 magic")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLength
+	if footerStart < 4 {
+		return nil, errors.New("footer length overruns the file")
+	}
+
+	metadata, n, err := decodeCompactStruct(data[footerStart : len(data)-8])
+	if err != nil {
+		return nil, fmt.Errorf("file metadata: %w", err)
+	}
+	if n != footerLength {
+		return nil, fmt.Errorf("file metadata takes %d of the %d footer bytes", n, footerLength)
+	}
+	if _, ok := metadata[1].(int64); !ok {
+		return nil, errors.New("file metadata lacks the version")
+	}
+
+	schema, _ := metadata[2].([]any)
+	if len(schema) == 0 {
+		return nil, errors.New("file metadata lacks the schema")
+	}
+	root := schema[0].(compactStructValue)
+	if root[5] != int64(len(schema)-1) {
+		return nil, fmt.Errorf("schema root has %v children for %d elements", root[5], len(schema)-1)
+	}
+
+	table := &parquetTable{}
+	var optional []bool
+	var timestamps []bool
+	for _, element := range schema[1:] {
+		fields := element.(compactStructValue)
+		name, _ := fields[4].([]byte)
+		typ, ok := fields[1].(int64)
+		if !ok {
+			return nil, fmt.Errorf("column %q lacks a physical type", name)
+		}
+		repetition := fields[3]
+		if repetition != int64(specOptional) && repetition != int64(specRequired) {
+			return nil, fmt.Errorf("column %q is repeated", name)
+		}
+		table.names = append(table.names, string(name))
+		table.types = append(table.types, typ)
+		optional = append(optional, repetition == int64(specOptional))
+		timestamps = append(timestamps, fields[6] == int64(specTimestampMicros))
+		if fields[6] == int64(specUTF8) && typ != specByteArray {
+			return nil, fmt.Errorf("UTF8 column %q is not a byte array", name)
+		}
+	}
+
+	numRows, _ := metadata[3].(int64)
+	groups, _ := metadata[4].([]any)
+	for g, group := range groups {
+		fields := group.(compactStructValue)
+		groupRows := fields[3].(int64)
+		chunks := fields[1].([]any)
+		if len(chunks) != len(table.names) {
+			return nil, fmt.Errorf("row group %d has %d column chunks for %d columns", g, len(chunks), len(table.names))
+		}
+
+		columns := make([][]any, len(chunks))
+		var groupSize int64
+		for c, chunk := range chunks {
+			meta, ok := chunk.(compactStructValue)[3].(compactStructValue)
+			if !ok {
+				return nil, fmt.Errorf("row group %d column %d lacks its metadata", g, c)
+			}
+			if meta[1] != table.types[c] {
+				return nil, fmt.Errorf("column %q chunk has type %v", table.names[c], meta[1])
+			}
+			if path := meta[3].([]any); len(path) != 1 || string(path[0].([]byte)) != table.names[c] {
+				return nil, fmt.Errorf("column %q chunk has path %q", table.names[c], path)
+			}
+			if meta[4] != int64(specUncompressed) {
+				return nil, fmt.Errorf("column %q is compressed with codec %v", table.names[c], meta[4])
+			}
+			if meta[5] != groupRows {
+				return nil, fmt.Errorf("column %q chunk has %v values for %d rows", table.names[c], meta[5], groupRows)
+			}
+
+			values, size, err := readColumnChunk(data, meta[9].(int64), groupRows, table.types[c], optional[c])
+			if err != nil {
+				return nil, fmt.Errorf("column %q of row group %d: %w", table.names[c], g, err)
+			}
+			if meta[6] != size || meta[7] != size {
+				return nil, fmt.Errorf("column %q chunk takes %d bytes, not %v", table.names[c], size, meta[7])
+			}
+			if timestamps[c] {
+				for i, v := range values {
+					if v != nil {
+						values[i] = time.UnixMicro(v.(int64)).UTC()
+					}
+				}
+			}
+			columns[c] = values
+			groupSize += size
+		}
+		if fields[2] != groupSize {
+			return nil, fmt.Errorf("row group %d takes %d bytes, not %v", g, groupSize, fields[2])
+		}
+
+		for r := int64(0); r < groupRows; r++ {
+			row := make([]any, len(columns))
+			for c := range columns {
+				row[c] = columns[c][r]
+			}
+			table.rows = append(table.rows, row)
+		}
+	}
+	if int64(len(table.rows)) != numRows {
+		return nil, fmt.Errorf("row groups hold %d rows, not %d", len(table.rows), numRows)
+	}
+	return table, nil
+}
+
+// readColumnChunk reads the data pages of a column chunk starting at offset
+// until it has read numValues values, returning them and the chunk's size.
+func readColumnChunk(data []byte, offset, numValues, typ int64, optional bool) ([]any, int64, error) {
+	var values []any
+	position := offset
+	for int64(len(values)) < numValues {
+		if position < 4 || position >= int64(len(data)) {
+			return nil, 0, fmt.Errorf("page offset %d is outside the file", position)
+		}
+		header, n, err := decodeCompactStruct(data[position:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("page header: %w", err)
+		}
+		if header[1] != int64(specDataPage) {
+			return nil, 0, fmt.Errorf("page of type %v", header[1])
+		}
+		size := header[3].(int64)
+		if header[2] != size {
+			return nil, 0, errors.New("uncompressed page sizes differ")
+		}
+		start := position + int64(n)
+		if start+size > int64(len(data)) {
+			return nil, 0, errors.New("page overruns the file")
+		}
+
+		dataPage := header[5].(compactStructValue)
+		if dataPage[2] != int64(specPlain) || dataPage[3] != int64(specRLE) {
+			return nil, 0, fmt.Errorf("page encodings %v and %v", dataPage[2], dataPage[3])
+		}
+		page, err := readDataPage(data[start:start+size], int(dataPage[1].(int64)), typ, optional)
+		if err != nil {
+			return nil, 0, err
+		}
+		values = append(values, page...)
+		position = start + size
+	}
+	if int64(len(values)) != numValues {
+		return nil, 0, fmt.Errorf("pages hold %d values, not %d", len(values), numValues)
+	}
+	return values, position - offset, nil
+}
+
+// readDataPage reads the values of a v1 data page: the definition levels, if
+// the column is optional, then the plain encoded non-null values.
+func readDataPage(page []byte, numValues int, typ int64, optional bool) ([]any, error) {
+	defined := make([]bool, numValues)
+	for i := range defined {
+		defined[i] = true
+	}
+	if optional {
+		if len(page) < 4 {
+			return nil, errors.New("page lacks the definition levels")
+		}
+		length := int(binary.LittleEndian.Uint32(page))
+		if 4+length > len(page) {
+			return nil, errors.New("definition levels overrun the page")
+		}
+		levels, err := decodeHybrid(page[4:4+length], 1, numValues)
+		if err != nil {
+			return nil, fmt.Errorf("definition levels: %w", err)
+		}
+		for i, level := range levels {
+			if level > specDefinitionMax {
+				return nil, fmt.Errorf("definition level %d", level)
+			}
+			defined[i] = level == specDefinitionMax
+		}
+		page = page[4+length:]
+	}
+
+	r := bytes.NewReader(page)
+	values := make([]any, numValues)
+	bit := 0
+	var bits byte
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch typ {
+		case specBoolean:
+			if bit%8 == 0 {
+				var err error
+				if bits, err = r.ReadByte(); err != nil {
+					return nil, err
+				}
+			}
+			values[i] = bits>>(bit%8)&1 == 1
+			bit++
+		case specInt64:
+			var v int64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return nil, err
+			}
+			values[i] = v
+		case specDouble:
+			var v float64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return nil, err
+			}
+			values[i] = v
+		case specByteArray:
+			var length uint32
+			if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+				return nil, err
+			}
+			if int(length) > r.Len() {
+				return nil, errors.New("byte array overruns the page")
+			}
+			b := make([]byte, length)
+			_, _ = io.ReadFull(r, b)
+			values[i] = string(b)
+		default:
+			return nil, fmt.Errorf("unsupported physical type %d", typ)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes left over in the page", r.Len())
+	}
+	return values, nil
+}
+
+// decodeHybrid decodes n values of the RLE/bit-packing hybrid encoding of the
+// given bit width.
+func decodeHybrid(data []byte, bitWidth, n int) ([]int, error) {
+	r := bytes.NewReader(data)
+	byteWidth := (bitWidth + 7) / 8
+	var values []int
+	for len(values) < n {
+		header, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if header&1 == 0 {
+			// RLE run: a count and the repeated value
+			var value [4]byte
+			if _, err := io.ReadFull(r, value[:byteWidth]); err != nil {
+				return nil, err
+			}
+			v := int(binary.LittleEndian.Uint32(value[:]))
+			for i := uint64(0); i < header>>1; i++ {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		// Bit-packed run: groups of 8 values, least significant bit first
+		packed := make([]byte, int(header>>1)*bitWidth)
+		if _, err := io.ReadFull(r, packed); err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(header>>1)*8; i++ {
+			v := 0
+			for b := 0; b < bitWidth; b++ {
+				position := i*bitWidth + b
+				v |= int(packed[position/8]>>(position%8)&1) << b
+			}
+			values = append(values, v)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes left over after the levels", r.Len())
+	}
+	// Bit-packed runs are padded to groups of 8
+	return values[:n], nil
+}
@@ -0,0 +1,219 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftStructValue is a decoded Thrift struct: field values by field ID.
+type thriftStructValue map[int16]any
+
+// thriftReader decodes the compact protocol subset thriftWriter encodes.
+type thriftReader struct {
+	r *bytes.Reader
+}
+
+func (t *thriftReader) varint() int64 {
+	u, _ := binary.ReadUvarint(t.r)
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (t *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return t.varint()
+	case thriftBinary:
+		n, _ := binary.ReadUvarint(t.r)
+		b := make([]byte, n)
+		_, _ = t.r.Read(b)
+		return string(b)
+	case thriftList:
+		header, _ := t.r.ReadByte()
+		n := uint64(header >> 4)
+		if n == 15 {
+			n, _ = binary.ReadUvarint(t.r)
+		}
+		values := make([]any, n)
+		for i := range values {
+			values[i] = t.value(header & 0x0f)
+		}
+		return values
+	case thriftStruct:
+		fields := thriftStructValue{}
+		var id int16
+		for {
+			header, _ := t.r.ReadByte()
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				id = int16(t.varint())
+			}
+			fields[id] = t.value(header & 0x0f)
+		}
+	}
+	panic("unsupported thrift type")
+}
+
+func readStruct(data []byte) (thriftStructValue, int) {
+	t := &thriftReader{r: bytes.NewReader(data)}
+	value := t.value(thriftStruct).(thriftStructValue)
+	return value, len(data) - t.r.Len()
+}
+
+func TestParquetWriter(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []Column{
+		{Name: "id", Type: String},
+		{Name: "x", Type: Double},
+		{Name: "version", Type: Int64},
+		{Name: "attr.occluded", Type: Bool},
+		{Name: "created_at", Type: Timestamp},
+	}
+	rows := [][]any{
+		{"a-1", 1.5, int64(1), true, created},
+		{"a-2", nil, int64(2), nil, created},
+		{"a-3", -2.0, int64(3), false, created},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, columns)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+
+	data := buf.Bytes()
+	assert.Equal(t, parquetMagic, string(data[:4]))
+	assert.Equal(t, parquetMagic, string(data[len(data)-4:]))
+
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metadata, n := readStruct(data[len(data)-8-footerLength : len(data)-8])
+	assert.Equal(t, footerLength, n)
+	assert.Equal(t, int64(3), metadata[3])
+
+	schema := metadata[2].([]any)
+	require.Len(t, schema, len(columns)+1)
+	assert.Equal(t, int64(len(columns)), schema[0].(thriftStructValue)[5])
+	assert.Equal(t, "attr.occluded", schema[4].(thriftStructValue)[4])
+	assert.Equal(t, int64(parquetTimestampMicros), schema[5].(thriftStructValue)[6])
+
+	groups := metadata[4].([]any)
+	require.Len(t, groups, 1)
+	chunks := groups[0].(thriftStructValue)[1].([]any)
+	require.Len(t, chunks, len(columns))
+
+	// Read back the x column: definition levels, then the defined values
+	meta := chunks[1].(thriftStructValue)[3].(thriftStructValue)
+	offset := meta[9].(int64)
+	header, n := readStruct(data[offset:])
+	assert.Equal(t, int64(3), header[5].(thriftStructValue)[1])
+	page := data[int(offset)+n : int(offset)+n+int(header[2].(int64))]
+
+	levelsLength := int(binary.LittleEndian.Uint32(page))
+	assert.Equal(t, []byte{1 << 1, 1, 1 << 1, 0, 1 << 1, 1}, page[4:4+levelsLength])
+
+	values := page[4+levelsLength:]
+	require.Len(t, values, 16)
+	assert.Equal(t, 1.5, math.Float64frombits(binary.LittleEndian.Uint64(values)))
+	assert.Equal(t, -2.0, math.Float64frombits(binary.LittleEndian.Uint64(values[8:])))
+
+	// The chunk sizes add up to the file between the magic and the footer
+	assert.Equal(t, int64(len(data)-8-footerLength-4), groups[0].(thriftStructValue)[2])
+}
+
+func TestParquetWriter_RowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, []Column{{Name: "n", Type: Int64}})
+	require.NoError(t, err)
+	for i := 0; i < ParquetRowGroupSize+1; i++ {
+		require.NoError(t, w.Write([]any{int64(i)}))
+	}
+	require.NoError(t, w.Close())
+
+	data := buf.Bytes()
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metadata, _ := readStruct(data[len(data)-8-footerLength : len(data)-8])
+	assert.Equal(t, int64(ParquetRowGroupSize+1), metadata[3])
+	assert.Len(t, metadata[4].([]any), 2)
+}
+
+func TestParquetWriter_ReadBack(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	columns := []Column{
+		{Name: "id", Type: String},
+		{Name: "x", Type: Double},
+		{Name: "version", Type: Int64},
+		{Name: "attr.occluded", Type: Bool},
+		{Name: "created_at", Type: Timestamp},
+		{Name: "attr.note", Type: String},
+	}
+
+	// Enough rows for two row groups, with runs of nulls and values of
+	// every column crossing the group boundary
+	var rows [][]any
+	for i := 0; i < ParquetRowGroupSize+21; i++ {
+		row := []any{
+			fmt.Sprintf("a-%d", i),
+			float64(i) / 4,
+			int64(i),
+			i%3 == 0,
+			created.Add(time.Duration(i) * time.Second),
+			nil,
+		}
+		if i%7 == 0 {
+			row[1], row[3] = nil, nil
+		}
+		if i%1000 < 20 {
+			row[5] = "Fußgänger, \"occluded\"\n"
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, columns)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+
+	table, err := readParquet(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "x", "version", "attr.occluded", "created_at", "attr.note"}, table.names)
+	assert.Equal(t, []int64{specByteArray, specDouble, specInt64, specBoolean, specInt64, specByteArray}, table.types)
+	require.Len(t, table.rows, len(rows))
+	for i := range rows {
+		require.Equal(t, rows[i], table.rows[i], "row %d", i)
+	}
+}
+
+func TestParquetWriter_ReadBackEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, []Column{{Name: "id", Type: String}})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	table, err := readParquet(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, table.names)
+	assert.Empty(t, table.rows)
+}
+
+func TestParquetWriter_InvalidRow(t *testing.T) {
+	w, err := NewParquetWriter(&bytes.Buffer{}, []Column{{Name: "n", Type: Int64}})
+	require.NoError(t, err)
+
+	assert.Error(t, w.Write([]any{1.5}))
+	assert.Error(t, w.Write([]any{int64(1), int64(2)}))
+}
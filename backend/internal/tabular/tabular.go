@@ -0,0 +1,78 @@
+// Package tabular writes tables of typed columns as CSV or Apache Parquet,
+// row by row, for analytics exports.
+package tabular
+
+import (
+	"fmt"
+	"time"
+)
+
+// ColumnType is the value type of a column.
+type ColumnType int
+
+// Column types and the Go types of their values.
+const (
+	// String columns hold string values.
+	String ColumnType = iota
+
+	// Double columns hold float64 values.
+	Double
+
+	// Int64 columns hold int64 values.
+	Int64
+
+	// Bool columns hold bool values.
+	Bool
+
+	// Timestamp columns hold time.Time values, stored with microsecond
+	// precision.
+	Timestamp
+)
+
+// Column is a named, typed column of a table.
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer writes the rows of a table. A row holds a value for each column, in
+// order; nil values are nulls, other values must be of the column's Go type.
+type Writer interface {
+	// Write writes a row.
+	Write(row []any) error
+
+	// Close writes what is buffered and ends the table. It does not close
+	// the underlying writer.
+	Close() error
+}
+
+// checkRow checks that a row matches the columns.
+func checkRow(columns []Column, row []any) error {
+	if len(row) != len(columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(columns))
+	}
+
+	for i, value := range row {
+		if value == nil {
+			continue
+		}
+
+		var ok bool
+		switch columns[i].Type {
+		case String:
+			_, ok = value.(string)
+		case Double:
+			_, ok = value.(float64)
+		case Int64:
+			_, ok = value.(int64)
+		case Bool:
+			_, ok = value.(bool)
+		case Timestamp:
+			_, ok = value.(time.Time)
+		}
+		if !ok {
+			return fmt.Errorf("column %q cannot hold %T", columns[i].Name, value)
+		}
+	}
+	return nil
+}
@@ -10,8 +10,16 @@ import * as THREE from "/potree/libs/three.js/build/three.module.js";
 // API Configuration
 const API_BASE_URL = window.location.origin + '/api/v1';
 
+// Scene registered when the server has no point clouds yet
+const DEFAULT_POINT_CLOUD = {
+    name: 'lion_takanawa',
+    description: 'Sample point cloud bundled with Potree',
+    source_url: '/potree/pointclouds/lion_takanawa/cloud.js',
+};
+
 // Application State
 const state = {
+    pointCloud: null, // The scene currently being annotated
     isAddingAnnotation: false,
     annotations: new Map(), // Map of annotation ID to Potree.Annotation
     pendingPosition: null,
@@ -32,20 +40,61 @@ const elements = {
 };
 
 /**
- * API Client for annotation operations
+ * Base URL of the annotation routes of the current point cloud
+ */
+function annotationsUrl() {
+    return `${API_BASE_URL}/pointclouds/${state.pointCloud.id}/annotations`;
+}
+
+/**
+ * API Client for point cloud and annotation operations
  */
 const api = {
-    async getAnnotations() {
-        const response = await fetch(`${API_BASE_URL}/annotations`);
+    async getPointClouds() {
+        const response = await fetch(`${API_BASE_URL}/pointclouds`);
         if (!response.ok) {
-            throw new Error(`Failed to fetch annotations: ${response.statusText}`);
+            throw new Error(`Failed to fetch point clouds: ${response.statusText}`);
         }
         const data = await response.json();
         return data.data || [];
     },
 
+    async createPointCloud(pointCloud) {
+        const response = await fetch(`${API_BASE_URL}/pointclouds`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(pointCloud),
+        });
+        if (!response.ok) {
+            const error = await response.json();
+            throw new Error(error.message || 'Failed to create point cloud');
+        }
+        const data = await response.json();
+        return data.data;
+    },
+
+    async getAnnotations() {
+        // Follow the pagination cursor until every page has been fetched
+        const annotations = [];
+        let cursor = null;
+        do {
+            const params = new URLSearchParams({ limit: '1000' });
+            if (cursor) {
+                params.set('cursor', cursor);
+            }
+            const response = await fetch(`${annotationsUrl()}?${params}`);
+            if (!response.ok) {
+                throw new Error(`Failed to fetch annotations: ${response.statusText}`);
+            }
+            const data = await response.json();
+            annotations.push(...(data.data || []));
+            cursor = data.next_cursor;
+        } while (cursor);
+        return annotations;
+    },
+
     async createAnnotation(annotation) {
-        const response = await fetch(`${API_BASE_URL}/annotations`, {
+        const response = await fetch(annotationsUrl(), {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(annotation),
@@ -59,7 +108,7 @@ const api = {
     },
 
     async updateAnnotation(id, updates) {
-        const response = await fetch(`${API_BASE_URL}/annotations/${id}`, {
+        const response = await fetch(`${annotationsUrl()}/${id}`, {
             method: 'PUT',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(updates),
@@ -73,7 +122,7 @@ const api = {
     },
 
     async deleteAnnotation(id) {
-        const response = await fetch(`${API_BASE_URL}/annotations/${id}`, {
+        const response = await fetch(`${annotationsUrl()}/${id}`, {
             method: 'DELETE',
         });
         if (!response.ok && response.status !== 204) {
@@ -194,6 +243,53 @@ async function loadAnnotations() {
     }
 }
 
+/**
+ * Remove an annotation from the scene, if it is shown
+ */
+function removePotreeAnnotation(id) {
+    const annotation = state.annotations.get(id);
+    if (annotation) {
+        viewer.scene.annotations.remove(annotation);
+        state.annotations.delete(id);
+    }
+}
+
+/**
+ * Follow the changes other users make to the point cloud's annotations.
+ * The stream reconnects by itself; as events may have been missed while it
+ * was down, the annotations are reloaded whenever it reconnects.
+ */
+function watchAnnotations() {
+    const source = new EventSource(`${API_BASE_URL}/annotations/events?point_cloud_id=${encodeURIComponent(state.pointCloud.id)}`);
+    let connected = false;
+
+    source.onopen = () => {
+        if (connected) {
+            loadAnnotations();
+        }
+        connected = true;
+    };
+
+    const apply = (e) => {
+        const event = JSON.parse(e.data);
+
+        // Changes made here are shown already
+        if (event.type === 'created' && state.annotations.has(event.annotation_id)) {
+            return;
+        }
+
+        removePotreeAnnotation(event.annotation_id);
+        if (event.annotation) {
+            viewer.scene.annotations.add(createPotreeAnnotation(event.annotation));
+        }
+        updateAnnotationCount();
+    };
+
+    for (const type of ['created', 'updated', 'deleted']) {
+        source.addEventListener(type, apply);
+    }
+}
+
 /**
  * Save a new annotation
  */
@@ -223,7 +319,9 @@ async function saveAnnotation() {
             description: description,
         });
 
-        // Create and add the Potree annotation
+        // Create and add the Potree annotation, replacing the one its
+        // change event may have added first
+        removePotreeAnnotation(annotationData.id);
         const potreeAnnotation = createPotreeAnnotation(annotationData);
         viewer.scene.annotations.add(potreeAnnotation);
 
@@ -252,11 +350,7 @@ async function deleteAnnotation(id) {
         await api.deleteAnnotation(id);
 
         // Remove from scene
-        const annotation = state.annotations.get(id);
-        if (annotation) {
-            viewer.scene.annotations.remove(annotation);
-            state.annotations.delete(id);
-        }
+        removePotreeAnnotation(id);
 
         updateAnnotationCount();
         setStatus('Annotation deleted', 'success');
@@ -334,10 +428,59 @@ function startAnnotationInsertion() {
     domElement.addEventListener('mouseup', onMouseUp);
 }
 
+/**
+ * Resolve the point cloud to annotate: the one named by the `pointcloud` URL
+ * parameter, otherwise the first registered scene, registering the bundled
+ * sample scene when the server has none.
+ */
+async function selectPointCloud() {
+    const pointClouds = await api.getPointClouds();
+    const requestedId = new URLSearchParams(window.location.search).get('pointcloud');
+
+    if (requestedId) {
+        const requested = pointClouds.find((pc) => pc.id === requestedId);
+        if (!requested) {
+            throw new Error(`Point cloud ${requestedId} not found`);
+        }
+        return requested;
+    }
+
+    if (pointClouds.length > 0) {
+        return pointClouds[0];
+    }
+
+    return api.createPointCloud(DEFAULT_POINT_CLOUD);
+}
+
+/**
+ * Load the selected point cloud into the viewer
+ */
+function loadPointCloud(pointCloud) {
+    Potree.loadPointCloud(pointCloud.source_url, pointCloud.name, function(e) {
+        viewer.scene.addPointCloud(e.pointcloud);
+
+        // Frame the whole point cloud
+        viewer.fitToScreen();
+
+        // Configure point cloud material - larger points are easier to click
+        e.pointcloud.material.pointSizeType = Potree.PointSizeType.ADAPTIVE;
+        e.pointcloud.material.size = 2;  // Increased from 1 for easier clicking
+        e.pointcloud.material.minSize = 2;
+
+        // Store reference for picking
+        window.pointcloud = e.pointcloud;
+
+        // Load existing annotations after point cloud is loaded, then
+        // follow the changes others make
+        loadAnnotations();
+        watchAnnotations();
+    });
+}
+
 /**
  * Initialize the Potree viewer
  */
-function initViewer() {
+async function initViewer() {
     // Set Potree paths - use build/potree/resources path which nginx rewrites to actual location
     const origin = window.location.origin;
     Potree.resourcePath = origin + "/potree/build/potree/resources";
@@ -365,25 +508,15 @@ function initViewer() {
         All annotations are persisted to the server.
     `);
 
-    // Load the lion point cloud
-    Potree.loadPointCloud("/potree/pointclouds/lion_takanawa/cloud.js", "lion", function(e) {
-        viewer.scene.addPointCloud(e.pointcloud);
-        
-        // Set initial camera position
-        viewer.scene.view.position.set(4.15, -6.12, 8.54);
-        viewer.scene.view.lookAt(new THREE.Vector3(0, -0.098, 4.23));
-        
-        // Configure point cloud material - larger points are easier to click
-        e.pointcloud.material.pointSizeType = Potree.PointSizeType.ADAPTIVE;
-        e.pointcloud.material.size = 2;  // Increased from 1 for easier clicking
-        e.pointcloud.material.minSize = 2;
-        
-        // Store reference for picking
-        window.pointcloud = e.pointcloud;
+    try {
+        state.pointCloud = await selectPointCloud();
+    } catch (error) {
+        console.error('Failed to select point cloud:', error);
+        setStatus(`Error: ${error.message}`, 'error');
+        return;
+    }
 
-        // Load existing annotations after point cloud is loaded
-        loadAnnotations();
-    });
+    loadPointCloud(state.pointCloud);
 }
 
 /**